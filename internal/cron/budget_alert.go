@@ -0,0 +1,85 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/money"
+	"github.com/jljl1337/xpense/internal/notify"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+// deliverBudgetAlerts sends the pending budget alerts through the notifier, and
+// returns the number of alerts delivered.
+//
+// Every attempt is recorded, an alert failing to be delivered is retried by the
+// next runs until it reaches maxAttempts.
+func deliverBudgetAlerts(ctx context.Context, dbInstance *sqlx.DB, notifier notify.Notifier, maxAttempts int) (int64, error) {
+	queries := repository.New(dbInstance)
+
+	alerts, err := queries.GetPendingBudgetAlerts(ctx, repository.GetPendingBudgetAlertsParams{
+		MaxAttempts: maxAttempts,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending budget alerts: %w", err)
+	}
+
+	var delivered int64
+	for _, alert := range alerts {
+		scale := money.Scale(alert.Currency)
+
+		deliveryError := ""
+		err := notifier.NotifyBudgetAlert(ctx, notify.BudgetAlert{
+			ID:           alert.ID,
+			BudgetID:     alert.BudgetID,
+			BookID:       alert.BookID,
+			BookName:     alert.BookName,
+			CategoryID:   alert.CategoryID,
+			CategoryName: alert.CategoryName,
+			Period:       alert.Period,
+			PeriodStart:  alert.PeriodStart,
+			PeriodEnd:    alert.PeriodEnd,
+			Threshold:    alert.Threshold,
+			Currency:     alert.Currency,
+			Spent:        money.New(alert.Spent, scale),
+			Available:    money.New(alert.Available, scale),
+			CreatedAt:    alert.CreatedAt,
+		})
+		if err != nil {
+			slog.Warn("Failed to deliver budget alert " + alert.ID + ": " + err.Error())
+			deliveryError = err.Error()
+		}
+
+		currentTime := generator.NowISO8601()
+
+		_, err = queries.CreateBudgetAlertDelivery(ctx, repository.CreateBudgetAlertDeliveryParams{
+			ID:            generator.NewULID(),
+			BudgetAlertID: alert.ID,
+			AttemptedAt:   currentTime,
+			Error:         deliveryError,
+		})
+		if err != nil {
+			return delivered, fmt.Errorf("failed to record budget alert delivery: %w", err)
+		}
+
+		if deliveryError != "" {
+			continue
+		}
+
+		_, err = queries.UpdateBudgetAlertDeliveredAt(ctx, repository.UpdateBudgetAlertDeliveredAtParams{
+			DeliveredAt: currentTime,
+			ID:          alert.ID,
+		})
+		if err != nil {
+			return delivered, fmt.Errorf("failed to update budget alert: %w", err)
+		}
+
+		delivered++
+	}
+
+	return delivered, nil
+}
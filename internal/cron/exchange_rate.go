@@ -0,0 +1,62 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jljl1337/xpense/internal/exchangerate"
+	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+// loadExchangeRates loads the exchange rates from the CSV file at path, and
+// returns the number of rates created or updated.
+//
+// Rates that already exist with the same value are left untouched, so the same
+// file can be loaded repeatedly.
+func loadExchangeRates(ctx context.Context, dbInstance *sqlx.DB, path, baseCurrency string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open exchange rate file: %w", err)
+	}
+	defer file.Close()
+
+	rates, err := exchangerate.ParseCSV(file, baseCurrency)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse exchange rate file: %w", err)
+	}
+
+	tx, err := dbInstance.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+	currentTime := generator.NowISO8601()
+
+	var changed int64
+	for _, rate := range rates {
+		rows, err := queries.UpsertExchangeRate(ctx, repository.UpsertExchangeRateParams{
+			Date:          rate.Date,
+			BaseCurrency:  rate.BaseCurrency,
+			QuoteCurrency: rate.QuoteCurrency,
+			Rate:          rate.Rate,
+			CreatedAt:     currentTime,
+			UpdatedAt:     currentTime,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to upsert exchange rate: %w", err)
+		}
+		changed += rows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
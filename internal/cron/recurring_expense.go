@@ -0,0 +1,123 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/recurrence"
+	"github.com/jljl1337/xpense/internal/repository"
+	"github.com/jljl1337/xpense/internal/service"
+)
+
+// materializeRecurringExpenses creates the expenses of all recurring expense
+// occurrences up to and including today, and returns the number of expenses
+// created.
+//
+// It is idempotent: occurrences that already exist are skipped, and missed
+// periods (e.g. after downtime) are caught up.
+func materializeRecurringExpenses(ctx context.Context, dbInstance *sqlx.DB) (int64, error) {
+	today := time.Now().UTC().Format("2006-01-02")
+
+	queries := repository.New(dbInstance)
+	recurringExpenses, err := queries.GetDueRecurringExpenses(ctx, today)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due recurring expenses: %w", err)
+	}
+
+	var created int64
+	for _, recurringExpense := range recurringExpenses {
+		rows, err := materializeRecurringExpense(ctx, dbInstance, recurringExpense, today)
+		if err != nil {
+			return created, fmt.Errorf("failed to materialize recurring expense %s: %w", recurringExpense.ID, err)
+		}
+		created += rows
+	}
+
+	return created, nil
+}
+
+func materializeRecurringExpense(ctx context.Context, dbInstance *sqlx.DB, recurringExpense repository.RecurringExpense, today string) (int64, error) {
+	rule, err := recurrence.Parse(recurringExpense.Rule)
+	if err != nil {
+		return 0, err
+	}
+
+	start, err := time.Parse("2006-01-02", recurringExpense.StartDate)
+	if err != nil {
+		return 0, err
+	}
+
+	// Materialize up to today or the end date, whichever is earlier
+	until := today
+	if recurringExpense.EndDate != "" && recurringExpense.EndDate < until {
+		until = recurringExpense.EndDate
+	}
+
+	untilTime, err := time.Parse("2006-01-02", until)
+	if err != nil {
+		return 0, err
+	}
+
+	// Start right before the start date if nothing has been materialized yet
+	after := start.AddDate(0, 0, -1)
+	if recurringExpense.MaterializedUntil != "" {
+		after, err = time.Parse("2006-01-02", recurringExpense.MaterializedUntil)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := dbInstance.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	var created int64
+	for _, occurrence := range rule.Between(start, after, untilTime) {
+		currentTime := generator.NowISO8601()
+
+		rows, err := queries.CreateRecurringExpenseOccurrence(ctx, repository.CreateRecurringExpenseOccurrenceParams{
+			ID:                 generator.NewULID(),
+			BookID:             recurringExpense.BookID,
+			CategoryID:         recurringExpense.CategoryID,
+			PaymentMethodID:    recurringExpense.PaymentMethodID,
+			Date:               occurrence.Format("2006-01-02"),
+			Amount:             recurringExpense.Amount,
+			Remark:             recurringExpense.Remark,
+			CreatedAt:          currentTime,
+			UpdatedAt:          currentTime,
+			RecurringExpenseID: recurringExpense.ID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		created += rows
+	}
+
+	// The occurrences can push a budget past a threshold like any expense
+	if created > 0 {
+		if err := service.CreateBudgetAlerts(ctx, queries, recurringExpense.BookID); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := queries.UpdateRecurringExpenseMaterializedUntil(ctx, repository.UpdateRecurringExpenseMaterializedUntilParams{
+		ID:                recurringExpense.ID,
+		MaterializedUntil: until,
+	}); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return created, nil
+}
@@ -11,7 +11,9 @@ import (
 
 	"github.com/jljl1337/xpense/internal/db"
 	"github.com/jljl1337/xpense/internal/env"
+	"github.com/jljl1337/xpense/internal/format"
 	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/notify"
 	"github.com/jljl1337/xpense/internal/repository"
 )
 
@@ -84,5 +86,176 @@ func NewScheduler(dbInstance *sqlx.DB) (gocron.Scheduler, error) {
 		slog.Warn("Session cleanup cron job not scheduled")
 	}
 
+	// Book invite cleanup job
+	if env.BookInviteCleanupCronSchedule != "" {
+		_, err = scheduler.NewJob(
+			gocron.CronJob(
+				env.BookInviteCleanupCronSchedule,
+				false,
+			),
+			gocron.NewTask(
+				func() {
+					slog.Info("Starting book invite cleanup")
+
+					start := time.Now()
+
+					now := generator.NowISO8601()
+					queries := repository.New(dbInstance)
+					rows, err := queries.DeleteBookInviteByExpiresAt(context.Background(), now)
+					if err != nil {
+						slog.Error("Failed to cleanup expired book invites: " + err.Error())
+						return
+					}
+
+					slog.Info(fmt.Sprintf("Book invite cleanup completed in %s, %d invites deleted", time.Since(start).String(), rows))
+				},
+			),
+			gocron.WithSingletonMode(gocron.LimitModeReschedule),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create book invite cleanup cron job: %w", err)
+		}
+	} else {
+		slog.Warn("Book invite cleanup cron job not scheduled")
+	}
+
+	// Sign-in throttle cleanup job
+	if env.SignInThrottleCleanupCronSchedule != "" {
+		_, err = scheduler.NewJob(
+			gocron.CronJob(
+				env.SignInThrottleCleanupCronSchedule,
+				false,
+			),
+			gocron.NewTask(
+				func() {
+					slog.Info("Starting sign-in throttle cleanup")
+
+					start := time.Now()
+
+					queries := repository.New(dbInstance)
+					windowStart := format.TimeToISO8601(start.Add(-time.Duration(env.SignInThrottleWindowMin) * time.Minute))
+					throttleRows, err := queries.DeleteSignInThrottleByWindowStart(context.Background(), windowStart)
+					if err != nil {
+						slog.Error("Failed to cleanup stale sign-in throttles: " + err.Error())
+						return
+					}
+
+					retentionStart := format.TimeToISO8601(start.Add(-time.Duration(env.SignInFailureRetentionMin) * time.Minute))
+					failureRows, err := queries.DeleteSignInFailureByCreatedAt(context.Background(), retentionStart)
+					if err != nil {
+						slog.Error("Failed to cleanup old sign-in failures: " + err.Error())
+						return
+					}
+
+					slog.Info(fmt.Sprintf("Sign-in throttle cleanup completed in %s, %d throttles and %d failures deleted", time.Since(start).String(), throttleRows, failureRows))
+				},
+			),
+			gocron.WithSingletonMode(gocron.LimitModeReschedule),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sign-in throttle cleanup cron job: %w", err)
+		}
+	} else {
+		slog.Warn("Sign-in throttle cleanup cron job not scheduled")
+	}
+
+	// Recurring expense materialization job
+	if env.RecurringExpenseCronSchedule != "" {
+		_, err = scheduler.NewJob(
+			gocron.CronJob(
+				env.RecurringExpenseCronSchedule,
+				false,
+			),
+			gocron.NewTask(
+				func() {
+					slog.Info("Starting recurring expense materialization")
+
+					start := time.Now()
+
+					rows, err := materializeRecurringExpenses(context.Background(), dbInstance)
+					if err != nil {
+						slog.Error("Failed to materialize recurring expenses: " + err.Error())
+						return
+					}
+
+					slog.Info(fmt.Sprintf("Recurring expense materialization completed in %s, %d expenses created", time.Since(start).String(), rows))
+				},
+			),
+			gocron.WithSingletonMode(gocron.LimitModeReschedule),
+			// Catch up on the occurrences missed while the server was down
+			gocron.WithStartAt(gocron.WithStartImmediately()),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create recurring expense cron job: %w", err)
+		}
+	} else {
+		slog.Warn("Recurring expense cron job not scheduled")
+	}
+
+	// Exchange rate loading job
+	if env.ExchangeRateCronSchedule != "" && env.ExchangeRateCSVPath != "" {
+		_, err = scheduler.NewJob(
+			gocron.CronJob(
+				env.ExchangeRateCronSchedule,
+				false,
+			),
+			gocron.NewTask(
+				func() {
+					slog.Info("Starting exchange rate loading")
+
+					start := time.Now()
+
+					rows, err := loadExchangeRates(context.Background(), dbInstance, env.ExchangeRateCSVPath, env.ExchangeRateCSVBaseCurrency)
+					if err != nil {
+						slog.Error("Failed to load exchange rates: " + err.Error())
+						return
+					}
+
+					slog.Info(fmt.Sprintf("Exchange rate loading completed in %s, %d rates created or updated", time.Since(start).String(), rows))
+				},
+			),
+			gocron.WithSingletonMode(gocron.LimitModeReschedule),
+			gocron.WithStartAt(gocron.WithStartImmediately()),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create exchange rate cron job: %w", err)
+		}
+	} else {
+		slog.Warn("Exchange rate cron job not scheduled")
+	}
+
+	// Budget alert delivery job
+	if env.BudgetAlertCronSchedule != "" && env.BudgetAlertWebhookURL != "" {
+		notifier := notify.NewWebhookNotifier(env.BudgetAlertWebhookURL, 10*time.Second)
+
+		_, err = scheduler.NewJob(
+			gocron.CronJob(
+				env.BudgetAlertCronSchedule,
+				false,
+			),
+			gocron.NewTask(
+				func() {
+					start := time.Now()
+
+					rows, err := deliverBudgetAlerts(context.Background(), dbInstance, notifier, env.BudgetAlertMaxAttempts)
+					if err != nil {
+						slog.Error("Failed to deliver budget alerts: " + err.Error())
+						return
+					}
+
+					if rows > 0 {
+						slog.Info(fmt.Sprintf("Budget alert delivery completed in %s, %d alerts delivered", time.Since(start).String(), rows))
+					}
+				},
+			),
+			gocron.WithSingletonMode(gocron.LimitModeReschedule),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create budget alert cron job: %w", err)
+		}
+	} else {
+		slog.Warn("Budget alert cron job not scheduled")
+	}
+
 	return scheduler, nil
 }
@@ -0,0 +1,60 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const keySize = 32
+
+// LoadOrCreateKey returns the secret key in hex in the file, creating the file
+// with a random key if it does not exist.
+func LoadOrCreateKey(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return createKey(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key: %w", err)
+	}
+
+	if len(key) < keySize {
+		return nil, fmt.Errorf("key must be at least %d bytes", keySize)
+	}
+
+	return key, nil
+}
+
+func createKey(path string) ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+
+	// Fail instead of overwriting a key created in the meantime
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,22 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken returns the SHA-256 hash of a token in hex. The tokens are random,
+// so they do not need a salt or a slow hash like the passwords.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashTokenWithKey returns the HMAC-SHA256 of a token in hex, so that the hash
+// is useless without the key.
+func HashTokenWithKey(token string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,95 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The TOTP parameters of RFC 6238, the defaults supported by the authenticator
+// apps.
+const (
+	totpSecretSize = 20
+	totpDigits     = 6
+	totpPeriod     = 30
+	// totpSkew is the number of time steps accepted before and after the
+	// current one, for the clock drift of the devices
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret returns a random TOTP secret in base32.
+func NewTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPURI returns the otpauth URI of a TOTP secret, usually shown as a QR code
+// to be added to an authenticator app.
+func TOTPURI(issuer, accountName, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", strconv.Itoa(totpDigits))
+	values.Set("period", strconv.Itoa(totpPeriod))
+
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+accountName) + "?" + values.Encode()
+}
+
+// CheckTOTPCode returns the time step of the code if it is valid for the secret
+// at t. The steps up to lastStep are rejected, so that a code cannot be used
+// twice.
+func CheckTOTPCode(secret, code string, t time.Time, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := t.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// totpCode returns the HOTP code of RFC 4226 for the counter.
+func totpCode(key []byte, counter int64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for range totpDigits {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo)
+}
@@ -0,0 +1,172 @@
+package crypto
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the test vectors of RFC 4226 and RFC 6238,
+// "12345678901234567890" in base32.
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC4226(t *testing.T) {
+	// Appendix D of RFC 4226
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, code := range want {
+		if got := totpCode([]byte("12345678901234567890"), int64(counter)); got != code {
+			t.Errorf("totpCode(%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestCheckTOTPCodeRFC6238(t *testing.T) {
+	// Appendix B of RFC 6238 for SHA1, the codes being the last 6 of the 8
+	// digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tt := range tests {
+		step, ok := CheckTOTPCode(rfcSecret, tt.code, time.Unix(tt.unix, 0), 0)
+		if !ok {
+			t.Errorf("CheckTOTPCode(%s) at %d is invalid", tt.code, tt.unix)
+			continue
+		}
+
+		if want := tt.unix / 30; step != want {
+			t.Errorf("CheckTOTPCode(%s) at %d = step %d, want %d", tt.code, tt.unix, step, want)
+		}
+	}
+}
+
+func TestCheckTOTPCodeSkew(t *testing.T) {
+	// The code of the step 37037036 (1111111080 to 1111111109)
+	const code = "081804"
+
+	tests := []struct {
+		unix  int64
+		valid bool
+	}{
+		{unix: 1111111049, valid: false},
+		{unix: 1111111050, valid: true},
+		{unix: 1111111080, valid: true},
+		{unix: 1111111109, valid: true},
+		{unix: 1111111139, valid: true},
+		{unix: 1111111140, valid: false},
+	}
+
+	for _, tt := range tests {
+		step, ok := CheckTOTPCode(rfcSecret, code, time.Unix(tt.unix, 0), 0)
+		if ok != tt.valid {
+			t.Errorf("CheckTOTPCode at %d = %v, want %v", tt.unix, ok, tt.valid)
+		}
+
+		if ok && step != 37037036 {
+			t.Errorf("CheckTOTPCode at %d = step %d, want 37037036", tt.unix, step)
+		}
+	}
+}
+
+func TestCheckTOTPCodeReplay(t *testing.T) {
+	at := time.Unix(1111111109, 0)
+
+	step, ok := CheckTOTPCode(rfcSecret, "081804", at, 0)
+	if !ok {
+		t.Fatal("CheckTOTPCode is invalid")
+	}
+
+	// The same code is rejected once its step is used
+	if _, ok := CheckTOTPCode(rfcSecret, "081804", at, step); ok {
+		t.Error("CheckTOTPCode accepted a used step")
+	}
+
+	// So is the code of a previous step still within the skew
+	previous := totpCode([]byte("12345678901234567890"), step-1)
+	if _, ok := CheckTOTPCode(rfcSecret, previous, at, step); ok {
+		t.Error("CheckTOTPCode accepted a step before the last used one")
+	}
+
+	// But not the code of the next step
+	next := totpCode([]byte("12345678901234567890"), step+1)
+	if got, ok := CheckTOTPCode(rfcSecret, next, at, step); !ok || got != step+1 {
+		t.Errorf("CheckTOTPCode of the next step = %d, %v, want %d, true", got, ok, step+1)
+	}
+}
+
+func TestCheckTOTPCodeInvalid(t *testing.T) {
+	at := time.Unix(1111111109, 0)
+
+	for _, tt := range []struct {
+		secret string
+		code   string
+	}{
+		{secret: rfcSecret, code: "081805"},
+		{secret: rfcSecret, code: "81804"},
+		{secret: rfcSecret, code: "0081804"},
+		{secret: rfcSecret, code: ""},
+		{secret: rfcSecret, code: "08180a"},
+		{secret: "not base32!", code: "081804"},
+		{secret: rfcSecret + "=", code: "081804"},
+	} {
+		if _, ok := CheckTOTPCode(tt.secret, tt.code, at, 0); ok {
+			t.Errorf("CheckTOTPCode(%q, %q) is valid", tt.secret, tt.code)
+		}
+	}
+
+	// The secret is case insensitive
+	if _, ok := CheckTOTPCode(strings.ToLower(rfcSecret), "081804", at, 0); !ok {
+		t.Error("CheckTOTPCode with a lowercase secret is invalid")
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != totpSecretSize {
+		t.Errorf("NewTOTPSecret = %q, %d bytes, %v", secret, len(key), err)
+	}
+
+	other, _ := NewTOTPSecret()
+	if other == secret {
+		t.Error("NewTOTPSecret returned the same secret twice")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(TOTPURI("xpense", "alice smith", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/xpense:alice smith" {
+		t.Errorf("TOTPURI = %s", uri)
+	}
+
+	query := uri.Query()
+	for key, want := range map[string]string{
+		"secret":    "JBSWY3DPEHPK3PXP",
+		"issuer":    "xpense",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("TOTPURI %s = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const getUnhashedSessions = `
+	SELECT
+		s.id,
+		s.token_hash,
+		s.csrf_token_hash
+	FROM
+		session AS s
+	JOIN
+		unhashed_session AS u ON u.session_id = s.id;
+`
+
+const updateSessionTokenHashes = `
+	UPDATE
+		session
+	SET
+		token_hash = ?,
+		csrf_token_hash = ?
+	WHERE
+		id = ?;
+`
+
+const deleteUnhashedSessions = `
+	DELETE FROM
+		unhashed_session;
+`
+
+// HashSessionTokens replaces the plaintext tokens of the sessions created
+// before the tokens were hashed, so that they stay signed in. It returns the
+// number of sessions hashed.
+func HashSessionTokens(db *sqlx.DB, hashToken func(string) string) (int64, error) {
+	ctx := context.Background()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Only the tokens of the marked sessions are plaintext
+	sessions := []struct {
+		ID        string `db:"id"`
+		Token     string `db:"token_hash"`
+		CSRFToken string `db:"csrf_token_hash"`
+	}{}
+	if err := tx.SelectContext(ctx, &sessions, getUnhashedSessions); err != nil {
+		return 0, err
+	}
+
+	for _, session := range sessions {
+		if _, err := tx.ExecContext(ctx, updateSessionTokenHashes, hashToken(session.Token), hashToken(session.CSRFToken), session.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, deleteUnhashedSessions); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int64(len(sessions)), nil
+}
@@ -1,32 +1,62 @@
 package env
 
-import "net/http"
+import (
+	"net/http"
+	"net/netip"
+)
 
 var (
 	Version = "dev"
 
-	DbPath                     string
-	DbBusyTimeout              string
-	BackupDbPath               string
-	BackupCronSchedule         string
-	SessionCleanupCronSchedule string
-	LogLevel                   int
-	LogHealthCheck             bool
-	Port                       string
-	CORSOrigins                string
-	PasswordBcryptCost         int
-	SessionCookieName          string
-	SessionCookieHttpOnly      bool
-	SessionCookieSecure        bool
-	SessionTokenLength         int
-	SessionTokenCharset        string
-	SessionLifetimeMin         int
-	SessionRefreshThresholdMin int
-	PreSessionLifetimeMin      int
-	CSRFTokenLength            int
-	CSRFTokenCharset           string
-	PageSizeMax                int64
-	PageSizeDefault            int64
+	DbPath                            string
+	DbBusyTimeout                     string
+	BackupDbPath                      string
+	BackupCronSchedule                string
+	SessionCleanupCronSchedule        string
+	RecurringExpenseCronSchedule      string
+	ExchangeRateCSVPath               string
+	ExchangeRateCSVBaseCurrency       string
+	ExchangeRateCronSchedule          string
+	BudgetAlertWebhookURL             string
+	BudgetAlertThresholds             []int64
+	BudgetAlertCronSchedule           string
+	BudgetAlertMaxAttempts            int
+	BookInviteCleanupCronSchedule     string
+	SignInThrottleCleanupCronSchedule string
+	LogLevel                          int
+	LogHealthCheck                    bool
+	Port                              string
+	CORSOrigins                       string
+	TrustedProxies                    []netip.Prefix
+	PasswordBcryptCost                int
+	SessionCookieName                 string
+	SessionCookieHttpOnly             bool
+	SessionCookieSecure               bool
+	SessionTokenLength                int
+	SessionTokenCharset               string
+	SessionTokenHashKeyPath           string
+	SessionLifetimeMin                int
+	SessionRefreshThresholdMin        int
+	PreSessionLifetimeMin             int
+	BookInviteCodeLength              int
+	BookInviteCodeCharset             string
+	BookInviteLifetimeMin             int
+	APITokenLength                    int
+	APITokenCharset                   string
+	TOTPIssuer                        string
+	SecondFactorMaxAttempts           int
+	RecoveryCodeCount                 int
+	RecoveryCodeLength                int
+	RecoveryCodeCharset               string
+	SignInThrottleThreshold           int
+	SignInThrottleWindowMin           int
+	SignInLockoutBaseMin              int
+	SignInLockoutMaxMin               int
+	SignInFailureRetentionMin         int
+	PageSizeMax                       int64
+	PageSizeDefault                   int64
+	DefaultCurrency                   string
+	ImportFileSizeMax                 int64
 
 	SessionCookieSameSiteMode http.SameSite
 )
@@ -39,23 +69,50 @@ func MustSetConstants() {
 	BackupDbPath = MustGetString("SQLITE_BACKUP_DB_PATH", "data/backup/db/backup.db")
 	BackupCronSchedule = MustGetString("SQLITE_BACKUP_CRON_SCHEDULE", "0 0 * * *")
 	SessionCleanupCronSchedule = MustGetString("SESSION_CLEANUP_CRON_SCHEDULE", "0 0 * * 0")
+	RecurringExpenseCronSchedule = MustGetString("RECURRING_EXPENSE_CRON_SCHEDULE", "0 * * * *")
+	ExchangeRateCSVPath = MustGetString("EXCHANGE_RATE_CSV_PATH", "")
+	ExchangeRateCSVBaseCurrency = MustGetString("EXCHANGE_RATE_CSV_BASE_CURRENCY", "EUR")
+	ExchangeRateCronSchedule = MustGetString("EXCHANGE_RATE_CRON_SCHEDULE", "0 1 * * *")
+	BudgetAlertWebhookURL = MustGetString("BUDGET_ALERT_WEBHOOK_URL", "")
+	BudgetAlertThresholds = MustGetInt64List("BUDGET_ALERT_THRESHOLDS", "80,100")
+	BudgetAlertCronSchedule = MustGetString("BUDGET_ALERT_CRON_SCHEDULE", "* * * * *")
+	BudgetAlertMaxAttempts = MustGetInt("BUDGET_ALERT_MAX_ATTEMPTS", 5)
+	BookInviteCleanupCronSchedule = MustGetString("BOOK_INVITE_CLEANUP_CRON_SCHEDULE", "0 0 * * *")
+	SignInThrottleCleanupCronSchedule = MustGetString("SIGN_IN_THROTTLE_CLEANUP_CRON_SCHEDULE", "0 0 * * *")
 	LogLevel = MustGetInt("LOG_LEVEL", 0)
 	LogHealthCheck = MustGetBool("LOG_HEALTH_CHECK", false)
 	Port = MustGetString("PORT", "8080")
 	CORSOrigins = MustGetString("CORS_ORIGINS", "*")
+	TrustedProxies = MustGetPrefixList("TRUSTED_PROXIES", "")
 	PasswordBcryptCost = MustGetInt("PASSWORD_BCRYPT_COST", 12)
 	SessionCookieName = MustGetString("SESSION_COOKIE_NAME", "xpense_session_token")
 	SessionCookieHttpOnly = MustGetBool("SESSION_COOKIE_HTTP_ONLY", true)
 	SessionCookieSecure = MustGetBool("SESSION_COOKIE_SECURE", false)
 	SessionTokenLength = MustGetInt("SESSION_TOKEN_LENGTH", 32)
 	SessionTokenCharset = MustGetString("SESSION_TOKEN_CHARSET", "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+	SessionTokenHashKeyPath = MustGetString("SESSION_TOKEN_HASH_KEY_PATH", "data/live/secret/session_token_hash.key")
 	SessionLifetimeMin = MustGetInt("SESSION_LIFETIME_MIN", 60*24*7)
 	SessionRefreshThresholdMin = MustGetInt("SESSION_REFRESH_THRESHOLD_MIN", 60*24)
 	PreSessionLifetimeMin = MustGetInt("PRE_SESSION_LIFETIME_MIN", 15)
-	CSRFTokenLength = MustGetInt("CSRF_TOKEN_LENGTH", 32)
-	CSRFTokenCharset = MustGetString("CSRF_TOKEN_CHARSET", "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+	BookInviteCodeLength = MustGetInt("BOOK_INVITE_CODE_LENGTH", 16)
+	BookInviteCodeCharset = MustGetString("BOOK_INVITE_CODE_CHARSET", "ABCDEFGHJKLMNPQRSTUVWXYZ23456789")
+	BookInviteLifetimeMin = MustGetInt("BOOK_INVITE_LIFETIME_MIN", 60*24*7)
+	APITokenLength = MustGetInt("API_TOKEN_LENGTH", 40)
+	APITokenCharset = MustGetString("API_TOKEN_CHARSET", "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+	TOTPIssuer = MustGetString("TOTP_ISSUER", "Xpense")
+	SecondFactorMaxAttempts = MustGetInt("SECOND_FACTOR_MAX_ATTEMPTS", 5)
+	RecoveryCodeCount = MustGetInt("RECOVERY_CODE_COUNT", 10)
+	RecoveryCodeLength = MustGetInt("RECOVERY_CODE_LENGTH", 10)
+	RecoveryCodeCharset = MustGetString("RECOVERY_CODE_CHARSET", "abcdefghjkmnpqrstuvwxyz23456789")
+	SignInThrottleThreshold = MustGetInt("SIGN_IN_THROTTLE_THRESHOLD", 5)
+	SignInThrottleWindowMin = MustGetInt("SIGN_IN_THROTTLE_WINDOW_MIN", 15)
+	SignInLockoutBaseMin = MustGetInt("SIGN_IN_LOCKOUT_BASE_MIN", 1)
+	SignInLockoutMaxMin = MustGetInt("SIGN_IN_LOCKOUT_MAX_MIN", 60*24)
+	SignInFailureRetentionMin = MustGetInt("SIGN_IN_FAILURE_RETENTION_MIN", 60*24*30)
 	PageSizeMax = MustGetInt64("PAGE_SIZE_MAX", 100)
 	PageSizeDefault = MustGetInt64("PAGE_SIZE_DEFAULT", 10)
+	DefaultCurrency = MustGetString("DEFAULT_CURRENCY", "USD")
+	ImportFileSizeMax = MustGetInt64("IMPORT_FILE_SIZE_MAX", 10<<20)
 
 	sessionCookieSameSite := MustGetString("SESSION_COOKIE_SAME_SITE_MODE", "lax")
 	switch sessionCookieSameSite {
@@ -2,6 +2,7 @@ package env
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
 	"strings"
@@ -71,6 +72,80 @@ func GetInt64(key string, defaultValue int64) (int64, error) {
 	return intValue, nil
 }
 
+func MustGetInt64List(key string, defaultValue string) []int64 {
+	value, err := GetInt64List(key, defaultValue)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// GetInt64List returns a comma separated list of integers, an empty value
+// being an empty list.
+func GetInt64List(key string, defaultValue string) ([]int64, error) {
+	value, err := GetString(key, defaultValue)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []int64{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		intValue, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, intValue)
+	}
+	return list, nil
+}
+
+func MustGetPrefixList(key string, defaultValue string) []netip.Prefix {
+	value, err := GetPrefixList(key, defaultValue)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// GetPrefixList returns a comma separated list of IP addresses or CIDR
+// prefixes, an address being a prefix of its own, and an empty value being an
+// empty list.
+func GetPrefixList(key string, defaultValue string) ([]netip.Prefix, error) {
+	value, err := GetString(key, defaultValue)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []netip.Prefix{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, prefix.Masked())
+	}
+	return list, nil
+}
+
 func MustGetString(key string, defaultValue string) string {
 	value, err := GetString(key, defaultValue)
 	if err != nil {
@@ -0,0 +1,28 @@
+package env
+
+import "testing"
+
+func TestGetPrefixList(t *testing.T) {
+	t.Setenv("TEST_TRUSTED_PROXIES", " 10.0.0.1, 192.168.1.7/16,::1 ,, fd00::/8")
+
+	got, err := GetPrefixList("TEST_TRUSTED_PROXIES", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"10.0.0.1/32", "192.168.0.0/16", "::1/128", "fd00::/8"}
+	if len(got) != len(want) {
+		t.Fatalf("GetPrefixList = %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("GetPrefixList[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	t.Setenv("TEST_TRUSTED_PROXIES", "10.0.0.1,proxy")
+	if _, err := GetPrefixList("TEST_TRUSTED_PROXIES", ""); err == nil {
+		t.Error("GetPrefixList with an invalid address returned no error")
+	}
+}
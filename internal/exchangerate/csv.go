@@ -0,0 +1,93 @@
+package exchangerate
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Rate struct {
+	Date          string
+	BaseCurrency  string
+	QuoteCurrency string
+	Rate          float64
+}
+
+// ParseCSV parses exchange rates in the ECB format, i.e. a header row of
+// "Date" followed by the quote currencies, and one row of rates per date, each
+// rate being the amount of the quote currency for 1 unit of the base currency.
+//
+// Dates can be either "2006-01-02" (historical files) or "02 January 2006"
+// (daily file), empty and "N/A" rates are skipped, and rates that are not
+// positive are invalid. A rate of 1 from the base currency to itself is added
+// for every date, so that cross rates between any two currencies can be
+// derived.
+func ParseCSV(r io.Reader, baseCurrency string) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	if len(header) < 2 || !strings.EqualFold(strings.TrimSpace(header[0]), "Date") {
+		return nil, errors.New("first column must be Date")
+	}
+
+	currencies := make([]string, len(header))
+	for i, currency := range header[1:] {
+		currencies[i+1] = strings.ToUpper(strings.TrimSpace(currency))
+	}
+
+	rates := []Rate{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		date, err := parseDate(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, err
+		}
+
+		rates = append(rates, Rate{Date: date, BaseCurrency: baseCurrency, QuoteCurrency: baseCurrency, Rate: 1})
+
+		for i := 1; i < len(record) && i < len(currencies); i++ {
+			value := strings.TrimSpace(record[i])
+			if currencies[i] == "" || value == "" || value == "N/A" {
+				continue
+			}
+
+			// Rates are divided by each other to derive cross rates
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil || !(rate > 0) || math.IsInf(rate, 0) {
+				return nil, fmt.Errorf("invalid rate %q for %s on %s", value, currencies[i], date)
+			}
+
+			rates = append(rates, Rate{Date: date, BaseCurrency: baseCurrency, QuoteCurrency: currencies[i], Rate: rate})
+		}
+	}
+
+	return rates, nil
+}
+
+func parseDate(s string) (string, error) {
+	for _, layout := range []string{"2006-01-02", "02 January 2006", "2 January 2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid date: %s", s)
+}
@@ -0,0 +1,46 @@
+package exchangerate
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	rates, err := ParseCSV(strings.NewReader("Date, USD, JPY, XYZ, \n2024-03-01, 1.0845, 162.57, N/A, \n04 March 2024, 1.0850, , 2, \n"), "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Rate{
+		{Date: "2024-03-01", BaseCurrency: "EUR", QuoteCurrency: "EUR", Rate: 1},
+		{Date: "2024-03-01", BaseCurrency: "EUR", QuoteCurrency: "USD", Rate: 1.0845},
+		{Date: "2024-03-01", BaseCurrency: "EUR", QuoteCurrency: "JPY", Rate: 162.57},
+		{Date: "2024-03-04", BaseCurrency: "EUR", QuoteCurrency: "EUR", Rate: 1},
+		{Date: "2024-03-04", BaseCurrency: "EUR", QuoteCurrency: "USD", Rate: 1.085},
+		{Date: "2024-03-04", BaseCurrency: "EUR", QuoteCurrency: "XYZ", Rate: 2},
+	}
+
+	if !slices.Equal(rates, want) {
+		t.Errorf("ParseCSV = %+v, want %+v", rates, want)
+	}
+}
+
+func TestParseCSVInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"USD\n",
+		"Day, USD\n2024-03-01, 1\n",
+		"Date, USD\n2024-13-01, 1\n",
+		"Date, USD\n2024-03-01, one\n",
+		// The rates are divided by each other
+		"Date, USD\n2024-03-01, 0\n",
+		"Date, USD\n2024-03-01, -1.5\n",
+		"Date, USD\n2024-03-01, NaN\n",
+		"Date, USD\n2024-03-01, Inf\n",
+	} {
+		if rates, err := ParseCSV(strings.NewReader(input), "EUR"); err == nil {
+			t.Errorf("ParseCSV(%q) = %+v, want error", input, rates)
+		}
+	}
+}
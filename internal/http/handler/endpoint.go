@@ -19,10 +19,20 @@ func NewEndpointHandler(service *service.EndpointService) *EndpointHandler {
 func (h *EndpointHandler) RegisterRoutes(mux *http.ServeMux) {
 	h.registerAuthRoutes(mux)
 	h.registerUserRoutes(mux)
+	h.registerTwoFactorRoutes(mux)
+	h.registerAPITokenRoutes(mux)
 	h.registerBookRoutes(mux)
+	h.registerBookMemberRoutes(mux)
+	h.registerBookInviteRoutes(mux)
 	h.registerCategoryRoutes(mux)
 	h.registerPaymentMethodRoutes(mux)
+	h.registerTagRoutes(mux)
 	h.registerExpenseRoutes(mux)
+	h.registerRecurringExpenseRoutes(mux)
+	h.registerBudgetRoutes(mux)
+	h.registerImportRoutes(mux)
+	h.registerExportRoutes(mux)
+	h.registerReportRoutes(mux)
 	h.registerHealthCheckRoutes(mux)
 	h.registerVersionRoutes(mux)
 }
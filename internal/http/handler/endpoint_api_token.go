@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/jljl1337/xpense/internal/http/common"
+	"github.com/jljl1337/xpense/internal/http/middleware"
+	"github.com/jljl1337/xpense/internal/service"
+)
+
+type createAPITokenRequest struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+	// ExpiresAt is in RFC 3339 format, the token never expires if empty
+	ExpiresAt string `json:"expiresAt"`
+}
+
+func (h *EndpointHandler) registerAPITokenRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /users/me/api-tokens", h.createAPIToken)
+	mux.HandleFunc("GET /users/me/api-tokens", h.getAPITokens)
+	mux.HandleFunc("DELETE /users/me/api-tokens/{id}", h.deleteAPIToken)
+}
+
+// createAPIToken creates an API token, the token itself is only in this
+// response.
+func (h *EndpointHandler) createAPIToken(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req createAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "API token name is required", http.StatusBadRequest)
+		return
+	}
+
+	if !service.IsValidAPITokenScope(req.Scope) {
+		http.Error(w, "Scope must be read-only or read-write", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	apiToken, err := h.service.CreateAPIToken(ctx, userID, req.Name, req.Scope, req.ExpiresAt)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(apiToken)
+}
+
+func (h *EndpointHandler) getAPITokens(w http.ResponseWriter, r *http.Request) {
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	apiTokens, err := h.service.GetAPITokensByUserID(ctx, userID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apiTokens)
+}
+
+func (h *EndpointHandler) deleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	apiTokenID := r.PathValue("id")
+	if apiTokenID == "" {
+		http.Error(w, "API token ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.DeleteAPITokenByID(ctx, userID, apiTokenID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("API token revoked successfully"))
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/jljl1337/xpense/internal/env"
 	"github.com/jljl1337/xpense/internal/http/common"
 	"github.com/jljl1337/xpense/internal/http/middleware"
+	"github.com/jljl1337/xpense/internal/service"
 )
 
 type signUpSignInRequest struct {
@@ -15,17 +16,31 @@ type signUpSignInRequest struct {
 	Password string `json:"password"`
 }
 
+type signInSecondFactorRequest struct {
+	// Code is the TOTP code, RecoveryCode is used if it is empty
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
 type signInPreSessionCSRFTokenResponse struct {
 	CSRFToken string `json:"csrfToken"`
 }
 
+type signInResponse struct {
+	CSRFToken            string `json:"csrfToken"`
+	SecondFactorRequired bool   `json:"secondFactorRequired"`
+}
+
 func (h *EndpointHandler) registerAuthRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /auth/sign-up", h.signUp)
 	mux.HandleFunc("POST /auth/pre-session", h.preSession)
 	mux.HandleFunc("POST /auth/sign-in", h.signIn)
+	mux.HandleFunc("POST /auth/sign-in/second-factor", h.signInSecondFactor)
 	mux.HandleFunc("POST /auth/sign-out", h.signOut)
 	mux.HandleFunc("POST /auth/sign-out-all", h.signOutAll)
 	mux.HandleFunc("GET /auth/csrf-token", h.csrfToken)
+	mux.HandleFunc("GET /auth/sessions", h.getSessions)
+	mux.HandleFunc("DELETE /auth/sessions/{id}", h.revokeSession)
 }
 
 func (h *EndpointHandler) signUp(w http.ResponseWriter, r *http.Request) {
@@ -54,7 +69,7 @@ func (h *EndpointHandler) signUp(w http.ResponseWriter, r *http.Request) {
 
 func (h *EndpointHandler) preSession(w http.ResponseWriter, r *http.Request) {
 	// Process the request
-	sessionToken, CSRFToken, err := h.service.GetPreSession(r.Context())
+	sessionToken, CSRFToken, err := h.service.GetPreSession(r.Context(), r.UserAgent(), middleware.GetClientIP(r))
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -95,18 +110,61 @@ func (h *EndpointHandler) signIn(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Process the request
-	sessionToken, CSRFToken, err := h.service.SignIn(r.Context(), preSessionToken.Value, preSessionCSRFToken, req.Username, req.Password)
+	result, err := h.service.SignIn(r.Context(), preSessionToken.Value, preSessionCSRFToken, req.Username, req.Password, r.UserAgent(), middleware.GetClientIP(r))
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
 	}
 
 	// Respond to the client
-	http.SetCookie(w, NewActiveSessionCookie(sessionToken))
+	writeSignInResponse(w, result)
+}
+
+// signInSecondFactor finishes a sign-in waiting for the second factor, on the
+// same pre-session.
+func (h *EndpointHandler) signInSecondFactor(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	preSessionToken, err := r.Cookie(env.SessionCookieName)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	preSessionCSRFToken := r.Header.Get("X-CSRF-Token")
+	if preSessionCSRFToken == "" {
+		http.Error(w, "CSRF token is required", http.StatusUnauthorized)
+		return
+	}
+
+	var req signInSecondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Code == "" && req.RecoveryCode == "" {
+		http.Error(w, "Code or recovery code is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	result, err := h.service.SignInSecondFactor(r.Context(), preSessionToken.Value, preSessionCSRFToken, req.Code, req.RecoveryCode, r.UserAgent(), middleware.GetClientIP(r))
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	writeSignInResponse(w, result)
+}
+
+func writeSignInResponse(w http.ResponseWriter, result *service.SignInResult) {
+	http.SetCookie(w, NewActiveSessionCookie(result.SessionToken))
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(signInPreSessionCSRFTokenResponse{
-		CSRFToken: CSRFToken,
+	json.NewEncoder(w).Encode(signInResponse{
+		CSRFToken:            result.CSRFToken,
+		SecondFactorRequired: result.SecondFactorRequired,
 	})
 }
 
@@ -174,3 +232,60 @@ func (h *EndpointHandler) csrfToken(w http.ResponseWriter, r *http.Request) {
 		CSRFToken: CSRFToken,
 	})
 }
+
+// getSessions lists the active sessions of the user, marking the session of
+// the request.
+func (h *EndpointHandler) getSessions(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	sessionToken := ""
+	// The request may use an API token instead of a session
+	if cookie, err := r.Cookie(env.SessionCookieName); err == nil {
+		sessionToken = cookie.Value
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	sessions, err := h.service.GetSessionsByUserID(ctx, userID, sessionToken)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+func (h *EndpointHandler) revokeSession(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	sessionID := r.PathValue("id")
+	if sessionID == "" {
+		http.Error(w, "Session ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.RevokeSessionByID(ctx, userID, sessionID); err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Session revoked successfully"))
+}
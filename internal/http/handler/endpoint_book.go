@@ -11,7 +11,13 @@ import (
 	"github.com/jljl1337/xpense/internal/http/middleware"
 )
 
-type createUpdateBookRequest struct {
+type createBookRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Currency    string `json:"currency"`
+}
+
+type updateBookRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 }
@@ -31,7 +37,7 @@ func (h *EndpointHandler) registerBookRoutes(mux *http.ServeMux) {
 
 func (h *EndpointHandler) createBook(w http.ResponseWriter, r *http.Request) {
 	// Input validation
-	var req createUpdateBookRequest
+	var req createBookRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
@@ -42,6 +48,10 @@ func (h *EndpointHandler) createBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Currency == "" {
+		req.Currency = env.DefaultCurrency
+	}
+
 	// Process the request
 	ctx := r.Context()
 	userID, err := middleware.GetUserIDFromContext(ctx)
@@ -51,7 +61,7 @@ func (h *EndpointHandler) createBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.CreateBook(ctx, userID, req.Name, req.Description)
+	err = h.service.CreateBook(ctx, userID, req.Name, req.Description, req.Currency)
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -147,7 +157,7 @@ func (h *EndpointHandler) getBook(w http.ResponseWriter, r *http.Request) {
 
 func (h *EndpointHandler) updateBook(w http.ResponseWriter, r *http.Request) {
 	// Input validation
-	var req createUpdateBookRequest
+	var req updateBookRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/jljl1337/xpense/internal/http/common"
+	"github.com/jljl1337/xpense/internal/http/middleware"
+	"github.com/jljl1337/xpense/internal/service"
+)
+
+type createBookInviteRequest struct {
+	Role string `json:"role"`
+}
+
+type redeemBookInviteRequest struct {
+	Code string `json:"code"`
+}
+
+type redeemBookInviteResponse struct {
+	BookID string `json:"bookID"`
+}
+
+func (h *EndpointHandler) registerBookInviteRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /books/{id}/invites", h.createBookInvite)
+	mux.HandleFunc("GET /books/{id}/invites", h.getBookInvites)
+	mux.HandleFunc("DELETE /books/{id}/invites/{inviteID}", h.deleteBookInvite)
+	mux.HandleFunc("POST /invites/redeem", h.redeemBookInvite)
+}
+
+func (h *EndpointHandler) createBookInvite(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req createBookInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if !service.IsValidBookRole(req.Role) {
+		http.Error(w, "Role must be owner, editor or viewer", http.StatusBadRequest)
+		return
+	}
+
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	invite, err := h.service.CreateBookInvite(ctx, userID, bookID, req.Role)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invite)
+}
+
+func (h *EndpointHandler) getBookInvites(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	invites, err := h.service.GetBookInvitesByBookID(ctx, userID, bookID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invites)
+}
+
+func (h *EndpointHandler) deleteBookInvite(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	inviteID := r.PathValue("inviteID")
+	if inviteID == "" {
+		http.Error(w, "Invite ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.DeleteBookInviteByID(ctx, userID, bookID, inviteID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Book invite revoked successfully"))
+}
+
+func (h *EndpointHandler) redeemBookInvite(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req redeemBookInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	bookID, err := h.service.RedeemBookInvite(ctx, userID, req.Code)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(redeemBookInviteResponse{
+		BookID: bookID,
+	})
+}
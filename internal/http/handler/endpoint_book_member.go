@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/jljl1337/xpense/internal/http/common"
+	"github.com/jljl1337/xpense/internal/http/middleware"
+	"github.com/jljl1337/xpense/internal/service"
+)
+
+type addBookMemberRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+type updateBookMemberRequest struct {
+	Role string `json:"role"`
+}
+
+func (h *EndpointHandler) registerBookMemberRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /books/{id}/members", h.addBookMember)
+	mux.HandleFunc("GET /books/{id}/members", h.getBookMembers)
+	mux.HandleFunc("PUT /books/{id}/members/{userID}", h.updateBookMember)
+	mux.HandleFunc("DELETE /books/{id}/members/{userID}", h.removeBookMember)
+}
+
+func (h *EndpointHandler) addBookMember(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req addBookMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Username == "" {
+		http.Error(w, "Username is required", http.StatusBadRequest)
+		return
+	}
+
+	if !service.IsValidBookRole(req.Role) {
+		http.Error(w, "Role must be owner, editor or viewer", http.StatusBadRequest)
+		return
+	}
+
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.AddBookMember(ctx, userID, bookID, req.Username, req.Role)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("Book member added successfully"))
+}
+
+func (h *EndpointHandler) getBookMembers(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	members, err := h.service.GetBookMembersByBookID(ctx, userID, bookID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// updateBookMember changes the role of a member, changing it to owner
+// transfers the ownership of the book.
+func (h *EndpointHandler) updateBookMember(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req updateBookMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if !service.IsValidBookRole(req.Role) {
+		http.Error(w, "Role must be owner, editor or viewer", http.StatusBadRequest)
+		return
+	}
+
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	memberUserID := r.PathValue("userID")
+	if memberUserID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.UpdateBookMemberRole(ctx, userID, bookID, memberUserID, req.Role)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Book member updated successfully"))
+}
+
+// removeBookMember removes a member from a book, members can remove
+// themselves to leave the book.
+func (h *EndpointHandler) removeBookMember(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	memberUserID := r.PathValue("userID")
+	if memberUserID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.RemoveBookMember(ctx, userID, bookID, memberUserID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Book member removed successfully"))
+}
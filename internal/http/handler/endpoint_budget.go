@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/jljl1337/xpense/internal/http/common"
+	"github.com/jljl1337/xpense/internal/http/middleware"
+	"github.com/jljl1337/xpense/internal/money"
+	"github.com/jljl1337/xpense/internal/service"
+)
+
+type createBudgetRequest struct {
+	BookID     string        `json:"bookID"`
+	CategoryID string        `json:"categoryID"`
+	Amount     money.Decimal `json:"amount"`
+	Period     string        `json:"period"`
+	Rollover   bool          `json:"rollover"`
+}
+
+type updateBudgetRequest struct {
+	CategoryID string        `json:"categoryID"`
+	Amount     money.Decimal `json:"amount"`
+	Period     string        `json:"period"`
+	Rollover   bool          `json:"rollover"`
+}
+
+func (h *EndpointHandler) registerBudgetRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /budgets", h.createBudget)
+	mux.HandleFunc("GET /budgets", h.getBudgetsByBookID)
+	mux.HandleFunc("GET /budgets/{id}", h.getBudgetByID)
+	mux.HandleFunc("PUT /budgets/{id}", h.updateBudget)
+	mux.HandleFunc("DELETE /budgets/{id}", h.deleteBudget)
+	mux.HandleFunc("GET /budgets/{id}/alerts", h.getBudgetAlerts)
+	mux.HandleFunc("GET /books/{id}/budgets/status", h.getBudgetStatuses)
+}
+
+func (h *EndpointHandler) createBudget(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req createBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.BookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if !service.IsValidBudgetPeriod(req.Period) {
+		http.Error(w, "Period must be weekly, monthly or yearly", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.CreateBudget(ctx, userID, req.BookID, service.BudgetParams{
+		CategoryID: req.CategoryID,
+		Amount:     req.Amount,
+		Period:     req.Period,
+		Rollover:   req.Rollover,
+	})
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("Budget created successfully"))
+}
+
+func (h *EndpointHandler) getBudgetsByBookID(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.URL.Query().Get("book-id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	budgets, err := h.service.GetBudgetsByBookID(ctx, userID, bookID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budgets)
+}
+
+func (h *EndpointHandler) getBudgetByID(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	budgetID := r.PathValue("id")
+	if budgetID == "" {
+		http.Error(w, "Budget ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	budget, err := h.service.GetBudgetByID(ctx, userID, budgetID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget)
+}
+
+func (h *EndpointHandler) updateBudget(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req updateBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if !service.IsValidBudgetPeriod(req.Period) {
+		http.Error(w, "Period must be weekly, monthly or yearly", http.StatusBadRequest)
+		return
+	}
+
+	budgetID := r.PathValue("id")
+	if budgetID == "" {
+		http.Error(w, "Budget ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.UpdateBudgetByID(ctx, userID, budgetID, service.BudgetParams{
+		CategoryID: req.CategoryID,
+		Amount:     req.Amount,
+		Period:     req.Period,
+		Rollover:   req.Rollover,
+	})
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Budget updated successfully"))
+}
+
+func (h *EndpointHandler) deleteBudget(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	budgetID := r.PathValue("id")
+	if budgetID == "" {
+		http.Error(w, "Budget ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.DeleteBudgetByID(ctx, userID, budgetID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Budget deleted successfully"))
+}
+
+// getBudgetStatuses returns the spending of the budgets of a book in the
+// current period and the previous ones, the periods query parameter being the
+// total number of periods.
+func (h *EndpointHandler) getBudgetStatuses(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	periods := service.DefaultBudgetStatusPeriods
+	if value := r.URL.Query().Get("periods"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > service.MaxBudgetStatusPeriods {
+			http.Error(w, "Periods must be a number between 1 and "+strconv.Itoa(service.MaxBudgetStatusPeriods), http.StatusBadRequest)
+			return
+		}
+		periods = parsed
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	statuses, err := h.service.GetBudgetStatusesByBookID(ctx, userID, bookID, periods)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+func (h *EndpointHandler) getBudgetAlerts(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	budgetID := r.PathValue("id")
+	if budgetID == "" {
+		http.Error(w, "Budget ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	alerts, err := h.service.GetBudgetAlertsByBudgetID(ctx, userID, budgetID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alerts)
+}
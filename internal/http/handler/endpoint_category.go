@@ -13,11 +13,20 @@ type createCategoryRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	BookID      string `json:"bookID"`
+	// ParentID is empty for a root category
+	ParentID string `json:"parentID"`
 }
 
 type updateCategoryRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// ParentID keeps the current parent if omitted, and moves the category to
+	// the root if empty
+	ParentID *string `json:"parentID"`
+}
+
+type mergeCategoryRequest struct {
+	TargetID string `json:"targetID"`
 }
 
 func (h *EndpointHandler) registerCategoryRoutes(mux *http.ServeMux) {
@@ -26,6 +35,9 @@ func (h *EndpointHandler) registerCategoryRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /categories/{id}", h.getCategoryByID)
 	mux.HandleFunc("PUT /categories/{id}", h.updateCategory)
 	mux.HandleFunc("DELETE /categories/{id}", h.deleteCategory)
+	mux.HandleFunc("POST /categories/{id}/merge", h.mergeCategory)
+	mux.HandleFunc("POST /categories/{id}/archive", h.archiveCategory)
+	mux.HandleFunc("POST /categories/{id}/unarchive", h.unarchiveCategory)
 }
 
 func (h *EndpointHandler) createCategory(w http.ResponseWriter, r *http.Request) {
@@ -50,7 +62,7 @@ func (h *EndpointHandler) createCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.service.CreateCategory(ctx, userID, req.BookID, req.Name, req.Description)
+	err = h.service.CreateCategory(ctx, userID, req.BookID, req.Name, req.Description, req.ParentID)
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -69,6 +81,8 @@ func (h *EndpointHandler) getCategoriesByBookID(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	includeArchived := r.URL.Query().Get("include-archived") == "true"
+
 	// Process the request
 	ctx := r.Context()
 	userID, err := middleware.GetUserIDFromContext(ctx)
@@ -78,7 +92,7 @@ func (h *EndpointHandler) getCategoriesByBookID(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	categories, err := h.service.GetCategoriesByBookID(r.Context(), userID, bookID)
+	categories, err := h.service.GetCategoriesByBookID(r.Context(), userID, bookID, includeArchived)
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -145,7 +159,7 @@ func (h *EndpointHandler) updateCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.service.UpdateCategoryByID(ctx, userID, categoryID, req.Name, req.Description)
+	err = h.service.UpdateCategoryByID(ctx, userID, categoryID, req.Name, req.Description, req.ParentID)
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -164,6 +178,9 @@ func (h *EndpointHandler) deleteCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// The expenses are moved to this category before deleting, if given
+	reassignTo := r.URL.Query().Get("reassign-to")
+
 	// Process the request
 	ctx := r.Context()
 	userID, err := middleware.GetUserIDFromContext(ctx)
@@ -173,7 +190,7 @@ func (h *EndpointHandler) deleteCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.service.DeleteCategoryByID(ctx, userID, categoryID)
+	err = h.service.DeleteCategoryByID(ctx, userID, categoryID, reassignTo)
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -183,3 +200,98 @@ func (h *EndpointHandler) deleteCategory(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Category deleted successfully"))
 }
+
+func (h *EndpointHandler) mergeCategory(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req mergeCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.TargetID == "" {
+		http.Error(w, "Target category ID is required", http.StatusBadRequest)
+		return
+	}
+
+	categoryID := r.PathValue("id")
+	if categoryID == "" {
+		http.Error(w, "Category ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.MergeCategory(ctx, userID, categoryID, req.TargetID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Category merged successfully"))
+}
+
+func (h *EndpointHandler) archiveCategory(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	categoryID := r.PathValue("id")
+	if categoryID == "" {
+		http.Error(w, "Category ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.ArchiveCategoryByID(ctx, userID, categoryID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Category archived successfully"))
+}
+
+func (h *EndpointHandler) unarchiveCategory(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	categoryID := r.PathValue("id")
+	if categoryID == "" {
+		http.Error(w, "Category ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.UnarchiveCategoryByID(ctx, userID, categoryID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Category unarchived successfully"))
+}
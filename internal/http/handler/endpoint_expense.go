@@ -5,28 +5,51 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/jljl1337/xpense/internal/env"
 	"github.com/jljl1337/xpense/internal/http/common"
 	"github.com/jljl1337/xpense/internal/http/middleware"
+	"github.com/jljl1337/xpense/internal/money"
+	"github.com/jljl1337/xpense/internal/service"
 )
 
 type createExpenseRequest struct {
-	BookID          string  `json:"bookID"`
-	CategoryID      string  `json:"categoryID"`
-	PaymentMethodID string  `json:"paymentMethodID"`
-	Date            string  `json:"date"`
-	Amount          float64 `json:"amount"`
-	Remark          string  `json:"remark"`
+	BookID string `json:"bookID"`
+	updateExpenseRequest
 }
 
 type updateExpenseRequest struct {
-	CategoryID      string  `json:"categoryID"`
-	PaymentMethodID string  `json:"paymentMethodID"`
-	Date            string  `json:"date"`
-	Amount          float64 `json:"amount"`
-	Remark          string  `json:"remark"`
+	CategoryID      string         `json:"categoryID"`
+	PaymentMethodID string         `json:"paymentMethodID"`
+	Date            string         `json:"date"`
+	Amount          *money.Decimal `json:"amount"`
+	Remark          string         `json:"remark"`
+	// Type keeps the current type and destination payment method on update if
+	// it is empty or missing
+	Type                       string `json:"type"`
+	DestinationPaymentMethodID string `json:"destinationPaymentMethodID"`
+	// OriginalCurrency keeps the current original currency and amount on
+	// update if it is null or missing, and removes them if it is empty
+	OriginalCurrency *string        `json:"originalCurrency"`
+	OriginalAmount   *money.Decimal `json:"originalAmount"`
+	// TagIDs keeps the current tags on update if it is null or missing
+	TagIDs []string `json:"tagIDs"`
+}
+
+func (req updateExpenseRequest) params() service.ExpenseParams {
+	return service.ExpenseParams{
+		CategoryID:                 req.CategoryID,
+		PaymentMethodID:            req.PaymentMethodID,
+		Date:                       req.Date,
+		Amount:                     req.Amount,
+		Remark:                     req.Remark,
+		Type:                       req.Type,
+		DestinationPaymentMethodID: req.DestinationPaymentMethodID,
+		OriginalCurrency:           req.OriginalCurrency,
+		OriginalAmount:             req.OriginalAmount,
+		TagIDs:                     req.TagIDs,
+	}
 }
 
 func (h *EndpointHandler) registerExpenseRoutes(mux *http.ServeMux) {
@@ -51,11 +74,26 @@ func (h *EndpointHandler) createExpense(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+	if !service.IsValidExpenseDate(req.Date) {
 		http.Error(w, "Date must be a valid YYYY-MM-DD", http.StatusBadRequest)
 		return
 	}
 
+	// Entries without a type are expenses
+	if req.Type == "" {
+		req.Type = service.ExpenseTypeExpense
+	}
+
+	if !service.IsValidExpenseType(req.Type) {
+		http.Error(w, "Type must be one of expense, income or transfer", http.StatusBadRequest)
+		return
+	}
+
+	if req.Amount == nil && req.OriginalAmount == nil {
+		http.Error(w, "Amount or original amount is required", http.StatusBadRequest)
+		return
+	}
+
 	// Process the request
 	ctx := r.Context()
 	userID, err := middleware.GetUserIDFromContext(ctx)
@@ -65,7 +103,7 @@ func (h *EndpointHandler) createExpense(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.service.CreateExpense(ctx, userID, req.BookID, req.CategoryID, req.PaymentMethodID, req.Date, req.Amount, req.Remark)
+	err = h.service.CreateExpense(ctx, userID, req.BookID, req.params())
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -83,9 +121,11 @@ func (h *EndpointHandler) getExpensesCountByBookID(w http.ResponseWriter, r *htt
 		http.Error(w, "Book ID is required", http.StatusBadRequest)
 		return
 	}
-	categoryID := r.URL.Query().Get("category-id")
-	paymentMethodID := r.URL.Query().Get("payment-method-id")
-	remark := r.URL.Query().Get("remark")
+
+	filter, ok := parseExpenseFilter(w, r)
+	if !ok {
+		return
+	}
 
 	// Process the request
 	ctx := r.Context()
@@ -96,7 +136,7 @@ func (h *EndpointHandler) getExpensesCountByBookID(w http.ResponseWriter, r *htt
 		return
 	}
 
-	count, err := h.service.GetExpensesCountByBookID(r.Context(), userID, bookID, categoryID, paymentMethodID, remark)
+	count, err := h.service.GetExpensesCountByBookID(r.Context(), userID, bookID, filter)
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -114,9 +154,23 @@ func (h *EndpointHandler) getExpensesByBookID(w http.ResponseWriter, r *http.Req
 		http.Error(w, "Book ID is required", http.StatusBadRequest)
 		return
 	}
-	categoryID := r.URL.Query().Get("category-id")
-	paymentMethodID := r.URL.Query().Get("payment-method-id")
-	remark := r.URL.Query().Get("remark")
+
+	filter, ok := parseExpenseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	// Pages by cursor are requested with the cursor query parameter, which is
+	// empty for the first page
+	if r.URL.Query().Has("cursor") {
+		h.getExpensesByBookIDAfterCursor(w, r, bookID, filter)
+		return
+	}
+
+	sort, ok := parseExpenseSort(w, r)
+	if !ok {
+		return
+	}
 
 	page, err := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64)
 	if err != nil || page < 1 {
@@ -137,7 +191,7 @@ func (h *EndpointHandler) getExpensesByBookID(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	expenses, err := h.service.GetExpensesByBookID(r.Context(), userID, bookID, categoryID, paymentMethodID, remark, page, pageSize)
+	expenses, err := h.service.GetExpensesByBookID(r.Context(), userID, bookID, filter, sort, page, pageSize)
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -148,6 +202,45 @@ func (h *EndpointHandler) getExpensesByBookID(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(expenses)
 }
 
+// getExpensesByBookIDAfterCursor responds with the page of expenses after the
+// cursor and the cursor of the next page, sorted by date only.
+func (h *EndpointHandler) getExpensesByBookIDAfterCursor(w http.ResponseWriter, r *http.Request, bookID string, filter service.ExpenseFilter) {
+	// Input validation
+	if sort := r.URL.Query().Get("sort"); sort != "" && sort != service.ExpenseSortDate {
+		http.Error(w, "Cursor pagination only supports sorting by date", http.StatusBadRequest)
+		return
+	}
+
+	ascending, ok := parseSortOrder(w, r)
+	if !ok {
+		return
+	}
+
+	pageSize, err := strconv.ParseInt(r.URL.Query().Get("page-size"), 10, 64)
+	if err != nil || pageSize < 1 || pageSize > env.PageSizeMax {
+		pageSize = env.PageSizeDefault
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page, err := h.service.GetExpensesByBookIDAfterCursor(ctx, userID, bookID, filter, ascending, r.URL.Query().Get("cursor"), pageSize)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
 func (h *EndpointHandler) getExpenseByID(w http.ResponseWriter, r *http.Request) {
 	// Input validation
 	expenseID := r.PathValue("id")
@@ -195,11 +288,21 @@ func (h *EndpointHandler) updateExpense(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+	if !service.IsValidExpenseDate(req.Date) {
 		http.Error(w, "Date must be a valid YYYY-MM-DD", http.StatusBadRequest)
 		return
 	}
 
+	if req.Type != "" && !service.IsValidExpenseType(req.Type) {
+		http.Error(w, "Type must be one of expense, income or transfer", http.StatusBadRequest)
+		return
+	}
+
+	if req.Amount == nil && req.OriginalAmount == nil {
+		http.Error(w, "Amount or original amount is required", http.StatusBadRequest)
+		return
+	}
+
 	// Process the request
 	ctx := r.Context()
 	userID, err := middleware.GetUserIDFromContext(ctx)
@@ -209,7 +312,7 @@ func (h *EndpointHandler) updateExpense(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.service.UpdateExpense(ctx, userID, expenseID, req.CategoryID, req.PaymentMethodID, req.Date, req.Amount, req.Remark)
+	err = h.service.UpdateExpense(ctx, userID, expenseID, req.params())
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -247,3 +350,130 @@ func (h *EndpointHandler) deleteExpense(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Expense deleted successfully"))
 }
+
+// parseExpenseFilter reads the expense filter from the query parameters, and
+// writes a bad request response if it is invalid.
+func parseExpenseFilter(w http.ResponseWriter, r *http.Request) (service.ExpenseFilter, bool) {
+	filter := service.ExpenseFilter{
+		CategoryID:      r.URL.Query().Get("category-id"),
+		PaymentMethodID: r.URL.Query().Get("payment-method-id"),
+		Remark:          r.URL.Query().Get("remark"),
+		Type:            r.URL.Query().Get("type"),
+		Search:          r.URL.Query().Get("q"),
+	}
+
+	if filter.Type != "" && !service.IsValidExpenseType(filter.Type) {
+		http.Error(w, "Type must be one of expense, income or transfer", http.StatusBadRequest)
+		return service.ExpenseFilter{}, false
+	}
+
+	filter.DateFrom = r.URL.Query().Get("date-from")
+	if filter.DateFrom != "" && !service.IsValidExpenseDate(filter.DateFrom) {
+		http.Error(w, "Date from must be a valid YYYY-MM-DD", http.StatusBadRequest)
+		return service.ExpenseFilter{}, false
+	}
+
+	filter.DateTo = r.URL.Query().Get("date-to")
+	if filter.DateTo != "" && !service.IsValidExpenseDate(filter.DateTo) {
+		http.Error(w, "Date to must be a valid YYYY-MM-DD", http.StatusBadRequest)
+		return service.ExpenseFilter{}, false
+	}
+
+	if value := r.URL.Query().Get("amount-min"); value != "" {
+		amountMin, err := money.Parse(value)
+		if err != nil {
+			http.Error(w, "Amount min must be a decimal number", http.StatusBadRequest)
+			return service.ExpenseFilter{}, false
+		}
+		filter.AmountMin = &amountMin
+	}
+
+	if value := r.URL.Query().Get("amount-max"); value != "" {
+		amountMax, err := money.Parse(value)
+		if err != nil {
+			http.Error(w, "Amount max must be a decimal number", http.StatusBadRequest)
+			return service.ExpenseFilter{}, false
+		}
+		filter.AmountMax = &amountMax
+	}
+
+	tagFilter, ok := parseTagFilter(w, r)
+	if !ok {
+		return service.ExpenseFilter{}, false
+	}
+	filter.TagFilter = tagFilter
+
+	return filter, true
+}
+
+// parseTagFilter reads the tag filter from the comma separated tag-ids and the
+// tag-match (any or all) query parameters, and writes a bad request response
+// if it is invalid.
+func parseTagFilter(w http.ResponseWriter, r *http.Request) (service.TagFilter, bool) {
+	filter := service.TagFilter{}
+
+	if value := r.URL.Query().Get("tag-ids"); value != "" {
+		filter.TagIDs = strings.Split(value, ",")
+	}
+
+	switch r.URL.Query().Get("tag-match") {
+	case "", "any":
+	case "all":
+		filter.MatchAll = true
+	default:
+		http.Error(w, "Tag match must be one of any or all", http.StatusBadRequest)
+		return service.TagFilter{}, false
+	}
+
+	return filter, true
+}
+
+// parseExpenseSort reads the order of the expenses from the query parameters,
+// and writes a bad request response if it is invalid.
+func parseExpenseSort(w http.ResponseWriter, r *http.Request) (service.ExpenseSort, bool) {
+	sort := service.ExpenseSort{
+		By: r.URL.Query().Get("sort"),
+	}
+
+	// Search results are sorted by relevance by default
+	search := r.URL.Query().Get("q")
+
+	if sort.By == "" {
+		sort.By = service.ExpenseSortDate
+		if search != "" {
+			sort.By = service.ExpenseSortRelevance
+		}
+	}
+
+	if !service.IsValidExpenseSort(sort.By) {
+		http.Error(w, "Sort must be one of date, amount, created or relevance", http.StatusBadRequest)
+		return service.ExpenseSort{}, false
+	}
+
+	if sort.By == service.ExpenseSortRelevance && search == "" {
+		http.Error(w, "Sort by relevance requires a search query", http.StatusBadRequest)
+		return service.ExpenseSort{}, false
+	}
+
+	ascending, ok := parseSortOrder(w, r)
+	if !ok {
+		return service.ExpenseSort{}, false
+	}
+	sort.Ascending = ascending
+
+	return sort, true
+}
+
+// parseSortOrder reports whether the order query parameter is ascending, and
+// writes a bad request response if it is invalid.
+func parseSortOrder(w http.ResponseWriter, r *http.Request) (ascending bool, ok bool) {
+	switch r.URL.Query().Get("order") {
+	case "", "desc":
+		return false, true
+	case "asc":
+		return true, true
+	default:
+		http.Error(w, "Order must be one of asc or desc", http.StatusBadRequest)
+		return false, false
+	}
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/jljl1337/xpense/internal/http/common"
+	"github.com/jljl1337/xpense/internal/http/middleware"
+	"github.com/jljl1337/xpense/internal/journal"
+)
+
+var journalExtensions = map[string]string{
+	string(journal.FormatLedger):    "ledger",
+	string(journal.FormatHledger):   "journal",
+	string(journal.FormatBeancount): "beancount",
+}
+
+func (h *EndpointHandler) registerExportRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /books/{id}/export", h.exportExpenses)
+}
+
+// exportExpenses streams all the filtered expenses of a book as a CSV file, a
+// JSON array, or a ledger, hledger or beancount journal, depending on the
+// format query parameter.
+func (h *EndpointHandler) exportExpenses(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "csv" && format != "json" && !journal.IsValidFormat(format) {
+		http.Error(w, "Format must be one of csv, json, ledger, hledger or beancount", http.StatusBadRequest)
+		return
+	}
+
+	filter, ok := parseExpenseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if journal.IsValidFormat(format) {
+		writeJournal, err := h.service.ExportJournal(ctx, userID, bookID, filter, journal.Format(format))
+		if err != nil {
+			common.WriteErrorResponse(w, err)
+			return
+		}
+
+		// Respond to the client
+		w.Header().Set("Content-Disposition", `attachment; filename="expenses.`+journalExtensions[format]+`"`)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		if err := writeJournal(w); err != nil {
+			abortExport("Error exporting journal: ", err)
+		}
+		return
+	}
+
+	expenses, err := h.service.ExportExpenses(ctx, userID, bookID, filter)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client, errors after this point abort the response as it
+	// has already started
+	w.Header().Set("Content-Disposition", `attachment; filename="expenses.`+format+`"`)
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"id", "date", "type", "category", "paymentMethod", "destinationPaymentMethod", "amount", "currency", "originalCurrency", "originalAmount", "remark"})
+
+		for expense, err := range expenses {
+			if err != nil {
+				abortExport("Error exporting expenses: ", err)
+			}
+
+			originalAmount := ""
+			if expense.OriginalAmount != nil {
+				originalAmount = expense.OriginalAmount.String()
+			}
+
+			writer.Write([]string{
+				expense.ID,
+				expense.Date,
+				expense.Type,
+				expense.Category,
+				expense.PaymentMethod,
+				stringValue(expense.DestinationPaymentMethod),
+				expense.Amount.String(),
+				expense.Currency,
+				stringValue(expense.OriginalCurrency),
+				originalAmount,
+				expense.Remark,
+			})
+		}
+
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			abortExport("Error writing exported expenses: ", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte("["))
+
+	first := true
+	for expense, err := range expenses {
+		if err != nil {
+			abortExport("Error exporting expenses: ", err)
+		}
+
+		if !first {
+			w.Write([]byte(","))
+		}
+		first = false
+
+		data, err := json.Marshal(expense)
+		if err != nil {
+			abortExport("Error encoding exported expense: ", err)
+		}
+		w.Write(data)
+	}
+
+	w.Write([]byte("]\n"))
+}
+
+// abortExport logs an error of an export once the response has started, and
+// aborts the response so that the client gets a truncated body, instead of a
+// file that looks complete.
+func abortExport(message string, err error) {
+	slog.Error(message + err.Error())
+	panic(http.ErrAbortHandler)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/jljl1337/xpense/internal/env"
+	"github.com/jljl1337/xpense/internal/http/common"
+	"github.com/jljl1337/xpense/internal/http/middleware"
+	"github.com/jljl1337/xpense/internal/importer"
+	"github.com/jljl1337/xpense/internal/ofx"
+)
+
+func (h *EndpointHandler) registerImportRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /books/{id}/import/csv", h.importCSV)
+	mux.HandleFunc("POST /books/{id}/import/ofx", h.importOFX)
+}
+
+// importCSV imports expenses from a multipart form with the CSV file in the
+// "file" field, the column mapping as JSON in the "mapping" field, and an
+// optional "dry-run" field set to "true".
+func (h *EndpointHandler) importCSV(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, env.ImportFileSizeMax)
+	if err := r.ParseMultipartForm(env.ImportFileSizeMax); err != nil {
+		http.Error(w, "Invalid multipart form or file too large", http.StatusBadRequest)
+		return
+	}
+
+	var mapping importer.CSVMapping
+	if err := json.Unmarshal([]byte(r.FormValue("mapping")), &mapping); err != nil {
+		http.Error(w, "Invalid column mapping", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := r.FormValue("dry-run") == "true"
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "File is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	rows, err := importer.ParseCSV(file, mapping)
+	if err != nil {
+		http.Error(w, "Invalid CSV file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.service.ImportExpenses(ctx, userID, bookID, rows, dryRun)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	status := http.StatusCreated
+	if len(result.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	} else if dryRun {
+		status = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// importOFX imports the transactions of an OFX or QFX bank statement from a
+// multipart form with the file in the "file" field, the "category-id" and
+// "payment-method-id" fields, and an optional "dry-run" field set to "true".
+func (h *EndpointHandler) importOFX(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, env.ImportFileSizeMax)
+	if err := r.ParseMultipartForm(env.ImportFileSizeMax); err != nil {
+		http.Error(w, "Invalid multipart form or file too large", http.StatusBadRequest)
+		return
+	}
+
+	categoryID := r.FormValue("category-id")
+	paymentMethodID := r.FormValue("payment-method-id")
+	if categoryID == "" || paymentMethodID == "" {
+		http.Error(w, "Category ID and payment method ID are required", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := r.FormValue("dry-run") == "true"
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "File is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	statement, err := ofx.Parse(file)
+	if err != nil {
+		http.Error(w, "Invalid OFX file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.service.ImportOFX(ctx, userID, bookID, categoryID, paymentMethodID, statement, dryRun)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
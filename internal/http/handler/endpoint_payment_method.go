@@ -7,17 +7,24 @@ import (
 
 	"github.com/jljl1337/xpense/internal/http/common"
 	"github.com/jljl1337/xpense/internal/http/middleware"
+	"github.com/jljl1337/xpense/internal/service"
 )
 
 type createPaymentMethodRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	BookID      string `json:"bookID"`
+	Kind        string `json:"kind"`
 }
 
 type updatePaymentMethodRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	Kind        string `json:"kind"`
+}
+
+type mergePaymentMethodRequest struct {
+	TargetID string `json:"targetID"`
 }
 
 func (h *EndpointHandler) registerPaymentMethodRoutes(mux *http.ServeMux) {
@@ -26,6 +33,9 @@ func (h *EndpointHandler) registerPaymentMethodRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /payment-methods/{id}", h.getPaymentMethodByID)
 	mux.HandleFunc("PUT /payment-methods/{id}", h.updatePaymentMethod)
 	mux.HandleFunc("DELETE /payment-methods/{id}", h.deletePaymentMethod)
+	mux.HandleFunc("POST /payment-methods/{id}/merge", h.mergePaymentMethod)
+	mux.HandleFunc("POST /payment-methods/{id}/archive", h.archivePaymentMethod)
+	mux.HandleFunc("POST /payment-methods/{id}/unarchive", h.unarchivePaymentMethod)
 }
 
 func (h *EndpointHandler) createPaymentMethod(w http.ResponseWriter, r *http.Request) {
@@ -41,6 +51,16 @@ func (h *EndpointHandler) createPaymentMethod(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Payment methods without a kind are assets
+	if req.Kind == "" {
+		req.Kind = service.PaymentMethodKindAsset
+	}
+
+	if !service.IsValidPaymentMethodKind(req.Kind) {
+		http.Error(w, "Kind must be one of asset or liability", http.StatusBadRequest)
+		return
+	}
+
 	// Process the request
 	ctx := r.Context()
 	userID, err := middleware.GetUserIDFromContext(ctx)
@@ -50,7 +70,7 @@ func (h *EndpointHandler) createPaymentMethod(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = h.service.CreatePaymentMethod(ctx, userID, req.BookID, req.Name, req.Description)
+	err = h.service.CreatePaymentMethod(ctx, userID, req.BookID, req.Name, req.Description, req.Kind)
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -69,6 +89,8 @@ func (h *EndpointHandler) getPaymentMethodsByBookID(w http.ResponseWriter, r *ht
 		return
 	}
 
+	includeArchived := r.URL.Query().Get("include-archived") == "true"
+
 	// Process the request
 	ctx := r.Context()
 	userID, err := middleware.GetUserIDFromContext(ctx)
@@ -78,7 +100,7 @@ func (h *EndpointHandler) getPaymentMethodsByBookID(w http.ResponseWriter, r *ht
 		return
 	}
 
-	paymentMethods, err := h.service.GetPaymentMethodsByBookID(r.Context(), userID, bookID)
+	paymentMethods, err := h.service.GetPaymentMethodsByBookID(r.Context(), userID, bookID, includeArchived)
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -130,6 +152,11 @@ func (h *EndpointHandler) updatePaymentMethod(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if req.Kind != "" && !service.IsValidPaymentMethodKind(req.Kind) {
+		http.Error(w, "Kind must be one of asset or liability", http.StatusBadRequest)
+		return
+	}
+
 	paymentMethodID := r.PathValue("id")
 	if paymentMethodID == "" {
 		http.Error(w, "Payment method ID is required", http.StatusBadRequest)
@@ -145,7 +172,7 @@ func (h *EndpointHandler) updatePaymentMethod(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = h.service.UpdatePaymentMethodByID(ctx, userID, paymentMethodID, req.Name, req.Description)
+	err = h.service.UpdatePaymentMethodByID(ctx, userID, paymentMethodID, req.Name, req.Description, req.Kind)
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -164,6 +191,9 @@ func (h *EndpointHandler) deletePaymentMethod(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// The expenses are moved to this payment method before deleting, if given
+	reassignTo := r.URL.Query().Get("reassign-to")
+
 	// Process the request
 	ctx := r.Context()
 	userID, err := middleware.GetUserIDFromContext(ctx)
@@ -173,7 +203,7 @@ func (h *EndpointHandler) deletePaymentMethod(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = h.service.DeletePaymentMethodByID(ctx, userID, paymentMethodID)
+	err = h.service.DeletePaymentMethodByID(ctx, userID, paymentMethodID, reassignTo)
 	if err != nil {
 		common.WriteErrorResponse(w, err)
 		return
@@ -183,3 +213,98 @@ func (h *EndpointHandler) deletePaymentMethod(w http.ResponseWriter, r *http.Req
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Payment method deleted successfully"))
 }
+
+func (h *EndpointHandler) mergePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req mergePaymentMethodRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.TargetID == "" {
+		http.Error(w, "Target payment method ID is required", http.StatusBadRequest)
+		return
+	}
+
+	paymentMethodID := r.PathValue("id")
+	if paymentMethodID == "" {
+		http.Error(w, "Payment method ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.MergePaymentMethod(ctx, userID, paymentMethodID, req.TargetID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Payment method merged successfully"))
+}
+
+func (h *EndpointHandler) archivePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	paymentMethodID := r.PathValue("id")
+	if paymentMethodID == "" {
+		http.Error(w, "Payment method ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.ArchivePaymentMethodByID(ctx, userID, paymentMethodID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Payment method archived successfully"))
+}
+
+func (h *EndpointHandler) unarchivePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	paymentMethodID := r.PathValue("id")
+	if paymentMethodID == "" {
+		http.Error(w, "Payment method ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.UnarchivePaymentMethodByID(ctx, userID, paymentMethodID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Payment method unarchived successfully"))
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jljl1337/xpense/internal/http/common"
+	"github.com/jljl1337/xpense/internal/http/middleware"
+	"github.com/jljl1337/xpense/internal/money"
+)
+
+type createRecurringExpenseRequest struct {
+	BookID          string        `json:"bookID"`
+	CategoryID      string        `json:"categoryID"`
+	PaymentMethodID string        `json:"paymentMethodID"`
+	Amount          money.Decimal `json:"amount"`
+	Remark          string        `json:"remark"`
+	Rule            string        `json:"rule"`
+	StartDate       string        `json:"startDate"`
+	EndDate         string        `json:"endDate"`
+}
+
+type updateRecurringExpenseRequest struct {
+	CategoryID      string        `json:"categoryID"`
+	PaymentMethodID string        `json:"paymentMethodID"`
+	Amount          money.Decimal `json:"amount"`
+	Remark          string        `json:"remark"`
+	Rule            string        `json:"rule"`
+	StartDate       string        `json:"startDate"`
+	EndDate         string        `json:"endDate"`
+}
+
+func (h *EndpointHandler) registerRecurringExpenseRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /recurring-expenses", h.createRecurringExpense)
+	mux.HandleFunc("GET /recurring-expenses", h.getRecurringExpensesByBookID)
+	mux.HandleFunc("GET /recurring-expenses/{id}", h.getRecurringExpenseByID)
+	mux.HandleFunc("PUT /recurring-expenses/{id}", h.updateRecurringExpense)
+	mux.HandleFunc("DELETE /recurring-expenses/{id}", h.deleteRecurringExpense)
+}
+
+func (h *EndpointHandler) createRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req createRecurringExpenseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.BookID == "" || req.CategoryID == "" || req.PaymentMethodID == "" {
+		http.Error(w, "Book ID, category ID and payment method ID are required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Rule == "" {
+		http.Error(w, "Rule is required", http.StatusBadRequest)
+		return
+	}
+
+	if !isValidRecurringExpenseDateRange(req.StartDate, req.EndDate) {
+		http.Error(w, "Start date must be a valid YYYY-MM-DD, end date must be empty or a valid YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.CreateRecurringExpense(ctx, userID, req.BookID, req.CategoryID, req.PaymentMethodID, req.Amount, req.Remark, req.Rule, req.StartDate, req.EndDate)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("Recurring expense created successfully"))
+}
+
+func (h *EndpointHandler) getRecurringExpensesByBookID(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.URL.Query().Get("book-id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	recurringExpenses, err := h.service.GetRecurringExpensesByBookID(ctx, userID, bookID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recurringExpenses)
+}
+
+func (h *EndpointHandler) getRecurringExpenseByID(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	recurringExpenseID := r.PathValue("id")
+	if recurringExpenseID == "" {
+		http.Error(w, "Recurring expense ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	recurringExpense, err := h.service.GetRecurringExpenseByID(ctx, userID, recurringExpenseID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recurringExpense)
+}
+
+func (h *EndpointHandler) updateRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	recurringExpenseID := r.PathValue("id")
+	if recurringExpenseID == "" {
+		http.Error(w, "Recurring expense ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req updateRecurringExpenseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.CategoryID == "" || req.PaymentMethodID == "" {
+		http.Error(w, "Category ID and payment method ID are required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Rule == "" {
+		http.Error(w, "Rule is required", http.StatusBadRequest)
+		return
+	}
+
+	if !isValidRecurringExpenseDateRange(req.StartDate, req.EndDate) {
+		http.Error(w, "Start date must be a valid YYYY-MM-DD, end date must be empty or a valid YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.UpdateRecurringExpense(ctx, userID, recurringExpenseID, req.CategoryID, req.PaymentMethodID, req.Amount, req.Remark, req.Rule, req.StartDate, req.EndDate)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Recurring expense updated successfully"))
+}
+
+func (h *EndpointHandler) deleteRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	recurringExpenseID := r.PathValue("id")
+	if recurringExpenseID == "" {
+		http.Error(w, "Recurring expense ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.DeleteRecurringExpenseByID(ctx, userID, recurringExpenseID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Recurring expense deleted successfully"))
+}
+
+func isValidRecurringExpenseDateRange(startDate, endDate string) bool {
+	if _, err := time.Parse("2006-01-02", startDate); err != nil {
+		return false
+	}
+
+	if endDate == "" {
+		return true
+	}
+
+	_, err := time.Parse("2006-01-02", endDate)
+	return err == nil
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jljl1337/xpense/internal/http/common"
+	"github.com/jljl1337/xpense/internal/http/middleware"
+	"github.com/jljl1337/xpense/internal/service"
+)
+
+func (h *EndpointHandler) registerReportRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /books/{id}/reports/summary", h.getExpenseSummary)
+}
+
+// getExpenseSummary returns the totals of the expenses of a book, grouped by
+// the comma separated dimensions of the group-by query parameter.
+func (h *EndpointHandler) getExpenseSummary(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.PathValue("id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	from := r.URL.Query().Get("from")
+	if from != "" && !service.IsValidExpenseDate(from) {
+		http.Error(w, "From must be a valid YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	to := r.URL.Query().Get("to")
+	if to != "" && !service.IsValidExpenseDate(to) {
+		http.Error(w, "To must be a valid YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	if from != "" && to != "" && from > to {
+		http.Error(w, "From must not be after to", http.StatusBadRequest)
+		return
+	}
+
+	tagFilter, ok := parseTagFilter(w, r)
+	if !ok {
+		return
+	}
+
+	groupBy := []string{}
+	if value := r.URL.Query().Get("group-by"); value != "" {
+		groupBy = strings.Split(value, ",")
+	}
+
+	for _, dimension := range groupBy {
+		if !service.IsValidSummaryGroupBy(dimension) {
+			http.Error(w, "Group by must be a comma separated list of month, category or payment-method", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	summary, err := h.service.GetExpenseSummary(ctx, userID, bookID, from, to, tagFilter, groupBy)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
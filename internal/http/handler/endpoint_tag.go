@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/jljl1337/xpense/internal/http/common"
+	"github.com/jljl1337/xpense/internal/http/middleware"
+)
+
+type createTagRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	BookID      string `json:"bookID"`
+}
+
+type updateTagRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (h *EndpointHandler) registerTagRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /tags", h.createTag)
+	mux.HandleFunc("GET /tags", h.getTagsByBookID)
+	mux.HandleFunc("GET /tags/{id}", h.getTagByID)
+	mux.HandleFunc("PUT /tags/{id}", h.updateTag)
+	mux.HandleFunc("DELETE /tags/{id}", h.deleteTag)
+}
+
+func (h *EndpointHandler) createTag(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req createTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || req.BookID == "" {
+		http.Error(w, "Tag name and book ID are required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.CreateTag(ctx, userID, req.BookID, req.Name, req.Description)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("Tag created successfully"))
+}
+
+func (h *EndpointHandler) getTagsByBookID(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	bookID := r.URL.Query().Get("book-id")
+	if bookID == "" {
+		http.Error(w, "Book ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := h.service.GetTagsByBookID(r.Context(), userID, bookID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+func (h *EndpointHandler) getTagByID(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	tagID := r.PathValue("id")
+	if tagID == "" {
+		http.Error(w, "Tag ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	tag, err := h.service.GetTagByID(r.Context(), userID, tagID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+func (h *EndpointHandler) updateTag(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req updateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "Tag name is required", http.StatusBadRequest)
+		return
+	}
+
+	tagID := r.PathValue("id")
+	if tagID == "" {
+		http.Error(w, "Tag ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.UpdateTagByID(ctx, userID, tagID, req.Name, req.Description)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Tag updated successfully"))
+}
+
+func (h *EndpointHandler) deleteTag(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	tagID := r.PathValue("id")
+	if tagID == "" {
+		http.Error(w, "Tag ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.DeleteTagByID(ctx, userID, tagID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Tag deleted successfully"))
+}
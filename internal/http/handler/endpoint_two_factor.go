@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/jljl1337/xpense/internal/http/common"
+	"github.com/jljl1337/xpense/internal/http/middleware"
+)
+
+type confirmTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+type twoFactorPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+func (h *EndpointHandler) registerTwoFactorRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /users/me/2fa", h.getTwoFactorStatus)
+	mux.HandleFunc("POST /users/me/2fa/totp", h.enrollTOTP)
+	mux.HandleFunc("POST /users/me/2fa/totp/confirm", h.confirmTOTP)
+	mux.HandleFunc("POST /users/me/2fa/disable", h.disableTwoFactor)
+	mux.HandleFunc("POST /users/me/2fa/recovery-codes", h.regenerateRecoveryCodes)
+}
+
+func (h *EndpointHandler) getTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	status, err := h.service.GetTwoFactorStatus(ctx, userID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// enrollTOTP starts the TOTP enrolment, which has to be confirmed with a code
+// before it is enabled.
+func (h *EndpointHandler) enrollTOTP(w http.ResponseWriter, r *http.Request) {
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	enrollment, err := h.service.EnrollTOTP(ctx, userID)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// confirmTOTP enables TOTP, the recovery codes are only in this response.
+func (h *EndpointHandler) confirmTOTP(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req confirmTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	recoveryCodes, err := h.service.ConfirmTOTP(ctx, userID, req.Code)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+func (h *EndpointHandler) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req twoFactorPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" {
+		http.Error(w, "Password is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.DisableTwoFactor(ctx, userID, req.Password); err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Two-factor authentication disabled successfully"))
+}
+
+// regenerateRecoveryCodes replaces the recovery codes, the new codes are only
+// in this response.
+func (h *EndpointHandler) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	// Input validation
+	var req twoFactorPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" {
+		http.Error(w, "Password is required", http.StatusBadRequest)
+		return
+	}
+
+	// Process the request
+	ctx := r.Context()
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		slog.Error("Error getting user ID from context")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	recoveryCodes, err := h.service.RegenerateRecoveryCodes(ctx, userID, req.Password)
+	if err != nil {
+		common.WriteErrorResponse(w, err)
+		return
+	}
+
+	// Respond to the client
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"path"
+	"strings"
 
 	"github.com/jljl1337/xpense/internal/env"
 	"github.com/jljl1337/xpense/internal/http/common"
@@ -18,18 +20,40 @@ func (m *MiddlewareProvider) Auth() Middleware {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Skip for public routes
 			publicRoutes := map[string]bool{
-				"/auth/sign-up":     true,
-				"/auth/pre-session": true,
-				"/auth/sign-in":     true,
-				"/auth/csrf-token":  true,
-				"/health":           true,
-				"/users/exists":     true,
+				"/auth/sign-up":               true,
+				"/auth/pre-session":           true,
+				"/auth/sign-in":               true,
+				"/auth/sign-in/second-factor": true,
+				"/auth/csrf-token":            true,
+				"/health":                     true,
+				"/users/exists":               true,
 			}
 			if publicRoutes[r.URL.Path] {
 				next.ServeHTTP(w, r)
 				return
 			}
 
+			write := r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodDelete || r.Method == http.MethodPatch
+
+			// API tokens are not sent by browsers automatically, so they do not
+			// need a CSRF token
+			if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+				if isAccountRoute(r) {
+					http.Error(w, "API tokens cannot be used for account and session management", http.StatusForbidden)
+					return
+				}
+
+				userID, err := m.service.GetAPITokenUserID(r.Context(), token, write)
+				if err != nil {
+					common.WriteErrorResponse(w, err)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), UserIDKey, userID)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			// Get session token from cookie
 			cookie, err := r.Cookie(env.SessionCookieName)
 			if err != nil {
@@ -41,13 +65,13 @@ func (m *MiddlewareProvider) Auth() Middleware {
 			// Get CSRF token from header
 			CSRFToken := r.Header.Get("X-CSRF-Token")
 
-			if CSRFToken == "" && (r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodDelete || r.Method == http.MethodPatch) {
+			if CSRFToken == "" && write {
 				http.Error(w, "CSRF token is required", http.StatusUnauthorized)
 				return
 			}
 
 			// Validate session token (and CSRF token)
-			userID, err := m.service.GetSessionUserIDAndRefreshSession(r.Context(), cookie.Value, CSRFToken)
+			userID, err := m.service.GetSessionUserIDAndRefreshSession(r.Context(), cookie.Value, CSRFToken, r.UserAgent(), GetClientIP(r))
 			if err != nil {
 				common.WriteErrorResponse(w, err)
 				return
@@ -60,6 +84,19 @@ func (m *MiddlewareProvider) Auth() Middleware {
 	}
 }
 
+// isAccountRoute reports whether the request manages the account, its sessions
+// or its API tokens, which requires a session so that a leaked API token cannot
+// be used to take over the account. Reading the current user is allowed.
+func isAccountRoute(r *http.Request) bool {
+	p := path.Clean(r.URL.Path)
+
+	if p == "/users/me" {
+		return r.Method != http.MethodGet
+	}
+
+	return strings.HasPrefix(p, "/auth/") || strings.HasPrefix(p, "/users/me/")
+}
+
 // GetUserIDFromContext retrieves the user ID from the context.
 //
 // It returns an error if the user ID is not found or is of an unexpected type.
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 	"time"
 
@@ -53,22 +54,46 @@ func (m *MiddlewareProvider) Logging() Middleware {
 	}
 }
 
-// GetClientIP retrieves the client IP address from headers or connection
+// GetClientIP returns the client IP address of the request.
+//
+// The X-Forwarded-For header can be set by anyone, so it is only read if the
+// connection comes from a trusted proxy. The client is then the last forwarded
+// address not from a trusted proxy, as the ones before it can be forged too.
 func GetClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (used by proxies/load balancers)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// X-Forwarded-For can contain multiple IPs, get the first one
-		ips := strings.Split(xff, ",")
-		if ip := strings.TrimSpace(ips[0]); ip != "" {
-			return ip
-		}
-	}
-
-	// Fallback to RemoteAddr from the connection
 	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
 		// If no port is present, use RemoteAddr directly
-		return r.RemoteAddr
+		ip = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !isTrustedProxy(addr) {
+		return ip
 	}
+
+	// Walk the forwarded addresses from the closest proxy
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+
+		ip = hop.Unmap().String()
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+
 	return ip
 }
+
+func isTrustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	for _, prefix := range env.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
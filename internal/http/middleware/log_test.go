@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/jljl1337/xpense/internal/env"
+)
+
+func TestGetClientIP(t *testing.T) {
+	trustedProxies := env.TrustedProxies
+	t.Cleanup(func() { env.TrustedProxies = trustedProxies })
+
+	env.TrustedProxies = []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("::1/128"),
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.7:51000", want: "203.0.113.7"},
+		{name: "no port", remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+		{name: "untrusted proxy", remoteAddr: "203.0.113.7:51000", forwarded: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.2:51000", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "trusted IPv6 proxy", remoteAddr: "[::1]:51000", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "trusted proxy without header", remoteAddr: "10.0.0.2:51000", want: "10.0.0.2"},
+		{name: "forged addresses before the client", remoteAddr: "10.0.0.2:51000", forwarded: []string{"192.0.2.1, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "chain of trusted proxies", remoteAddr: "10.0.0.2:51000", forwarded: []string{"198.51.100.1, 10.0.0.3", "10.0.0.4"}, want: "198.51.100.1"},
+		{name: "only trusted proxies", remoteAddr: "10.0.0.2:51000", forwarded: []string{"10.0.0.3"}, want: "10.0.0.3"},
+		{name: "invalid address", remoteAddr: "10.0.0.2:51000", forwarded: []string{"198.51.100.1, not-an-ip, 10.0.0.3"}, want: "10.0.0.3"},
+		{name: "IPv4-mapped address", remoteAddr: "[::ffff:10.0.0.2]:51000", forwarded: []string{"::ffff:198.51.100.1"}, want: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		for _, value := range tt.forwarded {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+
+		if got := GetClientIP(r); got != tt.want {
+			t.Errorf("%s: GetClientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Nothing is trusted by default
+	env.TrustedProxies = nil
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "127.0.0.1:51000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := GetClientIP(r); got != "127.0.0.1" {
+		t.Errorf("GetClientIP without trusted proxies = %q, want %q", got, "127.0.0.1")
+	}
+}
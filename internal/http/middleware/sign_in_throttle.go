@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/jljl1337/xpense/internal/env"
+	"github.com/jljl1337/xpense/internal/http/common"
+)
+
+// signInBodySizeMax is the size of the sign-in request body read for the
+// username, far above any valid request
+const signInBodySizeMax = 1 << 16
+
+// SignInThrottle middleware locks out the client IP and the username after
+// repeated failed sign-in attempts, responding with 429 until the lockout ends.
+// Failed second factor attempts count against the username of the user waiting
+// for the second factor.
+func (m *MiddlewareProvider) SignInThrottle() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Only the sign-in routes are throttled
+			if r.Method != http.MethodPost || (r.URL.Path != "/auth/sign-in" && r.URL.Path != "/auth/sign-in/second-factor") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ip := GetClientIP(r)
+			secondFactor := r.URL.Path == "/auth/sign-in/second-factor"
+
+			// The pre-session of the sign-in, which waits for the second factor
+			// after a valid password if the user has it enabled
+			preSessionToken := ""
+			if cookie, err := r.Cookie(env.SessionCookieName); err == nil {
+				preSessionToken = cookie.Value
+			}
+
+			// The second factor attempts are throttled by the user waiting for
+			// it, the sign-in attempts by the username of the request
+			var username string
+			if secondFactor {
+				var err error
+				username, err = m.service.GetSignInPendingUsername(r.Context(), preSessionToken)
+				if err != nil {
+					common.WriteErrorResponse(w, err)
+					return
+				}
+			} else {
+				// Read the username and restore the body for the handler
+				body, err := io.ReadAll(io.LimitReader(r.Body, signInBodySizeMax))
+				if err != nil {
+					http.Error(w, "Invalid request payload", http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+
+				var req struct {
+					Username string `json:"username"`
+				}
+				json.Unmarshal(body, &req)
+				username = req.Username
+			}
+
+			// The attempt is counted before the handler checks it, so that
+			// parallel attempts cannot all pass the lockout
+			lockout, err := m.service.ReserveSignInAttempt(r.Context(), ip, username)
+			if err != nil {
+				common.WriteErrorResponse(w, err)
+				return
+			}
+
+			if lockout > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockout.Seconds()))))
+				http.Error(w, "Too many failed sign-in attempts, try again later", http.StatusTooManyRequests)
+				return
+			}
+
+			// The outcome is recorded even if the client goes away
+			ctx := context.WithoutCancel(r.Context())
+			defer func() {
+				if err := m.service.ReleaseSignInAttempt(ctx, ip, username); err != nil {
+					slog.Error("Failed to release sign-in attempt: " + err.Error())
+				}
+			}()
+
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			switch wrapped.statusCode {
+			case http.StatusUnauthorized:
+				if err := m.service.RecordSignInFailure(ctx, ip, username); err != nil {
+					slog.Error("Failed to record sign-in failure: " + err.Error())
+				}
+			case http.StatusOK:
+				if username == "" {
+					break
+				}
+
+				// The failures are only reset once the sign-in is finished, not
+				// when the second factor is still required after the password
+				if !secondFactor {
+					pendingUsername, err := m.service.GetSignInPendingUsername(ctx, preSessionToken)
+					if err != nil {
+						slog.Error("Failed to get sign-in pending username: " + err.Error())
+						break
+					}
+
+					if pendingUsername != "" {
+						break
+					}
+				}
+
+				if err := m.service.ResetSignInFailures(ctx, username); err != nil {
+					slog.Error("Failed to reset sign-in failures: " + err.Error())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSVMapping maps the fields of an expense to the header names of the CSV
+// columns. The remark column is optional.
+type CSVMapping struct {
+	Date          string `json:"date"`
+	Amount        string `json:"amount"`
+	Remark        string `json:"remark"`
+	Category      string `json:"category"`
+	PaymentMethod string `json:"paymentMethod"`
+}
+
+// Row is an unvalidated expense read from a file.
+type Row struct {
+	// Line is the 1-based line number of the row in the file
+	Line          int
+	Date          string
+	Amount        string
+	Remark        string
+	Category      string
+	PaymentMethod string
+}
+
+// ParseCSV reads the rows of a CSV file with a header row, using the mapping
+// to find the columns of each field.
+//
+// The values are trimmed but not validated, missing values in short rows are
+// left empty.
+func ParseCSV(r io.Reader, mapping CSVMapping) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Excel adds a byte order mark to UTF-8 files
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+
+	column := func(field, name string, required bool) (int, error) {
+		if name == "" {
+			if required {
+				return 0, fmt.Errorf("column of %s is required", field)
+			}
+			return -1, nil
+		}
+
+		i, ok := columns[name]
+		if !ok {
+			return 0, fmt.Errorf("column %q of %s not found", name, field)
+		}
+
+		return i, nil
+	}
+
+	dateColumn, err := column("date", mapping.Date, true)
+	if err != nil {
+		return nil, err
+	}
+
+	amountColumn, err := column("amount", mapping.Amount, true)
+	if err != nil {
+		return nil, err
+	}
+
+	remarkColumn, err := column("remark", mapping.Remark, false)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryColumn, err := column("category", mapping.Category, true)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentMethodColumn, err := column("payment method", mapping.PaymentMethod, true)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []Row{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		value := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		rows = append(rows, Row{
+			Line:          line,
+			Date:          value(dateColumn),
+			Amount:        value(amountColumn),
+			Remark:        value(remarkColumn),
+			Category:      value(categoryColumn),
+			PaymentMethod: value(paymentMethodColumn),
+		})
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,149 @@
+package journal
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	rootAssets      = "Assets"
+	rootLiabilities = "Liabilities"
+	rootExpenses    = "Expenses"
+	rootIncome      = "Income"
+)
+
+type Category struct {
+	ID   string
+	Name string
+}
+
+type PaymentMethod struct {
+	ID        string
+	Name      string
+	Liability bool
+}
+
+// Accounts maps the categories and payment methods of a book to account
+// names, categories being both expense and income accounts.
+type Accounts struct {
+	expenses       map[string]string
+	incomes        map[string]string
+	paymentMethods map[string]string
+}
+
+// NewAccounts returns the account names of the categories and payment methods
+// for the format.
+//
+// The names are stable: the same category or payment method always gets the
+// same name as long as it is not renamed. If several of them have the same
+// sanitized name, the one with the smallest ID keeps it, and the others get
+// their ID appended, followed by a counter in the unlikely case that this name
+// is taken too.
+func NewAccounts(format Format, categories []Category, paymentMethods []PaymentMethod) *Accounts {
+	accounts := &Accounts{
+		expenses:       map[string]string{},
+		incomes:        map[string]string{},
+		paymentMethods: map[string]string{},
+	}
+
+	categories = slices.Clone(categories)
+	slices.SortFunc(categories, func(a, b Category) int { return strings.Compare(a.ID, b.ID) })
+
+	paymentMethods = slices.Clone(paymentMethods)
+	slices.SortFunc(paymentMethods, func(a, b PaymentMethod) int { return strings.Compare(a.ID, b.ID) })
+
+	used := map[string]bool{}
+	name := func(root, id, name string) string {
+		account := root + ":" + SanitizeAccountName(format, name)
+		if used[account] {
+			// The name with the ID can still be taken by another name
+			withID := account + "-" + SanitizeAccountName(format, id)
+			account = withID
+			for i := 2; used[account]; i++ {
+				account = withID + "-" + strconv.Itoa(i)
+			}
+		}
+		used[account] = true
+		return account
+	}
+
+	for _, category := range categories {
+		accounts.expenses[category.ID] = name(rootExpenses, category.ID, category.Name)
+		accounts.incomes[category.ID] = name(rootIncome, category.ID, category.Name)
+	}
+
+	for _, paymentMethod := range paymentMethods {
+		root := rootAssets
+		if paymentMethod.Liability {
+			root = rootLiabilities
+		}
+		accounts.paymentMethods[paymentMethod.ID] = name(root, paymentMethod.ID, paymentMethod.Name)
+	}
+
+	return accounts
+}
+
+// Expense returns the expense account of a category.
+func (a *Accounts) Expense(categoryID string) string {
+	return a.expenses[categoryID]
+}
+
+// Income returns the income account of a category.
+func (a *Accounts) Income(categoryID string) string {
+	return a.incomes[categoryID]
+}
+
+// PaymentMethod returns the asset or liability account of a payment method.
+func (a *Accounts) PaymentMethod(paymentMethodID string) string {
+	return a.paymentMethods[paymentMethodID]
+}
+
+// All returns all the account names in alphabetical order.
+func (a *Accounts) All() []string {
+	all := []string{}
+	for _, m := range []map[string]string{a.expenses, a.incomes, a.paymentMethods} {
+		for _, account := range m {
+			all = append(all, account)
+		}
+	}
+
+	slices.Sort(all)
+	return all
+}
+
+// SanitizeAccountName turns a name into a valid account name component for the
+// format, the result only depends on the name and the format.
+//
+// For ledger and hledger, colons (the component separator) are replaced and
+// whitespace is collapsed to single spaces, as two spaces end an account name.
+// For beancount, components must start with a capital letter or a digit and
+// only contain letters, digits and hyphens, so words are capitalized and joined
+// with hyphens, e.g. "eating out & bars" becomes "Eating-Out-Bars".
+func SanitizeAccountName(format Format, name string) string {
+	var sanitized string
+
+	if format == FormatBeancount {
+		words := strings.FieldsFunc(name, func(r rune) bool {
+			// Non-ASCII characters are allowed by beancount
+			return r < unicode.MaxASCII && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+
+		for i, word := range words {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+
+		sanitized = strings.Join(words, "-")
+	} else {
+		sanitized = strings.Join(strings.Fields(strings.ReplaceAll(name, ":", "-")), " ")
+	}
+
+	if sanitized == "" {
+		return "Unnamed"
+	}
+
+	return sanitized
+}
@@ -0,0 +1,120 @@
+package journal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jljl1337/xpense/internal/money"
+)
+
+type Format string
+
+const (
+	FormatLedger    Format = "ledger"
+	FormatHledger   Format = "hledger"
+	FormatBeancount Format = "beancount"
+)
+
+// IsValidFormat reports whether format is one of the journal formats.
+func IsValidFormat(format string) bool {
+	switch Format(format) {
+	case FormatLedger, FormatHledger, FormatBeancount:
+		return true
+	default:
+		return false
+	}
+}
+
+// Transaction moves an amount from the source account to the destination
+// account, e.g. from Assets:Cash to Expenses:Food for an expense.
+type Transaction struct {
+	Date               string
+	Description        string
+	DestinationAccount string
+	SourceAccount      string
+	Amount             money.Decimal
+	Currency           string
+	// OriginalAmount and OriginalCurrency are optional, the destination posting
+	// is in the original currency at the total cost of Amount if given
+	OriginalAmount   *money.Decimal
+	OriginalCurrency string
+}
+
+// Writer writes a journal in one of the plain text accounting formats.
+type Writer struct {
+	w      io.Writer
+	format Format
+}
+
+func NewWriter(w io.Writer, format Format) *Writer {
+	return &Writer{w: w, format: format}
+}
+
+// WriteHeader writes the declarations of the journal, which beancount requires
+// for every account used.
+func (jw *Writer) WriteHeader(title, currency string, accounts []string) error {
+	var b strings.Builder
+
+	if jw.format == FormatBeancount {
+		fmt.Fprintf(&b, "option \"title\" %s\n", quote(title))
+		fmt.Fprintf(&b, "option \"operating_currency\" %s\n\n", quote(currency))
+		for _, account := range accounts {
+			fmt.Fprintf(&b, "1970-01-01 open %s\n", account)
+		}
+	} else {
+		fmt.Fprintf(&b, "; %s\n\n", singleLine(title))
+		fmt.Fprintf(&b, "commodity %s\n\n", currency)
+		for _, account := range accounts {
+			fmt.Fprintf(&b, "account %s\n", account)
+		}
+	}
+
+	_, err := io.WriteString(jw.w, b.String())
+	return err
+}
+
+// WriteTransaction writes a transaction with explicit amounts on both
+// postings.
+func (jw *Writer) WriteTransaction(t Transaction) error {
+	var b strings.Builder
+
+	amount := t.Amount.String() + " " + t.Currency
+	negatedAmount := negate(t.Amount.String()) + " " + t.Currency
+
+	destinationAmount := amount
+	if t.OriginalAmount != nil && t.OriginalCurrency != "" {
+		// The total cost is always positive
+		destinationAmount = t.OriginalAmount.String() + " " + t.OriginalCurrency + " @@ " + t.Amount.Abs().String() + " " + t.Currency
+	}
+
+	indent := "    "
+	if jw.format == FormatBeancount {
+		indent = "  "
+		fmt.Fprintf(&b, "\n%s * %s\n", t.Date, quote(t.Description))
+	} else {
+		// A semicolon starts a comment in the description
+		fmt.Fprintf(&b, "\n%s %s\n", t.Date, strings.ReplaceAll(singleLine(t.Description), ";", ","))
+	}
+
+	fmt.Fprintf(&b, "%s%s  %s\n", indent, t.DestinationAccount, destinationAmount)
+	fmt.Fprintf(&b, "%s%s  %s\n", indent, t.SourceAccount, negatedAmount)
+
+	_, err := io.WriteString(jw.w, b.String())
+	return err
+}
+
+func negate(amount string) string {
+	if negative, ok := strings.CutPrefix(amount, "-"); ok {
+		return negative
+	}
+	return "-" + amount
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(singleLine(s)) + `"`
+}
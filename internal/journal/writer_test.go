@@ -0,0 +1,340 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jljl1337/xpense/internal/money"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+var fixtureCategories = []Category{
+	{ID: "01F", Name: "Rent: Home"},
+	{ID: "01A", Name: "Food"},
+	{ID: "01B", Name: "Food-01C"},
+	{ID: "01C", Name: "food"},
+	{ID: "01D", Name: "Salary"},
+	{ID: "01E", Name: "Eating out & bars"},
+}
+
+var fixturePaymentMethods = []PaymentMethod{
+	{ID: "02B", Name: "Credit  Card", Liability: true},
+	{ID: "02A", Name: "Cash"},
+	{ID: "02C", Name: "   "},
+}
+
+type fixtureExpense struct {
+	date                       string
+	description                string
+	kind                       string
+	categoryID                 string
+	paymentMethodID            string
+	destinationPaymentMethodID string
+	amount                     money.Decimal
+	originalAmount             *money.Decimal
+	originalCurrency           string
+}
+
+var fixtureExpenses = []fixtureExpense{
+	{date: "2024-01-01", description: "Groceries", kind: "expense", categoryID: "01A", paymentMethodID: "02A", amount: money.New(1250, 2)},
+	{date: "2024-01-02", description: "January salary", kind: "income", categoryID: "01D", paymentMethodID: "02A", amount: money.New(100000, 2)},
+	{date: "2024-01-03", description: "Snacks", kind: "expense", categoryID: "01C", paymentMethodID: "02B", amount: money.New(325, 2)},
+	{date: "2024-01-04", description: "Card payment", kind: "transfer", categoryID: "01A", paymentMethodID: "02A", destinationPaymentMethodID: "02B", amount: money.New(10000, 2)},
+	{date: "2024-01-05", description: "Ramen in Tokyo", kind: "expense", categoryID: "01B", paymentMethodID: "02C", amount: money.New(745, 2), originalAmount: ptr(money.New(1000, 0)), originalCurrency: "JPY"},
+	{date: "2024-01-06", description: "Dinner \"at\" Joe's\n  late", kind: "expense", categoryID: "01E", paymentMethodID: "02B", amount: money.New(4000, 2)},
+	{date: "2024-01-07", description: "Rent; January", kind: "expense", categoryID: "01F", paymentMethodID: "02A", amount: money.New(80000, 2)},
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// writeFixture writes the fixture book the same way as the journal export.
+func writeFixture(t *testing.T, format Format) ([]byte, *Accounts) {
+	t.Helper()
+
+	accounts := NewAccounts(format, fixtureCategories, fixturePaymentMethods)
+
+	var b bytes.Buffer
+	writer := NewWriter(&b, format)
+
+	if err := writer.WriteHeader("Household \"2024\"", "USD", accounts.All()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expense := range fixtureExpenses {
+		transaction := Transaction{
+			Date:               expense.date,
+			Description:        expense.description,
+			DestinationAccount: accounts.Expense(expense.categoryID),
+			SourceAccount:      accounts.PaymentMethod(expense.paymentMethodID),
+			Amount:             expense.amount,
+			Currency:           "USD",
+			OriginalAmount:     expense.originalAmount,
+			OriginalCurrency:   expense.originalCurrency,
+		}
+
+		switch expense.kind {
+		case "income":
+			transaction.DestinationAccount = accounts.PaymentMethod(expense.paymentMethodID)
+			transaction.SourceAccount = accounts.Income(expense.categoryID)
+		case "transfer":
+			transaction.DestinationAccount = accounts.PaymentMethod(expense.destinationPaymentMethodID)
+		}
+
+		if err := writer.WriteTransaction(transaction); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return b.Bytes(), accounts
+}
+
+// journal is the content of a written journal read back.
+type journal struct {
+	declared []string
+	// balances are in units of 0.0001 by account and currency
+	balances map[string]map[string]int64
+}
+
+var postingRegexp = regexp.MustCompile(`^(\S.*?)  (-?[0-9.]+) ([A-Z]+)(?: @@ ([0-9.]+) ([A-Z]+))?$`)
+
+// readJournal reads the account declarations and postings of a journal,
+// checking that every transaction balances.
+func readJournal(t *testing.T, format Format, data []byte) *journal {
+	t.Helper()
+
+	j := &journal{declared: []string{}, balances: map[string]map[string]int64{}}
+
+	// The sum of the current transaction in its cost currency
+	var sum int64
+	checkSum := func(line int) {
+		if sum != 0 {
+			t.Errorf("%s: transaction before line %d does not balance: %d", format, line, sum)
+		}
+		sum = 0
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+
+		if account, ok := strings.CutPrefix(text, "1970-01-01 open "); ok && format == FormatBeancount {
+			j.declared = append(j.declared, account)
+			continue
+		}
+
+		if account, ok := strings.CutPrefix(text, "account "); ok && format != FormatBeancount {
+			j.declared = append(j.declared, account)
+			continue
+		}
+
+		posting, ok := strings.CutPrefix(text, "  ")
+		if !ok {
+			checkSum(line)
+			continue
+		}
+
+		matches := postingRegexp.FindStringSubmatch(strings.TrimLeft(posting, " "))
+		if matches == nil {
+			t.Errorf("%s: invalid posting on line %d: %q", format, line, text)
+			continue
+		}
+
+		account, amount, currency := matches[1], minorUnits(t, matches[2]), matches[3]
+
+		if j.balances[account] == nil {
+			j.balances[account] = map[string]int64{}
+		}
+		j.balances[account][currency] += amount
+
+		if matches[4] != "" {
+			cost := minorUnits(t, matches[4])
+			if amount < 0 {
+				cost = -cost
+			}
+			sum += cost
+		} else {
+			sum += amount
+		}
+	}
+	checkSum(-1)
+
+	return j
+}
+
+func minorUnits(t *testing.T, s string) int64 {
+	t.Helper()
+
+	d, err := money.Parse(s)
+	if err != nil {
+		t.Fatalf("invalid amount %q: %v", s, err)
+	}
+
+	units, err := d.MinorUnits(4)
+	if err != nil {
+		t.Fatalf("invalid amount %q: %v", s, err)
+	}
+
+	return units
+}
+
+func TestWriterGolden(t *testing.T) {
+	for format, extension := range map[Format]string{
+		FormatLedger:    "ledger",
+		FormatHledger:   "journal",
+		FormatBeancount: "beancount",
+	} {
+		data, _ := writeFixture(t, format)
+
+		golden := "testdata/book." + extension
+		if *update {
+			if err := os.WriteFile(golden, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(data, want) {
+			t.Errorf("%s: output does not match %s:\n%s", format, golden, data)
+		}
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatLedger, FormatHledger, FormatBeancount} {
+		data, accounts := writeFixture(t, format)
+		j := readJournal(t, format, data)
+
+		if !slices.Equal(j.declared, accounts.All()) {
+			t.Errorf("%s: declared accounts = %q, want %q", format, j.declared, accounts.All())
+		}
+
+		for account := range j.balances {
+			if !slices.Contains(j.declared, account) {
+				t.Errorf("%s: account %q is not declared", format, account)
+			}
+		}
+
+		want := map[string]map[string]int64{
+			accounts.PaymentMethod("02A"): {"USD": 875000},
+			accounts.PaymentMethod("02B"): {"USD": 567500},
+			accounts.PaymentMethod("02C"): {"USD": -74500},
+			accounts.Expense("01A"):       {"USD": 125000},
+			accounts.Expense("01B"):       {"JPY": 10000000},
+			accounts.Expense("01C"):       {"USD": 32500},
+			accounts.Expense("01E"):       {"USD": 400000},
+			accounts.Expense("01F"):       {"USD": 8000000},
+			accounts.Income("01D"):        {"USD": -10000000},
+		}
+
+		if len(j.balances) != len(want) {
+			t.Errorf("%s: balances of %d accounts, want %d", format, len(j.balances), len(want))
+		}
+
+		for account, balances := range want {
+			for currency, balance := range balances {
+				if got := j.balances[account][currency]; got != balance {
+					t.Errorf("%s: balance of %q = %d %s, want %d %s", format, account, got, currency, balance, currency)
+				}
+			}
+		}
+	}
+}
+
+func TestSanitizeAccountName(t *testing.T) {
+	tests := []struct {
+		format Format
+		name   string
+		want   string
+	}{
+		{format: FormatLedger, name: "Food", want: "Food"},
+		{format: FormatLedger, name: "Rent: Home", want: "Rent- Home"},
+		{format: FormatLedger, name: "  Credit \t Card  ", want: "Credit Card"},
+		{format: FormatLedger, name: "Eating out & bars", want: "Eating out & bars"},
+		{format: FormatLedger, name: "", want: "Unnamed"},
+		{format: FormatLedger, name: " \n ", want: "Unnamed"},
+		{format: FormatHledger, name: "a::b", want: "a--b"},
+		{format: FormatBeancount, name: "Food", want: "Food"},
+		{format: FormatBeancount, name: "eating out & bars", want: "Eating-Out-Bars"},
+		{format: FormatBeancount, name: "Rent: Home", want: "Rent-Home"},
+		{format: FormatBeancount, name: "2024 trip", want: "2024-Trip"},
+		{format: FormatBeancount, name: "café", want: "Café"},
+		{format: FormatBeancount, name: "élan", want: "Élan"},
+		{format: FormatBeancount, name: "--", want: "Unnamed"},
+		{format: FormatBeancount, name: "", want: "Unnamed"},
+	}
+
+	for _, tt := range tests {
+		got := SanitizeAccountName(tt.format, tt.name)
+		if got != tt.want {
+			t.Errorf("SanitizeAccountName(%s, %q) = %q, want %q", tt.format, tt.name, got, tt.want)
+		}
+
+		if again := SanitizeAccountName(tt.format, got); again != got {
+			t.Errorf("SanitizeAccountName(%s, %q) = %q, not stable", tt.format, got, again)
+		}
+	}
+}
+
+var beancountComponentRegexp = regexp.MustCompile(`^[A-Z0-9][A-Za-z0-9-]*$`)
+
+func TestNewAccounts(t *testing.T) {
+	for _, format := range []Format{FormatLedger, FormatHledger, FormatBeancount} {
+		accounts := NewAccounts(format, fixtureCategories, fixturePaymentMethods)
+		all := accounts.All()
+
+		if len(all) != len(fixtureCategories)*2+len(fixturePaymentMethods) {
+			t.Errorf("%s: %d accounts, want %d", format, len(all), len(fixtureCategories)*2+len(fixturePaymentMethods))
+		}
+
+		if len(slices.Compact(slices.Clone(all))) != len(all) {
+			t.Errorf("%s: duplicate accounts in %q", format, all)
+		}
+
+		if format == FormatBeancount {
+			for _, account := range all {
+				for component := range strings.SplitSeq(account, ":") {
+					if !beancountComponentRegexp.MatchString(component) {
+						t.Errorf("%s: invalid account %q", format, account)
+					}
+				}
+			}
+		}
+
+		// The names do not depend on the order of the categories and payment
+		// methods
+		categories := slices.Clone(fixtureCategories)
+		slices.Reverse(categories)
+		paymentMethods := slices.Clone(fixturePaymentMethods)
+		slices.Reverse(paymentMethods)
+
+		reversed := NewAccounts(format, categories, paymentMethods)
+		if !slices.Equal(reversed.All(), all) {
+			t.Errorf("%s: accounts depend on the order: %q and %q", format, reversed.All(), all)
+		}
+	}
+
+	accounts := NewAccounts(FormatBeancount, fixtureCategories, fixturePaymentMethods)
+	for id, want := range map[string]string{
+		"01A": "Expenses:Food",
+		"01B": "Expenses:Food-01C",
+		"01C": "Expenses:Food-01C-2",
+	} {
+		if got := accounts.Expense(id); got != want {
+			t.Errorf("Expense(%s) = %q, want %q", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package money
+
+// scales are the ISO 4217 minor unit exponents that differ from 2.
+var scales = map[string]int{
+	"BIF": 0,
+	"CLP": 0,
+	"DJF": 0,
+	"GNF": 0,
+	"ISK": 0,
+	"JPY": 0,
+	"KMF": 0,
+	"KRW": 0,
+	"PYG": 0,
+	"RWF": 0,
+	"UGX": 0,
+	"UYI": 0,
+	"VND": 0,
+	"VUV": 0,
+	"XAF": 0,
+	"XOF": 0,
+	"XPF": 0,
+	"BHD": 3,
+	"IQD": 3,
+	"JOD": 3,
+	"KWD": 3,
+	"LYD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"CLF": 4,
+	"UYW": 4,
+}
+
+// Scale returns the number of decimal places of the minor unit of the
+// currency, e.g. 2 for USD (cents) and 0 for JPY.
+//
+// Unknown currencies have a scale of 2.
+func Scale(currency string) int {
+	if scale, ok := scales[currency]; ok {
+		return scale
+	}
+	return 2
+}
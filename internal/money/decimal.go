@@ -0,0 +1,168 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number, e.g. 12.34 is stored as 1234 units with
+// a scale of 2.
+//
+// It is encoded in JSON as a string to avoid any loss of precision, and can be
+// decoded from either a string or a number.
+type Decimal struct {
+	units int64
+	scale int
+}
+
+// New returns the decimal units / 10^scale, e.g. New(1234, 2) is 12.34.
+func New(units int64, scale int) Decimal {
+	return Decimal{units: units, scale: scale}
+}
+
+// Parse parses a decimal string like "-12.34". Exponents are not supported.
+func Parse(s string) (Decimal, error) {
+	digits := strings.TrimPrefix(s, "-")
+	negative := len(digits) != len(s)
+
+	integer, fraction, _ := strings.Cut(digits, ".")
+	if integer == "" || strings.ContainsAny(integer+fraction, "+-") || strings.HasSuffix(digits, ".") {
+		return Decimal{}, fmt.Errorf("invalid decimal: %q", s)
+	}
+
+	units, err := strconv.ParseUint(integer+fraction, 10, 63)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("invalid decimal: %q", s)
+	}
+
+	d := Decimal{units: int64(units), scale: len(fraction)}
+	if negative {
+		d.units = -d.units
+	}
+
+	return d, nil
+}
+
+// MinorUnits returns the decimal as an integer number of minor units with the
+// given scale, e.g. 12.3 is 1230 with a scale of 2.
+//
+// It returns an error if the decimal has more significant decimal places than
+// the scale, or if the result overflows.
+func (d Decimal) MinorUnits(scale int) (int64, error) {
+	units := d.units
+
+	for s := d.scale; s > scale; s-- {
+		if units%10 != 0 {
+			return 0, fmt.Errorf("more than %d decimal places", scale)
+		}
+		units /= 10
+	}
+
+	for s := d.scale; s < scale; s++ {
+		if units > math.MaxInt64/10 || units < math.MinInt64/10 {
+			return 0, errors.New("amount too large")
+		}
+		units *= 10
+	}
+
+	return units, nil
+}
+
+// IsNegative reports whether the decimal is less than zero.
+func (d Decimal) IsNegative() bool {
+	return d.units < 0
+}
+
+// Abs returns the absolute value of the decimal.
+func (d Decimal) Abs() Decimal {
+	if d.units < 0 {
+		return Decimal{units: -d.units, scale: d.scale}
+	}
+	return d
+}
+
+// MulRat returns the decimal multiplied by r exactly, rounded half away from
+// zero to scale decimal places, e.g. for a currency conversion.
+//
+// It returns an error if the result overflows.
+func (d Decimal) MulRat(r *big.Rat, scale int) (Decimal, error) {
+	// units / 10^d.scale * r * 10^scale
+	product := new(big.Rat).SetFrac(big.NewInt(d.units), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.scale)), nil))
+	product.Mul(product, r)
+	product.Mul(product, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+
+	// Round half away from zero with the quotient and remainder of the
+	// truncated division
+	quotient, remainder := new(big.Int).QuoRem(product.Num(), product.Denom(), new(big.Int))
+	if remainder.Abs(remainder).Lsh(remainder, 1).Cmp(product.Denom()) >= 0 {
+		if product.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	if !quotient.IsInt64() {
+		return Decimal{}, errors.New("amount too large")
+	}
+
+	return Decimal{units: quotient.Int64(), scale: scale}, nil
+}
+
+func (d Decimal) String() string {
+	units := d.units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+	}
+
+	digits := strconv.FormatUint(absUint(units), 10)
+	if d.scale <= 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= d.scale {
+		digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+	}
+
+	point := len(digits) - d.scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	s := string(data)
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
+
+func absUint(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
@@ -0,0 +1,257 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Decimal
+		wantErr bool
+	}{
+		{input: "0", want: New(0, 0)},
+		{input: "12", want: New(12, 0)},
+		{input: "12.34", want: New(1234, 2)},
+		{input: "-12.34", want: New(-1234, 2)},
+		{input: "-0", want: New(0, 0)},
+		{input: "0.05", want: New(5, 2)},
+		{input: "1.000", want: New(1000, 3)},
+		{input: "0.000000000000000001", want: New(1, 18)},
+		{input: "9223372036854775807", want: New(math.MaxInt64, 0)},
+		{input: "-9223372036854775807", want: New(-math.MaxInt64, 0)},
+		{input: "", wantErr: true},
+		{input: "-", wantErr: true},
+		{input: "+12", wantErr: true},
+		{input: "--12", wantErr: true},
+		{input: "1-2", wantErr: true},
+		{input: "12.", wantErr: true},
+		{input: ".5", wantErr: true},
+		{input: "-.5", wantErr: true},
+		{input: "1.2.3", wantErr: true},
+		{input: "1.-2", wantErr: true},
+		{input: " 12", wantErr: true},
+		{input: "12a", wantErr: true},
+		{input: "1e3", wantErr: true},
+		{input: "1.5E-2", wantErr: true},
+		{input: "0x10", wantErr: true},
+		{input: "9223372036854775808", wantErr: true},
+		{input: "92233720368547758.08", wantErr: true},
+		{input: "-9223372036854775808", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %v, want error", tt.input, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.input, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	tests := []struct {
+		name    string
+		d       Decimal
+		scale   int
+		want    int64
+		wantErr bool
+	}{
+		{name: "same scale", d: New(1234, 2), scale: 2, want: 1234},
+		{name: "scale up", d: New(123, 1), scale: 2, want: 1230},
+		{name: "integer to cents", d: New(12, 0), scale: 2, want: 1200},
+		{name: "trailing zeros dropped", d: New(12300, 4), scale: 2, want: 123},
+		{name: "negative", d: New(-1234, 2), scale: 2, want: -1234},
+		{name: "negative scale up", d: New(-5, 1), scale: 3, want: -500},
+		{name: "too many decimal places", d: New(12345, 3), scale: 2, wantErr: true},
+		{name: "too many negative decimal places", d: New(-1, 3), scale: 2, wantErr: true},
+		{name: "JPY integer", d: New(500, 0), scale: Scale("JPY"), want: 500},
+		{name: "JPY zero fraction", d: New(50000, 2), scale: Scale("JPY"), want: 500},
+		{name: "JPY with fraction", d: New(50050, 2), scale: Scale("JPY"), wantErr: true},
+		{name: "KWD", d: New(1234, 2), scale: Scale("KWD"), want: 12340},
+		{name: "largest", d: New(math.MaxInt64/100, 0), scale: 2, want: math.MaxInt64 / 100 * 100},
+		{name: "overflow", d: New(math.MaxInt64/10+1, 0), scale: 1, wantErr: true},
+		{name: "negative overflow", d: New(math.MinInt64/10-1, 0), scale: 1, wantErr: true},
+		{name: "overflow on a later step", d: New(math.MaxInt64/10, 0), scale: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.d.MinorUnits(tt.scale)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: MinorUnits(%d) = %d, want error", tt.name, tt.scale, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: MinorUnits(%d) returned error: %v", tt.name, tt.scale, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: MinorUnits(%d) = %d, want %d", tt.name, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		d    Decimal
+		want string
+	}{
+		{d: New(0, 0), want: "0"},
+		{d: New(0, 2), want: "0.00"},
+		{d: New(1234, 2), want: "12.34"},
+		{d: New(-1234, 2), want: "-12.34"},
+		{d: New(5, 2), want: "0.05"},
+		{d: New(-5, 2), want: "-0.05"},
+		{d: New(500, 0), want: "500"},
+		{d: New(1, 3), want: "0.001"},
+		{d: New(math.MinInt64, 0), want: "-9223372036854775808"},
+		{d: New(math.MaxInt64, 2), want: "92233720368547758.07"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.d.String(); got != tt.want {
+			t.Errorf("%#v.String() = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestParseStringRoundTrip(t *testing.T) {
+	for _, input := range []string{"0", "0.00", "12.34", "-12.34", "-0.05", "1000000.001"} {
+		d, err := Parse(input)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", input, err)
+		}
+
+		if got := d.String(); got != input {
+			t.Errorf("Parse(%q).String() = %q", input, got)
+		}
+	}
+}
+
+func TestMulRat(t *testing.T) {
+	tests := []struct {
+		d       Decimal
+		rate    string
+		scale   int
+		want    Decimal
+		wantErr bool
+	}{
+		{d: New(1000, 0), rate: "0.00745", scale: 2, want: New(745, 2)},
+		// 0.1 * 3 is not 0.30000000000000004 and 1.005 rounds up, as the
+		// product is exact
+		{d: New(1, 1), rate: "3", scale: 2, want: New(30, 2)},
+		{d: New(1005, 3), rate: "1", scale: 2, want: New(101, 2)},
+		{d: New(1234, 2), rate: "1.0845", scale: 2, want: New(1338, 2)},
+		// Half away from zero
+		{d: New(25, 1), rate: "1", scale: 0, want: New(3, 0)},
+		{d: New(-25, 1), rate: "1", scale: 0, want: New(-3, 0)},
+		{d: New(24, 1), rate: "1", scale: 0, want: New(2, 0)},
+		{d: New(-24, 1), rate: "1", scale: 0, want: New(-2, 0)},
+		{d: New(10, 0), rate: "1/3", scale: 3, want: New(3333, 3)},
+		{d: New(20, 0), rate: "1/3", scale: 0, want: New(7, 0)},
+		{d: New(0, 2), rate: "1.5", scale: 2, want: New(0, 2)},
+		{d: New(123, 2), rate: "2", scale: 3, want: New(2460, 3)},
+		{d: New(math.MaxInt64, 0), rate: "2", scale: 0, wantErr: true},
+		{d: New(math.MaxInt64, 0), rate: "1", scale: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		rate, ok := new(big.Rat).SetString(tt.rate)
+		if !ok {
+			t.Fatalf("invalid rate %q", tt.rate)
+		}
+
+		got, err := tt.d.MulRat(rate, tt.scale)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s.MulRat(%s, %d) = %s, want error", tt.d, tt.rate, tt.scale, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s.MulRat(%s, %d) returned error: %v", tt.d, tt.rate, tt.scale, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%s.MulRat(%s, %d) = %#v, want %#v", tt.d, tt.rate, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	tests := map[string]int{
+		"USD": 2,
+		"EUR": 2,
+		"JPY": 0,
+		"KRW": 0,
+		"KWD": 3,
+		"CLF": 4,
+		"XXX": 2,
+		"":    2,
+	}
+
+	for currency, want := range tests {
+		if got := Scale(currency); got != want {
+			t.Errorf("Scale(%q) = %d, want %d", currency, got, want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var payload struct {
+		Amount Decimal `json:"amount"`
+	}
+
+	for input, want := range map[string]Decimal{
+		`{"amount":"12.50"}`: New(1250, 2),
+		`{"amount":12.5}`:    New(125, 1),
+		`{"amount":-3}`:      New(-3, 0),
+	} {
+		payload.Amount = Decimal{}
+		if err := json.Unmarshal([]byte(input), &payload); err != nil {
+			t.Errorf("Unmarshal(%s) returned error: %v", input, err)
+			continue
+		}
+
+		if payload.Amount != want {
+			t.Errorf("Unmarshal(%s) = %#v, want %#v", input, payload.Amount, want)
+		}
+	}
+
+	for _, input := range []string{`{"amount":"1e3"}`, `{"amount":1e3}`, `{"amount":"abc"}`, `{"amount":true}`} {
+		if err := json.Unmarshal([]byte(input), &payload); err == nil {
+			t.Errorf("Unmarshal(%s) = %#v, want error", input, payload.Amount)
+		}
+	}
+
+	// null leaves the decimal unchanged
+	payload.Amount = New(1, 0)
+	if err := json.Unmarshal([]byte(`{"amount":null}`), &payload); err != nil || payload.Amount != New(1, 0) {
+		t.Errorf("Unmarshal(null) = %#v, %v", payload.Amount, err)
+	}
+
+	data, err := json.Marshal(New(-1234, 2))
+	if err != nil || string(data) != `"-12.34"` {
+		t.Errorf("Marshal = %s, %v, want %q", data, err, `"-12.34"`)
+	}
+}
@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/jljl1337/xpense/internal/money"
+)
+
+// BudgetAlert is sent when the spending of a budget reaches a threshold of the
+// available amount in a period.
+type BudgetAlert struct {
+	ID           string  `json:"id"`
+	BudgetID     string  `json:"budgetID"`
+	BookID       string  `json:"bookID"`
+	BookName     string  `json:"bookName"`
+	CategoryID   *string `json:"categoryID"`
+	CategoryName *string `json:"categoryName"`
+	Period       string  `json:"period"`
+	PeriodStart  string  `json:"periodStart"`
+	PeriodEnd    string  `json:"periodEnd"`
+	// Threshold is in percent of the available amount
+	Threshold int64         `json:"threshold"`
+	Currency  string        `json:"currency"`
+	Spent     money.Decimal `json:"spent"`
+	Available money.Decimal `json:"available"`
+	CreatedAt string        `json:"createdAt"`
+}
+
+// Notifier delivers the alerts, an error means the alert was not delivered and
+// can be retried.
+type Notifier interface {
+	NotifyBudgetAlert(ctx context.Context, alert BudgetAlert) error
+}
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier posts the alerts as JSON to a URL, any 2xx response being a
+// successful delivery.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (n *WebhookNotifier) NotifyBudgetAlert(ctx context.Context, alert BudgetAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	// The receiver can deduplicate retried deliveries with the alert ID
+	req.Header.Set("X-Xpense-Event", "budget.alert")
+	req.Header.Set("X-Xpense-Alert-ID", alert.ID)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so that the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package ofx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/jljl1337/xpense/internal/money"
+)
+
+// Transaction is a bank statement transaction (STMTTRN).
+type Transaction struct {
+	// FITID is the unique ID of the transaction assigned by the bank
+	FITID string
+	Type  string
+	// Date is the posted date in YYYY-MM-DD
+	Date string
+	// Amount is negative for debits and positive for credits
+	Amount money.Decimal
+	Name   string
+	Memo   string
+}
+
+// Statement is the content of an OFX file.
+type Statement struct {
+	// Currency is the default currency of the statement (CURDEF), it is empty
+	// if the file does not specify one
+	Currency     string
+	Transactions []Transaction
+}
+
+var entityReplacer = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ")
+
+// Parse parses an OFX 1.x (SGML) or 2.x (XML) file, which includes QFX files.
+//
+// Both versions are read as a stream of tags, the value of an element being the
+// text following its start tag. This works for the SGML elements without end
+// tags, and ignores the header of both versions.
+//
+// A transaction repeated with the same FITID, which happens when a file holds
+// overlapping statements, is only kept once. Transactions sharing a FITID with
+// different content are all kept.
+func Parse(r io.Reader) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	content := string(data)
+
+	start := strings.Index(strings.ToUpper(content), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("OFX element not found")
+	}
+	content = content[start:]
+
+	statement := &Statement{Transactions: []Transaction{}}
+
+	seen := make(map[string][]Transaction)
+
+	var current *Transaction
+	for len(content) > 0 {
+		open := strings.IndexByte(content, '<')
+		if open < 0 {
+			break
+		}
+
+		end := strings.IndexByte(content[open:], '>')
+		if end < 0 {
+			return nil, errors.New("unterminated tag")
+		}
+
+		tag := strings.ToUpper(strings.TrimSpace(content[open+1 : open+end]))
+		content = content[open+end+1:]
+
+		// The value is the text up to the next tag
+		next := strings.IndexByte(content, '<')
+		if next < 0 {
+			next = len(content)
+		}
+		value := strings.TrimSpace(entityReplacer.Replace(content[:next]))
+
+		switch {
+		case tag == "STMTTRN":
+			current = &Transaction{}
+		case tag == "/STMTTRN":
+			if current == nil {
+				return nil, errors.New("unexpected end of transaction")
+			}
+			if err := checkTransaction(current); err != nil {
+				return nil, err
+			}
+			if !slices.Contains(seen[current.FITID], *current) {
+				seen[current.FITID] = append(seen[current.FITID], *current)
+				statement.Transactions = append(statement.Transactions, *current)
+			}
+			current = nil
+		case tag == "CURDEF" && statement.Currency == "":
+			statement.Currency = strings.ToUpper(value)
+		case current != nil:
+			if err := setTransactionField(current, tag, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if current != nil {
+		return nil, errors.New("unterminated transaction")
+	}
+
+	return statement, nil
+}
+
+func setTransactionField(transaction *Transaction, tag, value string) error {
+	switch tag {
+	case "FITID":
+		transaction.FITID = value
+	case "TRNTYPE":
+		transaction.Type = value
+	case "DTPOSTED":
+		date, err := parseDate(value)
+		if err != nil {
+			return err
+		}
+		transaction.Date = date
+	case "TRNAMT":
+		// Some banks use a decimal comma or an explicit plus sign
+		amount, err := money.Parse(strings.ReplaceAll(strings.TrimPrefix(value, "+"), ",", "."))
+		if err != nil {
+			return fmt.Errorf("invalid transaction amount: %w", err)
+		}
+		transaction.Amount = amount
+	case "NAME":
+		transaction.Name = value
+	case "MEMO":
+		transaction.Memo = value
+	}
+
+	return nil
+}
+
+func checkTransaction(transaction *Transaction) error {
+	if transaction.FITID == "" {
+		return errors.New("transaction without FITID")
+	}
+
+	if transaction.Date == "" {
+		return fmt.Errorf("transaction %s without posted date", transaction.FITID)
+	}
+
+	return nil
+}
+
+// parseDate converts an OFX datetime like "20230915120000.000[-5:EST]" to
+// YYYY-MM-DD, ignoring the time and time zone.
+func parseDate(value string) (string, error) {
+	if len(value) < 8 {
+		return "", fmt.Errorf("invalid date: %s", value)
+	}
+
+	for _, c := range value[:8] {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("invalid date: %s", value)
+		}
+	}
+
+	return value[0:4] + "-" + value[4:6] + "-" + value[6:8], nil
+}
@@ -0,0 +1,175 @@
+package ofx
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jljl1337/xpense/internal/money"
+)
+
+// wantStatement is the content of both files in testdata, which hold the same
+// transactions with a repeated one.
+var wantStatement = &Statement{
+	Currency: "USD",
+	Transactions: []Transaction{
+		{
+			FITID:  "202403020001",
+			Type:   "DEBIT",
+			Date:   "2024-03-02",
+			Amount: money.New(-1234, 2),
+			Name:   "COFFEE & BAGELS",
+			Memo:   "POS PURCHASE",
+		},
+		{
+			FITID:  "202403050001",
+			Type:   "CREDIT",
+			Date:   "2024-03-05",
+			Amount: money.New(150000, 2),
+			Name:   "PAYROLL",
+			Memo:   "PAYROLL",
+		},
+		{
+			FITID:  "202403100001",
+			Type:   "DEBIT",
+			Date:   "2024-03-10",
+			Amount: money.New(-4500, 2),
+			Name:   "GROCERY",
+		},
+		{
+			FITID:  "202403180001",
+			Type:   "DEBIT",
+			Date:   "2024-03-18",
+			Amount: money.New(-85, 1),
+			Name:   "PARKING",
+		},
+	},
+}
+
+func TestParseFile(t *testing.T) {
+	for _, name := range []string{"statement_v1.qfx", "statement_v2.ofx"} {
+		file, err := os.Open("testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		statement, err := Parse(file)
+		file.Close()
+		if err != nil {
+			t.Errorf("%s: Parse returned error: %v", name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(statement, wantStatement) {
+			t.Errorf("%s: Parse = %+v, want %+v", name, statement, wantStatement)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Transaction
+		wantErr bool
+	}{
+		{
+			name:  "no transactions",
+			input: "<OFX><BANKTRANLIST></BANKTRANLIST></OFX>",
+			want:  []Transaction{},
+		},
+		{
+			name:  "unclosed elements",
+			input: "<OFX><STMTTRN><FITID>1<DTPOSTED>20240101<TRNAMT>-1.5<NAME>SHOP</STMTTRN></OFX>",
+			want:  []Transaction{{FITID: "1", Date: "2024-01-01", Amount: money.New(-15, 1), Name: "SHOP"}},
+		},
+		{
+			name:  "unclosed transaction list",
+			input: "<OFX><BANKTRANLIST><STMTTRN><FITID>1<DTPOSTED>20240101<TRNAMT>2</STMTTRN>",
+			want:  []Transaction{{FITID: "1", Date: "2024-01-01", Amount: money.New(2, 0)}},
+		},
+		{
+			name: "repeated FITID with the same content",
+			input: "<OFX>" +
+				"<STMTTRN><FITID>1<DTPOSTED>20240101<TRNAMT>2</STMTTRN>" +
+				"<STMTTRN><FITID>2<DTPOSTED>20240102<TRNAMT>3</STMTTRN>" +
+				"<STMTTRN><FITID>1<DTPOSTED>20240101<TRNAMT>2</STMTTRN>" +
+				"</OFX>",
+			want: []Transaction{
+				{FITID: "1", Date: "2024-01-01", Amount: money.New(2, 0)},
+				{FITID: "2", Date: "2024-01-02", Amount: money.New(3, 0)},
+			},
+		},
+		{
+			name: "repeated FITID with different content",
+			input: "<OFX>" +
+				"<STMTTRN><FITID>1<DTPOSTED>20240101<TRNAMT>2</STMTTRN>" +
+				"<STMTTRN><FITID>1<DTPOSTED>20240101<TRNAMT>2.5</STMTTRN>" +
+				"</OFX>",
+			want: []Transaction{
+				{FITID: "1", Date: "2024-01-01", Amount: money.New(2, 0)},
+				{FITID: "1", Date: "2024-01-01", Amount: money.New(25, 1)},
+			},
+		},
+		{
+			name:    "no OFX element",
+			input:   "<HTML></HTML>",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated tag",
+			input:   "<OFX><STMTTRN><FITID",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated transaction",
+			input:   "<OFX><STMTTRN><FITID>1<DTPOSTED>20240101</OFX>",
+			wantErr: true,
+		},
+		{
+			name:    "unexpected end of transaction",
+			input:   "<OFX></STMTTRN></OFX>",
+			wantErr: true,
+		},
+		{
+			name:    "missing FITID",
+			input:   "<OFX><STMTTRN><DTPOSTED>20240101<TRNAMT>2</STMTTRN></OFX>",
+			wantErr: true,
+		},
+		{
+			name:    "missing posted date",
+			input:   "<OFX><STMTTRN><FITID>1<TRNAMT>2</STMTTRN></OFX>",
+			wantErr: true,
+		},
+		{
+			name:    "invalid date",
+			input:   "<OFX><STMTTRN><FITID>1<DTPOSTED>2024-01-01<TRNAMT>2</STMTTRN></OFX>",
+			wantErr: true,
+		},
+		{
+			name:    "invalid amount",
+			input:   "<OFX><STMTTRN><FITID>1<DTPOSTED>20240101<TRNAMT>1e3</STMTTRN></OFX>",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		statement, err := Parse(strings.NewReader(tt.input))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: Parse = %+v, want error", tt.name, statement)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: Parse returned error: %v", tt.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(statement.Transactions, tt.want) {
+			t.Errorf("%s: Parse = %+v, want %+v", tt.name, statement.Transactions, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+	FrequencyYearly  Frequency = "YEARLY"
+)
+
+// Rule is a subset of the iCalendar RRULE, e.g. "FREQ=MONTHLY;INTERVAL=1".
+//
+// Only FREQ (required) and INTERVAL (optional, defaults to 1) are supported,
+// the first occurrence is always the start date of the schedule.
+type Rule struct {
+	Frequency Frequency
+	Interval  int
+}
+
+// Parse parses a rule string like "FREQ=WEEKLY;INTERVAL=2".
+func Parse(s string) (Rule, error) {
+	rule := Rule{Interval: 1}
+
+	for part := range strings.SplitSeq(strings.TrimPrefix(strings.TrimSpace(s), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			return Rule{}, fmt.Errorf("invalid rule part: %s", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			frequency := Frequency(strings.ToUpper(value))
+			switch frequency {
+			case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+				rule.Frequency = frequency
+			default:
+				return Rule{}, fmt.Errorf("unsupported frequency: %s", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return Rule{}, fmt.Errorf("invalid interval: %s", value)
+			}
+			rule.Interval = interval
+		default:
+			return Rule{}, fmt.Errorf("unsupported rule part: %s", key)
+		}
+	}
+
+	if rule.Frequency == "" {
+		return Rule{}, errors.New("frequency is required")
+	}
+
+	return rule, nil
+}
+
+// String returns the canonical form of the rule.
+func (r Rule) String() string {
+	return fmt.Sprintf("FREQ=%s;INTERVAL=%d", r.Frequency, r.Interval)
+}
+
+// Nth returns the n-th (0-based) occurrence of the rule starting from start.
+//
+// Monthly and yearly occurrences falling on a day that does not exist in the
+// target month (e.g. the 31st, or 29 February) are moved to the last day of
+// that month.
+func (r Rule) Nth(start time.Time, n int) time.Time {
+	steps := n * r.Interval
+
+	switch r.Frequency {
+	case FrequencyDaily:
+		return start.AddDate(0, 0, steps)
+	case FrequencyWeekly:
+		return start.AddDate(0, 0, 7*steps)
+	case FrequencyMonthly:
+		return addMonthsClamped(start, steps)
+	case FrequencyYearly:
+		return addMonthsClamped(start, 12*steps)
+	default:
+		return start
+	}
+}
+
+// Between returns the occurrences of the rule starting from start that are
+// strictly after after and not later than until.
+func (r Rule) Between(start, after, until time.Time) []time.Time {
+	occurrences := []time.Time{}
+
+	for n := 0; ; n++ {
+		occurrence := r.Nth(start, n)
+		if occurrence.After(until) {
+			break
+		}
+		if occurrence.After(after) {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+
+	return occurrences
+}
+
+func addMonthsClamped(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+
+	// Day 1 never overflows, so the target month is always correct
+	firstOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, t.Location()).AddDate(0, months, 0)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+
+	return firstOfMonth.AddDate(0, 0, min(day, lastDay)-1)
+}
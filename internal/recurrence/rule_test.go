@@ -0,0 +1,187 @@
+package recurrence
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dates(times []time.Time) []string {
+	result := []string{}
+	for _, t := range times {
+		result = append(result, t.Format("2006-01-02"))
+	}
+	return result
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Rule
+		wantErr bool
+	}{
+		{input: "FREQ=MONTHLY", want: Rule{Frequency: FrequencyMonthly, Interval: 1}},
+		{input: "FREQ=WEEKLY;INTERVAL=2", want: Rule{Frequency: FrequencyWeekly, Interval: 2}},
+		{input: "RRULE:FREQ=DAILY;INTERVAL=3", want: Rule{Frequency: FrequencyDaily, Interval: 3}},
+		{input: " freq=yearly; ", want: Rule{Frequency: FrequencyYearly, Interval: 1}},
+		{input: "INTERVAL=2;FREQ=MONTHLY", want: Rule{Frequency: FrequencyMonthly, Interval: 2}},
+		{input: "", wantErr: true},
+		{input: "INTERVAL=2", wantErr: true},
+		{input: "FREQ=HOURLY", wantErr: true},
+		{input: "FREQ=MONTHLY;INTERVAL=0", wantErr: true},
+		{input: "FREQ=MONTHLY;INTERVAL=-1", wantErr: true},
+		{input: "FREQ=MONTHLY;INTERVAL=x", wantErr: true},
+		{input: "FREQ=MONTHLY;BYDAY=MO", wantErr: true},
+		{input: "FREQ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %+v, want error", tt.input, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.input, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+
+		// The canonical form parses to the same rule
+		if again, err := Parse(got.String()); err != nil || again != got {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", got.String(), again, err, got)
+		}
+	}
+}
+
+func TestNth(t *testing.T) {
+	tests := []struct {
+		rule  Rule
+		start string
+		want  []string
+	}{
+		{
+			rule:  Rule{Frequency: FrequencyDaily, Interval: 1},
+			start: "2024-02-27",
+			want:  []string{"2024-02-27", "2024-02-28", "2024-02-29", "2024-03-01"},
+		},
+		{
+			rule:  Rule{Frequency: FrequencyWeekly, Interval: 2},
+			start: "2024-12-23",
+			want:  []string{"2024-12-23", "2025-01-06", "2025-01-20", "2025-02-03"},
+		},
+		// Month ends are clamped without drifting to the clamped day
+		{
+			rule:  Rule{Frequency: FrequencyMonthly, Interval: 1},
+			start: "2024-01-31",
+			want:  []string{"2024-01-31", "2024-02-29", "2024-03-31", "2024-04-30", "2024-05-31"},
+		},
+		{
+			rule:  Rule{Frequency: FrequencyMonthly, Interval: 1},
+			start: "2023-01-31",
+			want:  []string{"2023-01-31", "2023-02-28", "2023-03-31"},
+		},
+		{
+			rule:  Rule{Frequency: FrequencyMonthly, Interval: 1},
+			start: "2024-01-30",
+			want:  []string{"2024-01-30", "2024-02-29", "2024-03-30"},
+		},
+		{
+			rule:  Rule{Frequency: FrequencyMonthly, Interval: 3},
+			start: "2024-11-30",
+			want:  []string{"2024-11-30", "2025-02-28", "2025-05-30", "2025-08-30"},
+		},
+		{
+			rule:  Rule{Frequency: FrequencyYearly, Interval: 1},
+			start: "2024-02-29",
+			want:  []string{"2024-02-29", "2025-02-28", "2026-02-28", "2027-02-28", "2028-02-29"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := []string{}
+		for n := range tt.want {
+			got = append(got, tt.rule.Nth(date(tt.start), n).Format("2006-01-02"))
+		}
+
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s from %s = %q, want %q", tt.rule, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	rule := Rule{Frequency: FrequencyMonthly, Interval: 1}
+	start := date("2024-01-31")
+
+	tests := []struct {
+		after string
+		until string
+		want  []string
+	}{
+		// Nothing materialized yet, starting right before the start date
+		{after: "2024-01-30", until: "2024-01-31", want: []string{"2024-01-31"}},
+		{after: "2024-01-30", until: "2024-01-30", want: []string{}},
+		// After is exclusive and until is inclusive
+		{after: "2024-01-31", until: "2024-02-29", want: []string{"2024-02-29"}},
+		{after: "2024-01-31", until: "2024-02-28", want: []string{}},
+		{after: "2024-02-29", until: "2024-02-29", want: []string{}},
+		// Missed periods are caught up at once
+		{after: "2024-01-31", until: "2024-06-15", want: []string{"2024-02-29", "2024-03-31", "2024-04-30", "2024-05-31"}},
+		{after: "2024-01-30", until: "2023-12-31", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		got := dates(rule.Between(start, date(tt.after), date(tt.until)))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Between(%s, %s) = %q, want %q", tt.after, tt.until, got, tt.want)
+		}
+	}
+}
+
+func TestBetweenCatchUp(t *testing.T) {
+	// Materializing in several runs gives every occurrence exactly once,
+	// however far apart the runs are
+	for _, rule := range []Rule{
+		{Frequency: FrequencyDaily, Interval: 3},
+		{Frequency: FrequencyWeekly, Interval: 1},
+		{Frequency: FrequencyMonthly, Interval: 1},
+		{Frequency: FrequencyYearly, Interval: 1},
+	} {
+		start := date("2024-01-31")
+		end := date("2029-12-31")
+
+		want := dates(rule.Between(start, start.AddDate(0, 0, -1), end))
+
+		for _, days := range []int{1, 7, 45, 400} {
+			got := []string{}
+			after := start.AddDate(0, 0, -1)
+			for !after.Equal(end) {
+				until := after.AddDate(0, 0, days)
+				if until.After(end) {
+					until = end
+				}
+				got = append(got, dates(rule.Between(start, after, until))...)
+				after = until
+			}
+
+			if !slices.Equal(got, want) {
+				t.Errorf("%s in runs of %d days = %q, want %q", rule, days, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+)
+
+const createAPIToken = `
+INSERT INTO api_token (
+    id,
+    user_id,
+    name,
+    token_hash,
+    scope,
+    expires_at,
+    created_at,
+    updated_at
+) VALUES (
+    :id,
+    :user_id,
+    :name,
+    :token_hash,
+    :scope,
+    :expires_at,
+    :created_at,
+    :updated_at
+)
+`
+
+type CreateAPITokenParams struct {
+	ID        string  `db:"id"`
+	UserID    string  `db:"user_id"`
+	Name      string  `db:"name"`
+	TokenHash string  `db:"token_hash"`
+	Scope     string  `db:"scope"`
+	ExpiresAt *string `db:"expires_at"`
+	CreatedAt string  `db:"created_at"`
+	UpdatedAt string  `db:"updated_at"`
+}
+
+func (q *Queries) CreateAPIToken(ctx context.Context, arg CreateAPITokenParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createAPIToken, arg)
+}
+
+const getAPITokensByUserID = `
+SELECT
+    *
+FROM
+    api_token
+WHERE
+    user_id = :user_id
+ORDER BY
+    created_at DESC
+`
+
+type GetAPITokensByUserIDParams struct {
+	UserID string `db:"user_id"`
+}
+
+func (q *Queries) GetAPITokensByUserID(ctx context.Context, userID string) ([]APIToken, error) {
+	items := []APIToken{}
+	err := NamedSelectContext(ctx, q.db, &items, getAPITokensByUserID, GetAPITokensByUserIDParams{UserID: userID})
+	return items, err
+}
+
+const getAPITokenByTokenHash = `
+SELECT
+    *
+FROM
+    api_token
+WHERE
+    token_hash = :token_hash
+`
+
+type GetAPITokenByTokenHashParams struct {
+	TokenHash string `db:"token_hash"`
+}
+
+func (q *Queries) GetAPITokenByTokenHash(ctx context.Context, tokenHash string) ([]APIToken, error) {
+	items := []APIToken{}
+	err := NamedSelectContext(ctx, q.db, &items, getAPITokenByTokenHash, GetAPITokenByTokenHashParams{TokenHash: tokenHash})
+	return items, err
+}
+
+const updateAPITokenLastUsedAt = `
+UPDATE
+    api_token
+SET
+    last_used_at = :last_used_at
+WHERE
+    id = :id
+`
+
+type UpdateAPITokenLastUsedAtParams struct {
+	LastUsedAt string `db:"last_used_at"`
+	ID         string `db:"id"`
+}
+
+func (q *Queries) UpdateAPITokenLastUsedAt(ctx context.Context, arg UpdateAPITokenLastUsedAtParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateAPITokenLastUsedAt, arg)
+}
+
+const deleteAPITokenByID = `
+DELETE FROM
+    api_token
+WHERE
+    id = :id AND
+    user_id = :user_id
+`
+
+type DeleteAPITokenByIDParams struct {
+	ID     string `db:"id"`
+	UserID string `db:"user_id"`
+}
+
+// DeleteAPITokenByID deletes a token of the user, no row is affected if the
+// token belongs to another user.
+func (q *Queries) DeleteAPITokenByID(ctx context.Context, arg DeleteAPITokenByIDParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteAPITokenByID, arg)
+}
@@ -11,14 +11,16 @@ INSERT INTO book (
     name,
     description,
     created_at,
-    updated_at
+    updated_at,
+    currency
 ) VALUES (
     :id,
     :user_id,
 	:name,
 	:description,
 	:created_at,
-	:updated_at
+	:updated_at,
+	:currency
 )
 `
 
@@ -29,6 +31,7 @@ type CreateBookParams struct {
 	Description string `db:"description"`
 	CreatedAt   string `db:"created_at"`
 	UpdatedAt   string `db:"updated_at"`
+	Currency    string `db:"currency"`
 }
 
 func (q *Queries) CreateBook(ctx context.Context, arg CreateBookParams) (int64, error) {
@@ -40,8 +43,10 @@ SELECT
     COUNT(*) AS count
 FROM
     book
+JOIN
+    book_member ON book_member.book_id = book.id
 WHERE
-    user_id = :user_id
+    book_member.user_id = :user_id
 `
 
 type GetBooksCountByUserIDParams struct {
@@ -56,13 +61,15 @@ func (q *Queries) GetBooksCountByUserID(ctx context.Context, userID string) (int
 
 const getBooksByUserID = `
 SELECT
-    *
+    book.*
 FROM
     book
+JOIN
+    book_member ON book_member.book_id = book.id
 WHERE
-    user_id = :user_id
+    book_member.user_id = :user_id
 ORDER BY
-    name ASC
+    book.name ASC
 LIMIT
     :limit
 OFFSET
@@ -141,15 +148,16 @@ const checkBookAccess = `
 SELECT
     COUNT(*) > 0 AS can_access
 FROM
-    book
+    book_member AS m
 WHERE
-    id = :book_id AND
-    user_id = :user_id
-`
+    m.book_id = :book_id AND
+    m.user_id = :user_id AND` + memberHasRole
 
 type CheckBookAccessParams struct {
 	BookID string `db:"book_id"`
 	UserID string `db:"user_id"`
+	// Role is the minimum role of the user in the book
+	Role string `db:"role"`
 }
 
 func (q *Queries) CheckBookAccess(ctx context.Context, arg CheckBookAccessParams) (bool, error) {
@@ -157,3 +165,25 @@ func (q *Queries) CheckBookAccess(ctx context.Context, arg CheckBookAccessParams
 	err := NamedGetContext(ctx, q.db, &canAccess, checkBookAccess, arg)
 	return canAccess, err
 }
+
+const updateBookUserID = `
+UPDATE
+    book
+SET
+    user_id = :user_id,
+    updated_at = :updated_at
+WHERE
+    id = :id
+`
+
+type UpdateBookUserIDParams struct {
+	UserID    string `db:"user_id"`
+	UpdatedAt string `db:"updated_at"`
+	ID        string `db:"id"`
+}
+
+// UpdateBookUserID changes the owner of a book, the roles of the members are
+// not changed.
+func (q *Queries) UpdateBookUserID(ctx context.Context, arg UpdateBookUserIDParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateBookUserID, arg)
+}
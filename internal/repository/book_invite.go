@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+)
+
+const createBookInvite = `
+INSERT INTO book_invite (
+    id,
+    book_id,
+    code,
+    role,
+    created_by,
+    expires_at,
+    created_at
+) VALUES (
+    :id,
+    :book_id,
+    :code,
+    :role,
+    :created_by,
+    :expires_at,
+    :created_at
+)
+`
+
+type CreateBookInviteParams struct {
+	ID        string `db:"id"`
+	BookID    string `db:"book_id"`
+	Code      string `db:"code"`
+	Role      string `db:"role"`
+	CreatedBy string `db:"created_by"`
+	ExpiresAt string `db:"expires_at"`
+	CreatedAt string `db:"created_at"`
+}
+
+func (q *Queries) CreateBookInvite(ctx context.Context, arg CreateBookInviteParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createBookInvite, arg)
+}
+
+const getPendingBookInvitesByBookID = `
+SELECT
+    *
+FROM
+    book_invite
+WHERE
+    book_id = :book_id AND
+    expires_at > :now
+ORDER BY
+    created_at DESC
+`
+
+type GetPendingBookInvitesByBookIDParams struct {
+	BookID string `db:"book_id"`
+	Now    string `db:"now"`
+}
+
+// GetPendingBookInvitesByBookID returns the invites of a book not expired yet,
+// the latest first.
+func (q *Queries) GetPendingBookInvitesByBookID(ctx context.Context, arg GetPendingBookInvitesByBookIDParams) ([]BookInvite, error) {
+	items := []BookInvite{}
+	err := NamedSelectContext(ctx, q.db, &items, getPendingBookInvitesByBookID, arg)
+	return items, err
+}
+
+const getBookInviteByID = `
+SELECT
+    *
+FROM
+    book_invite
+WHERE
+    id = :id
+`
+
+type GetBookInviteByIDParams struct {
+	ID string `db:"id"`
+}
+
+func (q *Queries) GetBookInviteByID(ctx context.Context, id string) ([]BookInvite, error) {
+	items := []BookInvite{}
+	err := NamedSelectContext(ctx, q.db, &items, getBookInviteByID, GetBookInviteByIDParams{ID: id})
+	return items, err
+}
+
+const getBookInviteByCode = `
+SELECT
+    *
+FROM
+    book_invite
+WHERE
+    code = :code
+`
+
+type GetBookInviteByCodeParams struct {
+	Code string `db:"code"`
+}
+
+func (q *Queries) GetBookInviteByCode(ctx context.Context, code string) ([]BookInvite, error) {
+	items := []BookInvite{}
+	err := NamedSelectContext(ctx, q.db, &items, getBookInviteByCode, GetBookInviteByCodeParams{Code: code})
+	return items, err
+}
+
+const deleteBookInviteByID = `
+DELETE FROM
+    book_invite
+WHERE
+    id = :id
+`
+
+type DeleteBookInviteByIDParams struct {
+	ID string `db:"id"`
+}
+
+func (q *Queries) DeleteBookInviteByID(ctx context.Context, id string) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteBookInviteByID, DeleteBookInviteByIDParams{ID: id})
+}
+
+const deleteBookInviteByExpiresAt = `
+DELETE FROM
+    book_invite
+WHERE
+    expires_at < :expires_at
+`
+
+type DeleteBookInviteByExpiresAtParams struct {
+	ExpiresAt string `db:"expires_at"`
+}
+
+func (q *Queries) DeleteBookInviteByExpiresAt(ctx context.Context, expiresAt string) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteBookInviteByExpiresAt, DeleteBookInviteByExpiresAtParams{ExpiresAt: expiresAt})
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+)
+
+// memberHasRole is the condition of the member m having at least the role
+// :role, the roles being ordered viewer, editor, owner.
+const memberHasRole = `
+    (CASE m.role WHEN 'owner' THEN 3 WHEN 'editor' THEN 2 ELSE 1 END) >=
+    (CASE :role WHEN 'owner' THEN 3 WHEN 'editor' THEN 2 ELSE 1 END)`
+
+const createBookMember = `
+INSERT INTO book_member (
+    book_id,
+    user_id,
+    role,
+    created_at,
+    updated_at
+) VALUES (
+    :book_id,
+    :user_id,
+    :role,
+    :created_at,
+    :updated_at
+)
+ON CONFLICT (book_id, user_id) DO NOTHING
+`
+
+type CreateBookMemberParams struct {
+	BookID    string `db:"book_id"`
+	UserID    string `db:"user_id"`
+	Role      string `db:"role"`
+	CreatedAt string `db:"created_at"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+// CreateBookMember adds a member to a book, no row is affected if the user is
+// already a member.
+func (q *Queries) CreateBookMember(ctx context.Context, arg CreateBookMemberParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createBookMember, arg)
+}
+
+const getBookMembersByBookID = `
+SELECT
+    book_member.*,
+    user.username
+FROM
+    book_member
+JOIN
+    user ON user.id = book_member.user_id
+WHERE
+    book_member.book_id = :book_id
+ORDER BY
+    CASE book_member.role WHEN 'owner' THEN 1 WHEN 'editor' THEN 2 ELSE 3 END,
+    user.username ASC
+`
+
+type GetBookMembersByBookIDParams struct {
+	BookID string `db:"book_id"`
+}
+
+type BookMemberRow struct {
+	BookMember
+	Username string `json:"username" db:"username"`
+}
+
+// GetBookMembersByBookID returns the members of a book with their username, the
+// owner first.
+func (q *Queries) GetBookMembersByBookID(ctx context.Context, bookID string) ([]BookMemberRow, error) {
+	items := []BookMemberRow{}
+	err := NamedSelectContext(ctx, q.db, &items, getBookMembersByBookID, GetBookMembersByBookIDParams{BookID: bookID})
+	return items, err
+}
+
+const getBookMember = `
+SELECT
+    *
+FROM
+    book_member
+WHERE
+    book_id = :book_id AND
+    user_id = :user_id
+`
+
+type GetBookMemberParams struct {
+	BookID string `db:"book_id"`
+	UserID string `db:"user_id"`
+}
+
+func (q *Queries) GetBookMember(ctx context.Context, arg GetBookMemberParams) ([]BookMember, error) {
+	items := []BookMember{}
+	err := NamedSelectContext(ctx, q.db, &items, getBookMember, arg)
+	return items, err
+}
+
+const updateBookMemberRole = `
+UPDATE
+    book_member
+SET
+    role = :role,
+    updated_at = :updated_at
+WHERE
+    book_id = :book_id AND
+    user_id = :user_id
+`
+
+type UpdateBookMemberRoleParams struct {
+	Role      string `db:"role"`
+	UpdatedAt string `db:"updated_at"`
+	BookID    string `db:"book_id"`
+	UserID    string `db:"user_id"`
+}
+
+func (q *Queries) UpdateBookMemberRole(ctx context.Context, arg UpdateBookMemberRoleParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateBookMemberRole, arg)
+}
+
+const deleteBookMember = `
+DELETE FROM
+    book_member
+WHERE
+    book_id = :book_id AND
+    user_id = :user_id
+`
+
+type DeleteBookMemberParams struct {
+	BookID string `db:"book_id"`
+	UserID string `db:"user_id"`
+}
+
+func (q *Queries) DeleteBookMember(ctx context.Context, arg DeleteBookMemberParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteBookMember, arg)
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+)
+
+const createBudget = `
+INSERT INTO budget (
+    id,
+    book_id,
+    category_id,
+    amount,
+    period,
+    rollover,
+    created_at,
+    updated_at
+) VALUES (
+    :id,
+    :book_id,
+    :category_id,
+    :amount,
+    :period,
+    :rollover,
+    :created_at,
+    :updated_at
+)
+`
+
+type CreateBudgetParams struct {
+	ID         string  `db:"id"`
+	BookID     string  `db:"book_id"`
+	CategoryID *string `db:"category_id"`
+	Amount     int64   `db:"amount"`
+	Period     string  `db:"period"`
+	Rollover   bool    `db:"rollover"`
+	CreatedAt  string  `db:"created_at"`
+	UpdatedAt  string  `db:"updated_at"`
+}
+
+func (q *Queries) CreateBudget(ctx context.Context, arg CreateBudgetParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createBudget, arg)
+}
+
+const getBudgetsByBookID = `
+SELECT
+    *
+FROM
+    budget
+WHERE
+    book_id = :book_id
+ORDER BY
+    created_at ASC
+`
+
+type GetBudgetsByBookIDParams struct {
+	BookID string `db:"book_id"`
+}
+
+func (q *Queries) GetBudgetsByBookID(ctx context.Context, bookID string) ([]Budget, error) {
+	items := []Budget{}
+	err := NamedSelectContext(ctx, q.db, &items, getBudgetsByBookID, GetBudgetsByBookIDParams{BookID: bookID})
+	return items, err
+}
+
+const getBudgetByID = `
+SELECT
+    *
+FROM
+    budget
+WHERE
+    id = :id
+`
+
+type GetBudgetByIDParams struct {
+	ID string `db:"id"`
+}
+
+func (q *Queries) GetBudgetByID(ctx context.Context, id string) ([]Budget, error) {
+	items := []Budget{}
+	err := NamedSelectContext(ctx, q.db, &items, getBudgetByID, GetBudgetByIDParams{ID: id})
+	return items, err
+}
+
+const updateBudgetByID = `
+UPDATE
+    budget
+SET
+    category_id = :category_id,
+    amount = :amount,
+    period = :period,
+    rollover = :rollover,
+    updated_at = :updated_at
+WHERE
+    id = :id
+`
+
+type UpdateBudgetByIDParams struct {
+	CategoryID *string `db:"category_id"`
+	Amount     int64   `db:"amount"`
+	Period     string  `db:"period"`
+	Rollover   bool    `db:"rollover"`
+	UpdatedAt  string  `db:"updated_at"`
+	ID         string  `db:"id"`
+}
+
+func (q *Queries) UpdateBudgetByID(ctx context.Context, arg UpdateBudgetByIDParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateBudgetByID, arg)
+}
+
+const deleteBudgetByID = `
+DELETE FROM
+    budget
+WHERE
+    id = :id
+`
+
+type DeleteBudgetByIDParams struct {
+	ID string `db:"id"`
+}
+
+func (q *Queries) DeleteBudgetByID(ctx context.Context, id string) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteBudgetByID, DeleteBudgetByIDParams{ID: id})
+}
+
+const getBudgetSpendingByDate = `
+SELECT
+    date,
+    SUM(amount) AS total
+FROM
+    expense
+WHERE
+    book_id = :book_id AND
+    type = 'expense' AND
+    date >= :from AND
+    date <= :to AND
+    (:category_id = '' OR category_id IN (` + categoryDescendants + `))
+GROUP BY
+    date
+ORDER BY
+    date ASC
+`
+
+type GetBudgetSpendingByDateParams struct {
+	BookID string `db:"book_id"`
+	// CategoryID is empty for all the categories, and includes the
+	// subcategories otherwise
+	CategoryID string `db:"category_id"`
+	// From and To are the inclusive date range
+	From string `db:"from"`
+	To   string `db:"to"`
+}
+
+type BudgetSpendingRow struct {
+	Date  string `db:"date"`
+	Total int64  `db:"total"`
+}
+
+// GetBudgetSpendingByDate returns the total amount of the expenses of a book,
+// or of a category and its subcategories, for each date in a date range.
+// Incomes and transfers are not spending.
+func (q *Queries) GetBudgetSpendingByDate(ctx context.Context, arg GetBudgetSpendingByDateParams) ([]BudgetSpendingRow, error) {
+	items := []BudgetSpendingRow{}
+	err := NamedSelectContext(ctx, q.db, &items, getBudgetSpendingByDate, arg)
+	return items, err
+}
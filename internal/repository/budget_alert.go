@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+)
+
+const createBudgetAlert = `
+INSERT INTO budget_alert (
+    id,
+    budget_id,
+    period_start,
+    period_end,
+    threshold,
+    spent,
+    available,
+    created_at
+) VALUES (
+    :id,
+    :budget_id,
+    :period_start,
+    :period_end,
+    :threshold,
+    :spent,
+    :available,
+    :created_at
+)
+ON CONFLICT (budget_id, period_start, threshold) DO NOTHING
+`
+
+type CreateBudgetAlertParams struct {
+	ID          string `db:"id"`
+	BudgetID    string `db:"budget_id"`
+	PeriodStart string `db:"period_start"`
+	PeriodEnd   string `db:"period_end"`
+	Threshold   int64  `db:"threshold"`
+	Spent       int64  `db:"spent"`
+	Available   int64  `db:"available"`
+	CreatedAt   string `db:"created_at"`
+}
+
+// CreateBudgetAlert creates an alert unless the threshold of the budget has
+// already been alerted in the period, in which case no row is affected.
+func (q *Queries) CreateBudgetAlert(ctx context.Context, arg CreateBudgetAlertParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createBudgetAlert, arg)
+}
+
+const getBudgetAlertsByBudgetID = `
+SELECT
+    *
+FROM
+    budget_alert
+WHERE
+    budget_id = :budget_id
+ORDER BY
+    period_start DESC,
+    threshold DESC
+`
+
+type GetBudgetAlertsByBudgetIDParams struct {
+	BudgetID string `db:"budget_id"`
+}
+
+func (q *Queries) GetBudgetAlertsByBudgetID(ctx context.Context, budgetID string) ([]BudgetAlert, error) {
+	items := []BudgetAlert{}
+	err := NamedSelectContext(ctx, q.db, &items, getBudgetAlertsByBudgetID, GetBudgetAlertsByBudgetIDParams{BudgetID: budgetID})
+	return items, err
+}
+
+const getPendingBudgetAlerts = `
+SELECT
+    budget_alert.id,
+    budget_alert.budget_id,
+    budget.book_id,
+    book.name AS book_name,
+    book.currency,
+    budget.category_id,
+    category.name AS category_name,
+    budget.period,
+    budget_alert.period_start,
+    budget_alert.period_end,
+    budget_alert.threshold,
+    budget_alert.spent,
+    budget_alert.available,
+    budget_alert.created_at
+FROM
+    budget_alert
+JOIN
+    budget ON budget.id = budget_alert.budget_id
+JOIN
+    book ON book.id = budget.book_id
+LEFT JOIN
+    category ON category.id = budget.category_id
+WHERE
+    budget_alert.delivered_at IS NULL AND
+    (SELECT COUNT(*) FROM budget_alert_delivery WHERE budget_alert_id = budget_alert.id) < :max_attempts
+ORDER BY
+    budget_alert.created_at ASC
+`
+
+type GetPendingBudgetAlertsParams struct {
+	MaxAttempts int `db:"max_attempts"`
+}
+
+type PendingBudgetAlertRow struct {
+	ID           string  `db:"id"`
+	BudgetID     string  `db:"budget_id"`
+	BookID       string  `db:"book_id"`
+	BookName     string  `db:"book_name"`
+	Currency     string  `db:"currency"`
+	CategoryID   *string `db:"category_id"`
+	CategoryName *string `db:"category_name"`
+	Period       string  `db:"period"`
+	PeriodStart  string  `db:"period_start"`
+	PeriodEnd    string  `db:"period_end"`
+	Threshold    int64   `db:"threshold"`
+	Spent        int64   `db:"spent"`
+	Available    int64   `db:"available"`
+	CreatedAt    string  `db:"created_at"`
+}
+
+// GetPendingBudgetAlerts returns the alerts not delivered yet with less than
+// the maximum number of delivery attempts, oldest first.
+func (q *Queries) GetPendingBudgetAlerts(ctx context.Context, arg GetPendingBudgetAlertsParams) ([]PendingBudgetAlertRow, error) {
+	items := []PendingBudgetAlertRow{}
+	err := NamedSelectContext(ctx, q.db, &items, getPendingBudgetAlerts, arg)
+	return items, err
+}
+
+const updateBudgetAlertDeliveredAt = `
+UPDATE
+    budget_alert
+SET
+    delivered_at = :delivered_at
+WHERE
+    id = :id
+`
+
+type UpdateBudgetAlertDeliveredAtParams struct {
+	DeliveredAt string `db:"delivered_at"`
+	ID          string `db:"id"`
+}
+
+func (q *Queries) UpdateBudgetAlertDeliveredAt(ctx context.Context, arg UpdateBudgetAlertDeliveredAtParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateBudgetAlertDeliveredAt, arg)
+}
+
+const createBudgetAlertDelivery = `
+INSERT INTO budget_alert_delivery (
+    id,
+    budget_alert_id,
+    attempted_at,
+    error
+) VALUES (
+    :id,
+    :budget_alert_id,
+    :attempted_at,
+    :error
+)
+`
+
+type CreateBudgetAlertDeliveryParams struct {
+	ID            string `db:"id"`
+	BudgetAlertID string `db:"budget_alert_id"`
+	AttemptedAt   string `db:"attempted_at"`
+	Error         string `db:"error"`
+}
+
+func (q *Queries) CreateBudgetAlertDelivery(ctx context.Context, arg CreateBudgetAlertDeliveryParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createBudgetAlertDelivery, arg)
+}
+
+const getBudgetAlertDeliveriesByBudgetID = `
+SELECT
+    budget_alert_delivery.*
+FROM
+    budget_alert_delivery
+JOIN
+    budget_alert ON budget_alert.id = budget_alert_delivery.budget_alert_id
+WHERE
+    budget_alert.budget_id = :budget_id
+ORDER BY
+    budget_alert_delivery.attempted_at ASC
+`
+
+type GetBudgetAlertDeliveriesByBudgetIDParams struct {
+	BudgetID string `db:"budget_id"`
+}
+
+// GetBudgetAlertDeliveriesByBudgetID returns the delivery attempts of all the
+// alerts of a budget, oldest first.
+func (q *Queries) GetBudgetAlertDeliveriesByBudgetID(ctx context.Context, budgetID string) ([]BudgetAlertDelivery, error) {
+	items := []BudgetAlertDelivery{}
+	err := NamedSelectContext(ctx, q.db, &items, getBudgetAlertDeliveriesByBudgetID, GetBudgetAlertDeliveriesByBudgetIDParams{BudgetID: budgetID})
+	return items, err
+}
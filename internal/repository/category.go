@@ -11,24 +11,27 @@ INSERT INTO category (
     name,
     description,
     created_at,
-    updated_at
+    updated_at,
+    parent_id
 ) VALUES (
     :id,
     :book_id,
 	:name,
 	:description,
 	:created_at,
-	:updated_at
+	:updated_at,
+	:parent_id
 )
 `
 
 type CreateCategoryParams struct {
-	ID          string `db:"id"`
-	BookID      string `db:"book_id"`
-	Name        string `db:"name"`
-	Description string `db:"description"`
-	CreatedAt   string `db:"created_at"`
-	UpdatedAt   string `db:"updated_at"`
+	ID          string  `db:"id"`
+	BookID      string  `db:"book_id"`
+	Name        string  `db:"name"`
+	Description string  `db:"description"`
+	CreatedAt   string  `db:"created_at"`
+	UpdatedAt   string  `db:"updated_at"`
+	ParentID    *string `db:"parent_id"`
 }
 
 func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams) (int64, error) {
@@ -41,18 +44,20 @@ SELECT
 FROM
     category
 WHERE
-    book_id = :book_id
+    book_id = :book_id AND
+    (archived_at IS NULL OR :include_archived)
 ORDER BY
     name ASC
 `
 
 type GetCategoriesByBookIDParams struct {
-	BookID string `db:"book_id"`
+	BookID          string `db:"book_id"`
+	IncludeArchived bool   `db:"include_archived"`
 }
 
-func (q *Queries) GetCategoriesByBookID(ctx context.Context, bookID string) ([]Category, error) {
+func (q *Queries) GetCategoriesByBookID(ctx context.Context, arg GetCategoriesByBookIDParams) ([]Category, error) {
 	items := []Category{}
-	err := NamedSelectContext(ctx, q.db, &items, getCategoriesByBookID, GetCategoriesByBookIDParams{BookID: bookID})
+	err := NamedSelectContext(ctx, q.db, &items, getCategoriesByBookID, arg)
 	return items, err
 }
 
@@ -75,22 +80,70 @@ func (q *Queries) GetCategoryByID(ctx context.Context, id string) ([]Category, e
 	return items, err
 }
 
+// categoryDescendants is a subquery of the IDs of a category and all its
+// descendants, the category being the :category_id parameter.
+const categoryDescendants = `
+    WITH RECURSIVE descendant (id) AS (
+        SELECT :category_id
+        UNION
+        SELECT category.id FROM category JOIN descendant ON category.parent_id = descendant.id
+    )
+    SELECT id FROM descendant
+`
+
+// categoryAncestors is a common table expression of the pairs of categories
+// of a book and their ancestors, including themselves.
+const categoryAncestors = `
+WITH RECURSIVE category_ancestor (category_id, ancestor_id) AS (
+    SELECT id, id FROM category WHERE book_id = :book_id
+    UNION
+    SELECT
+        category_ancestor.category_id,
+        category.parent_id
+    FROM
+        category_ancestor
+    JOIN
+        category ON category.id = category_ancestor.ancestor_id
+    WHERE
+        category.parent_id IS NOT NULL
+)`
+
+const checkCategoryDescendant = `
+SELECT
+    :descendant_id IN (` + categoryDescendants + `) AS is_descendant
+`
+
+type CheckCategoryDescendantParams struct {
+	CategoryID   string `db:"category_id"`
+	DescendantID string `db:"descendant_id"`
+}
+
+// CheckCategoryDescendant reports whether a category is the category itself or
+// one of its descendants.
+func (q *Queries) CheckCategoryDescendant(ctx context.Context, arg CheckCategoryDescendantParams) (bool, error) {
+	var isDescendant bool
+	err := NamedGetContext(ctx, q.db, &isDescendant, checkCategoryDescendant, arg)
+	return isDescendant, err
+}
+
 const updateCategoryByID = `
 UPDATE 
     category
 SET
     name = :name,
     description = :description,
+    parent_id = :parent_id,
     updated_at = :updated_at
 WHERE
     id = :id
 `
 
 type UpdateCategoryByIDParams struct {
-	Name        string `db:"name"`
-	Description string `db:"description"`
-	UpdatedAt   string `db:"updated_at"`
-	ID          string `db:"id"`
+	Name        string  `db:"name"`
+	Description string  `db:"description"`
+	ParentID    *string `db:"parent_id"`
+	UpdatedAt   string  `db:"updated_at"`
+	ID          string  `db:"id"`
 }
 
 func (q *Queries) UpdateCategoryByID(ctx context.Context, arg UpdateCategoryByIDParams) (int64, error) {
@@ -117,18 +170,19 @@ SELECT
     COUNT(*) > 0 AS can_access
 FROM
     category AS c
-LEFT JOIN
-    book AS b
+JOIN
+    book_member AS m
 ON
-    c.book_id = b.id
+    c.book_id = m.book_id
 WHERE
     c.id = :id AND
-    b.user_id = :user_id
-`
+    m.user_id = :user_id AND` + memberHasRole
 
 type CheckCategoryAccessParams struct {
 	CategoryID string `db:"id"`
 	UserID     string `db:"user_id"`
+	// Role is the minimum role of the user in the book
+	Role string `db:"role"`
 }
 
 func (q *Queries) CheckCategoryAccess(ctx context.Context, arg CheckCategoryAccessParams) (bool, error) {
@@ -136,3 +190,130 @@ func (q *Queries) CheckCategoryAccess(ctx context.Context, arg CheckCategoryAcce
 	err := NamedGetContext(ctx, q.db, &canAccess, checkCategoryAccess, arg)
 	return canAccess, err
 }
+
+const countCategoryUsage = `
+SELECT
+    (SELECT COUNT(*) FROM expense WHERE category_id = :id) +
+    (SELECT COUNT(*) FROM recurring_expense WHERE category_id = :id) +
+    (SELECT COUNT(*) FROM budget WHERE category_id = :id) AS count
+`
+
+type CountCategoryUsageParams struct {
+	ID string `db:"id"`
+}
+
+// CountCategoryUsage returns the number of expenses, recurring expenses and
+// budgets of a category, which would be deleted with it.
+func (q *Queries) CountCategoryUsage(ctx context.Context, id string) (int64, error) {
+	var count int64
+	err := NamedGetContext(ctx, q.db, &count, countCategoryUsage, CountCategoryUsageParams{ID: id})
+	return count, err
+}
+
+const reassignExpensesCategory = `
+UPDATE
+    expense
+SET
+    category_id = :to_id,
+    updated_at = :updated_at
+WHERE
+    category_id = :from_id
+`
+
+type ReassignExpensesCategoryParams struct {
+	FromID    string `db:"from_id"`
+	ToID      string `db:"to_id"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+// ReassignExpensesCategory moves all the expenses of a category to another.
+func (q *Queries) ReassignExpensesCategory(ctx context.Context, arg ReassignExpensesCategoryParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, reassignExpensesCategory, arg)
+}
+
+const reassignRecurringExpensesCategory = `
+UPDATE
+    recurring_expense
+SET
+    category_id = :to_id,
+    updated_at = :updated_at
+WHERE
+    category_id = :from_id
+`
+
+type ReassignRecurringExpensesCategoryParams struct {
+	FromID    string `db:"from_id"`
+	ToID      string `db:"to_id"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+// ReassignRecurringExpensesCategory moves all the recurring expenses of a
+// category to another.
+func (q *Queries) ReassignRecurringExpensesCategory(ctx context.Context, arg ReassignRecurringExpensesCategoryParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, reassignRecurringExpensesCategory, arg)
+}
+
+const reassignBudgetsCategory = `
+UPDATE
+    budget
+SET
+    category_id = :to_id,
+    updated_at = :updated_at
+WHERE
+    category_id = :from_id
+`
+
+type ReassignBudgetsCategoryParams struct {
+	FromID    string `db:"from_id"`
+	ToID      string `db:"to_id"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+// ReassignBudgetsCategory moves all the budgets of a category to another,
+// keeping their alerts.
+func (q *Queries) ReassignBudgetsCategory(ctx context.Context, arg ReassignBudgetsCategoryParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, reassignBudgetsCategory, arg)
+}
+
+const reassignSubcategories = `
+UPDATE
+    category
+SET
+    parent_id = :to_id,
+    updated_at = :updated_at
+WHERE
+    parent_id = :from_id
+`
+
+type ReassignSubcategoriesParams struct {
+	FromID    string `db:"from_id"`
+	ToID      string `db:"to_id"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+// ReassignSubcategories moves the direct subcategories of a category under
+// another.
+func (q *Queries) ReassignSubcategories(ctx context.Context, arg ReassignSubcategoriesParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, reassignSubcategories, arg)
+}
+
+const setCategoryArchivedAt = `
+UPDATE
+    category
+SET
+    archived_at = :archived_at,
+    updated_at = :updated_at
+WHERE
+    id = :id
+`
+
+type SetCategoryArchivedAtParams struct {
+	// ArchivedAt is nil to unarchive
+	ArchivedAt *string `db:"archived_at"`
+	UpdatedAt  string  `db:"updated_at"`
+	ID         string  `db:"id"`
+}
+
+func (q *Queries) SetCategoryArchivedAt(ctx context.Context, arg SetCategoryArchivedAtParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, setCategoryArchivedAt, arg)
+}
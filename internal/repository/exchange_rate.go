@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+)
+
+const upsertExchangeRate = `
+INSERT INTO exchange_rate (
+    date,
+    base_currency,
+    quote_currency,
+    rate,
+    created_at,
+    updated_at
+) VALUES (
+    :date,
+    :base_currency,
+    :quote_currency,
+    :rate,
+    :created_at,
+    :updated_at
+)
+ON CONFLICT (date, base_currency, quote_currency) DO UPDATE SET
+    rate = excluded.rate,
+    updated_at = excluded.updated_at
+WHERE
+    rate != excluded.rate
+`
+
+type UpsertExchangeRateParams struct {
+	Date          string  `db:"date"`
+	BaseCurrency  string  `db:"base_currency"`
+	QuoteCurrency string  `db:"quote_currency"`
+	Rate          float64 `db:"rate"`
+	CreatedAt     string  `db:"created_at"`
+	UpdatedAt     string  `db:"updated_at"`
+}
+
+// UpsertExchangeRate creates an exchange rate, or updates the rate if it
+// already exists with a different value.
+func (q *Queries) UpsertExchangeRate(ctx context.Context, arg UpsertExchangeRateParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, upsertExchangeRate, arg)
+}
+
+const getExchangeRate = `
+SELECT
+    f.rate AS from_rate,
+    t.rate AS to_rate
+FROM
+    exchange_rate AS f
+JOIN
+    exchange_rate AS t
+ON
+    f.date = t.date AND
+    f.base_currency = t.base_currency
+WHERE
+    f.quote_currency = :from_currency AND
+    t.quote_currency = :to_currency AND
+    f.date <= :date AND
+    f.rate > 0 AND
+    t.rate > 0
+ORDER BY
+    f.date DESC
+LIMIT
+    1
+`
+
+type GetExchangeRateParams struct {
+	FromCurrency string `db:"from_currency"`
+	ToCurrency   string `db:"to_currency"`
+	Date         string `db:"date"`
+}
+
+// ExchangeRateRow are the rates of two currencies from the same base currency
+// on the same date.
+type ExchangeRateRow struct {
+	FromRate float64 `db:"from_rate"`
+	ToRate   float64 `db:"to_rate"`
+}
+
+// GetExchangeRate returns the rates of the from and to currencies, using the
+// latest rates on or before the date. The amount of the to currency for 1 unit
+// of the from currency is ToRate / FromRate.
+//
+// It returns an empty slice if no rate is available.
+func (q *Queries) GetExchangeRate(ctx context.Context, arg GetExchangeRateParams) ([]ExchangeRateRow, error) {
+	items := []ExchangeRateRow{}
+	err := NamedSelectContext(ctx, q.db, &items, getExchangeRate, arg)
+	return items, err
+}
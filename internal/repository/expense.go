@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"iter"
 )
 
 const createExpense = `
@@ -14,7 +16,12 @@ INSERT INTO expense (
     amount,
     remark,
     created_at,
-    updated_at
+    updated_at,
+    type,
+    destination_payment_method_id,
+    original_currency,
+    original_amount,
+    external_id
 ) VALUES (
     :id,
 	:book_id,
@@ -24,46 +31,147 @@ INSERT INTO expense (
 	:amount,
 	:remark,
 	:created_at,
-	:updated_at
+	:updated_at,
+	:type,
+	:destination_payment_method_id,
+	:original_currency,
+	:original_amount,
+	:external_id
 )
 `
 
 type CreateExpenseParams struct {
-	ID              string  `db:"id"`
-	BookID          string  `db:"book_id"`
-	CategoryID      string  `db:"category_id"`
-	PaymentMethodID string  `db:"payment_method_id"`
-	Date            string  `db:"date"`
-	Amount          float64 `db:"amount"`
-	Remark          string  `db:"remark"`
-	CreatedAt       string  `db:"created_at"`
-	UpdatedAt       string  `db:"updated_at"`
+	ID                         string  `db:"id"`
+	BookID                     string  `db:"book_id"`
+	CategoryID                 string  `db:"category_id"`
+	PaymentMethodID            string  `db:"payment_method_id"`
+	Date                       string  `db:"date"`
+	Amount                     int64   `db:"amount"`
+	Remark                     string  `db:"remark"`
+	CreatedAt                  string  `db:"created_at"`
+	UpdatedAt                  string  `db:"updated_at"`
+	Type                       string  `db:"type"`
+	DestinationPaymentMethodID *string `db:"destination_payment_method_id"`
+	OriginalCurrency           *string `db:"original_currency"`
+	OriginalAmount             *int64  `db:"original_amount"`
+	ExternalID                 *string `db:"external_id"`
 }
 
 func (q *Queries) CreateExpense(ctx context.Context, arg CreateExpenseParams) (int64, error) {
 	return NamedExecRowsAffectedContext(ctx, q.db, createExpense, arg)
 }
 
-const getExpenseCountByBookID = `
-SELECT
-    COUNT(*) AS count
-FROM
-    expense
-WHERE
-    book_id = :book_id AND
-    (category_id = :category_id OR :category_id = '') AND
-    (payment_method_id = :payment_method_id OR :payment_method_id = '') AND
-    (INSTR(remark, :remark) > 0 OR :remark = '')
+const createRecurringExpenseOccurrence = `
+INSERT INTO expense (
+    id,
+    book_id,
+    category_id,
+    payment_method_id,
+    date,
+    amount,
+    remark,
+    created_at,
+    updated_at,
+    recurring_expense_id
+) VALUES (
+    :id,
+    :book_id,
+    :category_id,
+    :payment_method_id,
+    :date,
+    :amount,
+    :remark,
+    :created_at,
+    :updated_at,
+    :recurring_expense_id
+)
+ON CONFLICT (recurring_expense_id, date) DO NOTHING
+`
+
+type CreateRecurringExpenseOccurrenceParams struct {
+	ID                 string `db:"id"`
+	BookID             string `db:"book_id"`
+	CategoryID         string `db:"category_id"`
+	PaymentMethodID    string `db:"payment_method_id"`
+	Date               string `db:"date"`
+	Amount             int64  `db:"amount"`
+	Remark             string `db:"remark"`
+	CreatedAt          string `db:"created_at"`
+	UpdatedAt          string `db:"updated_at"`
+	RecurringExpenseID string `db:"recurring_expense_id"`
+}
+
+// CreateRecurringExpenseOccurrence creates the expense of a recurring expense
+// for a date.
+//
+// It does nothing and returns 0 if the occurrence already exists.
+func (q *Queries) CreateRecurringExpenseOccurrence(ctx context.Context, arg CreateRecurringExpenseOccurrenceParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createRecurringExpenseOccurrence, arg)
+}
+
+// tagFilter is the condition on the tags of the expenses, its parameters are
+// the fields of TagFilterParams.
+const tagFilter = `
+    (:tag_ids = '' OR (
+        SELECT
+            COUNT(*)
+        FROM
+            expense_tag
+        WHERE
+            expense_tag.expense_id = expense.id AND
+            expense_tag.tag_id IN (SELECT value FROM json_each(:tag_ids))
+    ) >= CASE WHEN :tag_match_all THEN json_array_length(:tag_ids) ELSE 1 END)
 `
 
-type GetExpenseCountByBookIDParams struct {
-	BookID          string `db:"book_id"`
+type TagFilterParams struct {
+	// TagIDs is a JSON array of distinct tag IDs, or empty to match everything
+	TagIDs string `db:"tag_ids"`
+	// TagMatchAll matches the expenses with all the tags instead of any
+	TagMatchAll bool `db:"tag_match_all"`
+}
+
+// expenseFilter is the condition shared by the queries listing the expenses of
+// a book, its parameters are the fields of ExpenseFilterParams.
+const expenseFilter = `
+    expense.book_id = :book_id AND
+    (:category_id = '' OR expense.category_id IN (` + categoryDescendants + `)) AND
+    (expense.payment_method_id = :payment_method_id OR expense.destination_payment_method_id = :payment_method_id OR :payment_method_id = '') AND
+    (INSTR(expense.remark, :remark) > 0 OR :remark = '') AND
+    (expense.type = :type OR :type = '') AND
+    (expense.date >= :date_from OR :date_from = '') AND
+    (expense.date <= :date_to OR :date_to = '') AND
+    (expense.amount >= :amount_min OR :amount_min IS NULL) AND
+    (expense.amount <= :amount_max OR :amount_max IS NULL) AND
+    (:search = '' OR expense.id IN (SELECT expense_fts_row.expense_id FROM expense_fts JOIN expense_fts_row ON expense_fts_row.id = expense_fts.rowid WHERE expense_fts MATCH NULLIF(:search, ''))) AND
+` + tagFilter
+
+type ExpenseFilterParams struct {
+	BookID string `db:"book_id"`
+	// CategoryID matches the category and all its descendants
 	CategoryID      string `db:"category_id"`
 	PaymentMethodID string `db:"payment_method_id"`
 	Remark          string `db:"remark"`
+	Type            string `db:"type"`
+	// DateFrom and DateTo are inclusive
+	DateFrom string `db:"date_from"`
+	DateTo   string `db:"date_to"`
+	// AmountMin and AmountMax are inclusive, in minor units
+	AmountMin *int64 `db:"amount_min"`
+	AmountMax *int64 `db:"amount_max"`
+	// Search is an FTS5 query matched against the remarks
+	Search string `db:"search"`
+	TagFilterParams
 }
 
-func (q *Queries) GetExpenseCountByBookID(ctx context.Context, arg GetExpenseCountByBookIDParams) (int64, error) {
+const getExpenseCountByBookID = `
+SELECT
+    COUNT(*) AS count
+FROM
+    expense
+WHERE
+` + expenseFilter
+
+func (q *Queries) GetExpenseCountByBookID(ctx context.Context, arg ExpenseFilterParams) (int64, error) {
 	var count int64
 	err := NamedGetContext(ctx, q.db, &count, getExpenseCountByBookID, arg)
 	return count, err
@@ -71,38 +179,204 @@ func (q *Queries) GetExpenseCountByBookID(ctx context.Context, arg GetExpenseCou
 
 const getExpensesByBookID = `
 SELECT
-    *
+    expense.*
 FROM
     expense
+%s
 WHERE
-    book_id = :book_id AND
-    (category_id = :category_id OR :category_id = '') AND
-    (payment_method_id = :payment_method_id OR :payment_method_id = '') AND
-    (INSTR(remark, :remark) > 0 OR :remark = '')
+` + expenseFilter + `
 ORDER BY
-    date DESC,
-    updated_at DESC
+    %s
 LIMIT
     :limit
 OFFSET
     :offset
 `
 
+// expenseOrderBy maps the sort options of the expenses to their ORDER BY
+// clause, the direction being substituted for %[1]s. Every clause ends with the
+// ID, so that the pages are stable for the expenses created together.
+var expenseOrderBy = map[string]string{
+	"date":      "date %[1]s, updated_at %[1]s, id %[1]s",
+	"amount":    "amount %[1]s, date %[1]s, updated_at %[1]s, id %[1]s",
+	"created":   "created_at %[1]s, id %[1]s",
+	"relevance": "-search.rank %[1]s, date DESC, updated_at DESC, id DESC",
+}
+
 type GetExpensesByBookIDParams struct {
-	BookID          string `db:"book_id"`
-	CategoryID      string `db:"category_id"`
-	PaymentMethodID string `db:"payment_method_id"`
-	Remark          string `db:"remark"`
-	Offset          int64  `db:"offset"`
-	Limit           int64  `db:"limit"`
+	ExpenseFilterParams
+	Offset int64 `db:"offset"`
+	Limit  int64 `db:"limit"`
+	// Sort is one of date, amount, created or relevance (to the search), and
+	// defaults to date
+	Sort      string `db:"-"`
+	Ascending bool   `db:"-"`
 }
 
+// searchRankJoin joins the rank of the expenses matching the search, which is
+// only valid if the search is not empty.
+const searchRankJoin = `
+LEFT JOIN
+    (SELECT expense_fts_row.expense_id, expense_fts.rank FROM expense_fts JOIN expense_fts_row ON expense_fts_row.id = expense_fts.rowid WHERE expense_fts MATCH :search) AS search ON search.expense_id = expense.id`
+
 func (q *Queries) GetExpensesByBookID(ctx context.Context, arg GetExpensesByBookIDParams) ([]Expense, error) {
+	join := ""
+	if arg.Search != "" {
+		join = searchRankJoin
+	}
+
+	orderBy, ok := expenseOrderBy[arg.Sort]
+	if !ok || (arg.Sort == "relevance" && arg.Search == "") {
+		orderBy = expenseOrderBy["date"]
+	}
+
+	direction := "DESC"
+	if arg.Ascending {
+		direction = "ASC"
+	}
+
+	query := fmt.Sprintf(getExpensesByBookID, join, fmt.Sprintf(orderBy, direction))
+
 	items := []Expense{}
-	err := NamedSelectContext(ctx, q.db, &items, getExpensesByBookID, arg)
+	err := NamedSelectContext(ctx, q.db, &items, query, arg)
 	return items, err
 }
 
+const getExpensesByBookIDAfterCursor = `
+SELECT
+    *
+FROM
+    expense
+WHERE
+` + expenseFilter + `%[1]s
+ORDER BY
+    date %[2]s,
+    updated_at %[2]s,
+    id %[2]s
+LIMIT
+    :limit
+`
+
+type GetExpensesByBookIDAfterCursorParams struct {
+	ExpenseFilterParams
+	// The cursor is the last expense of the previous page, the first page is
+	// returned if CursorID is empty
+	CursorDate      string `db:"cursor_date"`
+	CursorUpdatedAt string `db:"cursor_updated_at"`
+	CursorID        string `db:"cursor_id"`
+	Limit           int64  `db:"limit"`
+	Ascending       bool   `db:"-"`
+}
+
+// GetExpensesByBookIDAfterCursor returns the filtered expenses of a book
+// ordered by date, last update time and ID, after the cursor in that order.
+//
+// Unlike pages by offset, pages by cursor stay consistent while expenses are
+// created or deleted, and are as fast to get at any depth.
+func (q *Queries) GetExpensesByBookIDAfterCursor(ctx context.Context, arg GetExpensesByBookIDAfterCursorParams) ([]Expense, error) {
+	operator, direction := "<", "DESC"
+	if arg.Ascending {
+		operator, direction = ">", "ASC"
+	}
+
+	// The condition is left out for the first page rather than disabled with
+	// an OR, so that the index is used for the range
+	cursor := ""
+	if arg.CursorID != "" {
+		cursor = " AND\n    (date, updated_at, id) " + operator + " (:cursor_date, :cursor_updated_at, :cursor_id)"
+	}
+
+	query := fmt.Sprintf(getExpensesByBookIDAfterCursor, cursor, direction)
+
+	items := []Expense{}
+	err := NamedSelectContext(ctx, q.db, &items, query, arg)
+	return items, err
+}
+
+const getExpenseExportRowsByBookID = `
+SELECT
+    expense.id,
+    expense.date,
+    expense.type,
+    expense.category_id,
+    category.name AS category_name,
+    expense.payment_method_id,
+    payment_method.name AS payment_method_name,
+    expense.destination_payment_method_id,
+    destination_payment_method.name AS destination_payment_method_name,
+    expense.amount,
+    expense.original_currency,
+    expense.original_amount,
+    expense.remark
+FROM
+    expense
+JOIN
+    category ON category.id = expense.category_id
+JOIN
+    payment_method ON payment_method.id = expense.payment_method_id
+LEFT JOIN
+    payment_method AS destination_payment_method ON destination_payment_method.id = expense.destination_payment_method_id
+WHERE
+` + expenseFilter + `
+ORDER BY
+    expense.date ASC,
+    expense.created_at ASC
+`
+
+type ExpenseExportRow struct {
+	ID                           string  `db:"id"`
+	Date                         string  `db:"date"`
+	Type                         string  `db:"type"`
+	CategoryID                   string  `db:"category_id"`
+	CategoryName                 string  `db:"category_name"`
+	PaymentMethodID              string  `db:"payment_method_id"`
+	PaymentMethodName            string  `db:"payment_method_name"`
+	DestinationPaymentMethodID   *string `db:"destination_payment_method_id"`
+	DestinationPaymentMethodName *string `db:"destination_payment_method_name"`
+	Amount                       int64   `db:"amount"`
+	OriginalCurrency             *string `db:"original_currency"`
+	OriginalAmount               *int64  `db:"original_amount"`
+	Remark                       string  `db:"remark"`
+}
+
+// GetExpenseExportRowsByBookID returns an iterator over all the filtered
+// expenses of a book with the names of their categories and payment methods,
+// the rows are read from the database one by one while iterating.
+//
+// The iteration stops after the first error.
+func (q *Queries) GetExpenseExportRowsByBookID(ctx context.Context, arg ExpenseFilterParams) iter.Seq2[ExpenseExportRow, error] {
+	return func(yield func(ExpenseExportRow, error) bool) {
+		query, args, err := q.db.BindNamed(getExpenseExportRowsByBookID, arg)
+		if err != nil {
+			yield(ExpenseExportRow{}, err)
+			return
+		}
+
+		rows, err := q.db.QueryxContext(ctx, query, args...)
+		if err != nil {
+			yield(ExpenseExportRow{}, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row ExpenseExportRow
+			if err := rows.StructScan(&row); err != nil {
+				yield(ExpenseExportRow{}, err)
+				return
+			}
+
+			if !yield(row, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(ExpenseExportRow{}, err)
+		}
+	}
+}
+
 const getExpenseByID = `
 SELECT
 	*
@@ -122,6 +396,29 @@ func (q *Queries) GetExpenseByID(ctx context.Context, id string) ([]Expense, err
 	return items, err
 }
 
+const getExpenseByExternalID = `
+SELECT
+    *
+FROM
+    expense
+WHERE
+    payment_method_id = :payment_method_id AND
+    external_id = :external_id
+`
+
+type GetExpenseByExternalIDParams struct {
+	PaymentMethodID string `db:"payment_method_id"`
+	ExternalID      string `db:"external_id"`
+}
+
+// GetExpenseByExternalID returns the expense imported from a transaction with
+// the external ID assigned by the bank, which is unique per payment method.
+func (q *Queries) GetExpenseByExternalID(ctx context.Context, arg GetExpenseByExternalIDParams) ([]Expense, error) {
+	items := []Expense{}
+	err := NamedSelectContext(ctx, q.db, &items, getExpenseByExternalID, arg)
+	return items, err
+}
+
 const updateExpenseByID = `
 UPDATE 
     expense
@@ -131,19 +428,27 @@ SET
     date = :date,
     amount = :amount,
     remark = :remark,
+    type = :type,
+    destination_payment_method_id = :destination_payment_method_id,
+    original_currency = :original_currency,
+    original_amount = :original_amount,
     updated_at = :updated_at
 WHERE
     id = :id
 `
 
 type UpdateExpenseByIDParams struct {
-	CategoryID      string  `db:"category_id"`
-	PaymentMethodID string  `db:"payment_method_id"`
-	Date            string  `db:"date"`
-	Amount          float64 `db:"amount"`
-	Remark          string  `db:"remark"`
-	UpdatedAt       string  `db:"updated_at"`
-	ID              string  `db:"id"`
+	CategoryID                 string  `db:"category_id"`
+	PaymentMethodID            string  `db:"payment_method_id"`
+	Date                       string  `db:"date"`
+	Amount                     int64   `db:"amount"`
+	Remark                     string  `db:"remark"`
+	Type                       string  `db:"type"`
+	UpdatedAt                  string  `db:"updated_at"`
+	ID                         string  `db:"id"`
+	DestinationPaymentMethodID *string `db:"destination_payment_method_id"`
+	OriginalCurrency           *string `db:"original_currency"`
+	OriginalAmount             *int64  `db:"original_amount"`
 }
 
 func (q *Queries) UpdateExpenseByID(ctx context.Context, arg UpdateExpenseByIDParams) (int64, error) {
@@ -170,18 +475,19 @@ SELECT
     COUNT(*) > 0 AS can_access
 FROM
     expense AS e
-LEFT JOIN
-    book AS b
+JOIN
+    book_member AS m
 ON
-    e.book_id = b.id
+    e.book_id = m.book_id
 WHERE
     e.id = :id AND
-    b.user_id = :user_id
-`
+    m.user_id = :user_id AND` + memberHasRole
 
 type CheckExpenseAccessParams struct {
 	ExpenseID string `db:"id"`
 	UserID    string `db:"user_id"`
+	// Role is the minimum role of the user in the book
+	Role string `db:"role"`
 }
 
 func (q *Queries) CheckExpenseAccess(ctx context.Context, arg CheckExpenseAccessParams) (bool, error) {
@@ -215,7 +521,8 @@ SELECT
 FROM
     expense
 WHERE
-    payment_method_id = :payment_method_id
+    payment_method_id = :payment_method_id OR
+    destination_payment_method_id = :payment_method_id
 `
 
 type CountExpensesByPaymentMethodIDParams struct {
@@ -4,6 +4,19 @@ import (
 	"database/sql"
 )
 
+type APIToken struct {
+	ID        string `json:"id" db:"id"`
+	UserID    string `json:"userID" db:"user_id"`
+	Name      string `json:"name" db:"name"`
+	TokenHash string `json:"-" db:"token_hash"`
+	Scope     string `json:"scope" db:"scope"`
+	// ExpiresAt is nil for a token that never expires
+	ExpiresAt  *string `json:"expiresAt" db:"expires_at"`
+	LastUsedAt *string `json:"lastUsedAt" db:"last_used_at"`
+	CreatedAt  string  `json:"createdAt" db:"created_at"`
+	UpdatedAt  string  `json:"updatedAt" db:"updated_at"`
+}
+
 type Book struct {
 	ID          string `json:"id" db:"id"`
 	UserID      string `json:"userID" db:"user_id"`
@@ -11,30 +24,89 @@ type Book struct {
 	Description string `json:"description" db:"description"`
 	CreatedAt   string `json:"createdAt" db:"created_at"`
 	UpdatedAt   string `json:"updatedAt" db:"updated_at"`
+	Currency    string `json:"currency" db:"currency"`
+}
+
+type BookInvite struct {
+	ID        string `json:"id" db:"id"`
+	BookID    string `json:"bookID" db:"book_id"`
+	Code      string `json:"code" db:"code"`
+	Role      string `json:"role" db:"role"`
+	CreatedBy string `json:"createdBy" db:"created_by"`
+	ExpiresAt string `json:"expiresAt" db:"expires_at"`
+	CreatedAt string `json:"createdAt" db:"created_at"`
+}
+
+type BookMember struct {
+	BookID    string `json:"bookID" db:"book_id"`
+	UserID    string `json:"userID" db:"user_id"`
+	Role      string `json:"role" db:"role"`
+	CreatedAt string `json:"createdAt" db:"created_at"`
+	UpdatedAt string `json:"updatedAt" db:"updated_at"`
+}
+
+type Budget struct {
+	ID     string `json:"id" db:"id"`
+	BookID string `json:"bookID" db:"book_id"`
+	// CategoryID is nil for a budget of all the expenses of the book
+	CategoryID *string `json:"categoryID" db:"category_id"`
+	Amount     int64   `json:"amount" db:"amount"`
+	Period     string  `json:"period" db:"period"`
+	Rollover   bool    `json:"rollover" db:"rollover"`
+	CreatedAt  string  `json:"createdAt" db:"created_at"`
+	UpdatedAt  string  `json:"updatedAt" db:"updated_at"`
+}
+
+type BudgetAlert struct {
+	ID          string  `json:"id" db:"id"`
+	BudgetID    string  `json:"budgetID" db:"budget_id"`
+	PeriodStart string  `json:"periodStart" db:"period_start"`
+	PeriodEnd   string  `json:"periodEnd" db:"period_end"`
+	Threshold   int64   `json:"threshold" db:"threshold"`
+	Spent       int64   `json:"spent" db:"spent"`
+	Available   int64   `json:"available" db:"available"`
+	CreatedAt   string  `json:"createdAt" db:"created_at"`
+	DeliveredAt *string `json:"deliveredAt" db:"delivered_at"`
+}
+
+type BudgetAlertDelivery struct {
+	ID            string `json:"id" db:"id"`
+	BudgetAlertID string `json:"budgetAlertID" db:"budget_alert_id"`
+	AttemptedAt   string `json:"attemptedAt" db:"attempted_at"`
+	// Error is empty for a successful delivery
+	Error string `json:"error" db:"error"`
 }
 
 type Category struct {
-	ID          string `json:"id" db:"id"`
-	BookID      string `json:"bookID" db:"book_id"`
-	Name        string `json:"name" db:"name"`
-	Description string `json:"description" db:"description"`
-	CreatedAt   string `json:"createdAt" db:"created_at"`
-	UpdatedAt   string `json:"updatedAt" db:"updated_at"`
+	ID          string  `json:"id" db:"id"`
+	BookID      string  `json:"bookID" db:"book_id"`
+	Name        string  `json:"name" db:"name"`
+	Description string  `json:"description" db:"description"`
+	CreatedAt   string  `json:"createdAt" db:"created_at"`
+	UpdatedAt   string  `json:"updatedAt" db:"updated_at"`
+	ParentID    *string `json:"parentID" db:"parent_id"`
+	ArchivedAt  *string `json:"archivedAt" db:"archived_at"`
 }
 
 type Expense struct {
-	ID              string  `json:"id" db:"id"`
-	BookID          string  `json:"bookID" db:"book_id"`
-	CategoryID      string  `json:"categoryID" db:"category_id"`
-	PaymentMethodID string  `json:"paymentMethodID" db:"payment_method_id"`
-	Date            string  `json:"date" db:"date"`
-	Amount          float64 `json:"amount" db:"amount"`
-	Remark          string  `json:"remark" db:"remark"`
-	CreatedAt       string  `json:"createdAt" db:"created_at"`
-	UpdatedAt       string  `json:"updatedAt" db:"updated_at"`
+	ID                         string  `json:"id" db:"id"`
+	BookID                     string  `json:"bookID" db:"book_id"`
+	CategoryID                 string  `json:"categoryID" db:"category_id"`
+	PaymentMethodID            string  `json:"paymentMethodID" db:"payment_method_id"`
+	Date                       string  `json:"date" db:"date"`
+	Amount                     int64   `json:"amount" db:"amount"`
+	Remark                     string  `json:"remark" db:"remark"`
+	CreatedAt                  string  `json:"createdAt" db:"created_at"`
+	UpdatedAt                  string  `json:"updatedAt" db:"updated_at"`
+	RecurringExpenseID         *string `json:"recurringExpenseID" db:"recurring_expense_id"`
+	Type                       string  `json:"type" db:"type"`
+	DestinationPaymentMethodID *string `json:"destinationPaymentMethodID" db:"destination_payment_method_id"`
+	OriginalCurrency           *string `json:"originalCurrency" db:"original_currency"`
+	OriginalAmount             *int64  `json:"originalAmount" db:"original_amount"`
+	ExternalID                 *string `json:"externalID" db:"external_id"`
 }
 
-type PaymentMethod struct {
+type Tag struct {
 	ID          string `json:"id" db:"id"`
 	BookID      string `json:"bookID" db:"book_id"`
 	Name        string `json:"name" db:"name"`
@@ -43,14 +115,71 @@ type PaymentMethod struct {
 	UpdatedAt   string `json:"updatedAt" db:"updated_at"`
 }
 
+type ExpenseTag struct {
+	ExpenseID string `json:"expenseID" db:"expense_id"`
+	TagID     string `json:"tagID" db:"tag_id"`
+}
+
+type PaymentMethod struct {
+	ID          string  `json:"id" db:"id"`
+	BookID      string  `json:"bookID" db:"book_id"`
+	Name        string  `json:"name" db:"name"`
+	Description string  `json:"description" db:"description"`
+	CreatedAt   string  `json:"createdAt" db:"created_at"`
+	UpdatedAt   string  `json:"updatedAt" db:"updated_at"`
+	Kind        string  `json:"kind" db:"kind"`
+	ArchivedAt  *string `json:"archivedAt" db:"archived_at"`
+}
+
+type RecurringExpense struct {
+	ID                string `json:"id" db:"id"`
+	BookID            string `json:"bookID" db:"book_id"`
+	CategoryID        string `json:"categoryID" db:"category_id"`
+	PaymentMethodID   string `json:"paymentMethodID" db:"payment_method_id"`
+	Amount            int64  `json:"amount" db:"amount"`
+	Remark            string `json:"remark" db:"remark"`
+	Rule              string `json:"rule" db:"rule"`
+	StartDate         string `json:"startDate" db:"start_date"`
+	EndDate           string `json:"endDate" db:"end_date"`
+	MaterializedUntil string `json:"materializedUntil" db:"materialized_until"`
+	CreatedAt         string `json:"createdAt" db:"created_at"`
+	UpdatedAt         string `json:"updatedAt" db:"updated_at"`
+}
+
 type Session struct {
 	ID        string         `json:"id" db:"id"`
 	UserID    sql.NullString `json:"userID" db:"user_id"`
-	Token     string         `json:"token" db:"token"`
-	CsrfToken string         `json:"csrfToken" db:"csrf_token"`
-	ExpiresAt string         `json:"expiresAt" db:"expires_at"`
-	CreatedAt string         `json:"createdAt" db:"created_at"`
-	UpdatedAt string         `json:"updatedAt" db:"updated_at"`
+	TokenHash string         `json:"-" db:"token_hash"`
+	// CsrfTokenHash is the hash of the CSRF token, which is derived from the
+	// session token unless the session predates the token hashing
+	CsrfTokenHash string `json:"-" db:"csrf_token_hash"`
+	ExpiresAt     string `json:"expiresAt" db:"expires_at"`
+	CreatedAt     string `json:"createdAt" db:"created_at"`
+	UpdatedAt     string `json:"updatedAt" db:"updated_at"`
+	// PendingUserID is set on a pre-session waiting for the second factor
+	PendingUserID        *string `json:"pendingUserID" db:"pending_user_id"`
+	SecondFactorAttempts int64   `json:"secondFactorAttempts" db:"second_factor_attempts"`
+	UserAgent            *string `json:"userAgent" db:"user_agent"`
+	IP                   *string `json:"ip" db:"ip"`
+	LastSeenAt           string  `json:"lastSeenAt" db:"last_seen_at"`
+}
+
+type SignInFailure struct {
+	ID        string  `json:"id" db:"id"`
+	Username  *string `json:"username" db:"username"`
+	IP        string  `json:"ip" db:"ip"`
+	CreatedAt string  `json:"createdAt" db:"created_at"`
+}
+
+type SignInThrottle struct {
+	Kind         string  `json:"kind" db:"kind"`
+	Value        string  `json:"value" db:"value"`
+	Failures     int64   `json:"failures" db:"failures"`
+	LockedUntil  *string `json:"lockedUntil" db:"locked_until"`
+	LastFailedAt string  `json:"lastFailedAt" db:"last_failed_at"`
+	CreatedAt    string  `json:"createdAt" db:"created_at"`
+	UpdatedAt    string  `json:"updatedAt" db:"updated_at"`
+	Pending      int64   `json:"pending" db:"pending"`
 }
 
 type User struct {
@@ -60,3 +189,21 @@ type User struct {
 	CreatedAt    string `json:"createdAt" db:"created_at"`
 	UpdatedAt    string `json:"updatedAt" db:"updated_at"`
 }
+
+type UserRecoveryCode struct {
+	ID        string  `json:"id" db:"id"`
+	UserID    string  `json:"userID" db:"user_id"`
+	CodeHash  string  `json:"-" db:"code_hash"`
+	UsedAt    *string `json:"usedAt" db:"used_at"`
+	CreatedAt string  `json:"createdAt" db:"created_at"`
+}
+
+type UserTOTP struct {
+	UserID string `json:"userID" db:"user_id"`
+	Secret string `json:"-" db:"secret"`
+	// EnabledAt is nil until the secret is confirmed
+	EnabledAt *string `json:"enabledAt" db:"enabled_at"`
+	LastStep  int64   `json:"-" db:"last_step"`
+	CreatedAt string  `json:"createdAt" db:"created_at"`
+	UpdatedAt string  `json:"updatedAt" db:"updated_at"`
+}
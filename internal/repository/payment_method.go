@@ -11,14 +11,16 @@ INSERT INTO payment_method (
     name,
     description,
     created_at,
-    updated_at
+    updated_at,
+    kind
 ) VALUES (
     :id,
     :book_id,
 	:name,
 	:description,
 	:created_at,
-	:updated_at
+	:updated_at,
+	:kind
 )
 `
 
@@ -29,6 +31,7 @@ type CreatePaymentMethodParams struct {
 	Description string `db:"description"`
 	CreatedAt   string `db:"created_at"`
 	UpdatedAt   string `db:"updated_at"`
+	Kind        string `db:"kind"`
 }
 
 func (q *Queries) CreatePaymentMethod(ctx context.Context, arg CreatePaymentMethodParams) (int64, error) {
@@ -41,18 +44,20 @@ SELECT
 FROM
     payment_method
 WHERE
-    book_id = :book_id
+    book_id = :book_id AND
+    (archived_at IS NULL OR :include_archived)
 ORDER BY
     name ASC
 `
 
 type GetPaymentMethodsByBookIDParams struct {
-	BookID string `db:"book_id"`
+	BookID          string `db:"book_id"`
+	IncludeArchived bool   `db:"include_archived"`
 }
 
-func (q *Queries) GetPaymentMethodsByBookID(ctx context.Context, bookID string) ([]PaymentMethod, error) {
+func (q *Queries) GetPaymentMethodsByBookID(ctx context.Context, arg GetPaymentMethodsByBookIDParams) ([]PaymentMethod, error) {
 	items := []PaymentMethod{}
-	err := NamedSelectContext(ctx, q.db, &items, getPaymentMethodsByBookID, GetPaymentMethodsByBookIDParams{BookID: bookID})
+	err := NamedSelectContext(ctx, q.db, &items, getPaymentMethodsByBookID, arg)
 	return items, err
 }
 
@@ -81,6 +86,7 @@ UPDATE
 SET
     name = :name,
     description = :description,
+    kind = CASE WHEN :kind = '' THEN kind ELSE :kind END,
     updated_at = :updated_at
 WHERE
     id = :id
@@ -89,10 +95,13 @@ WHERE
 type UpdatePaymentMethodByIDParams struct {
 	Name        string `db:"name"`
 	Description string `db:"description"`
+	Kind        string `db:"kind"`
 	UpdatedAt   string `db:"updated_at"`
 	ID          string `db:"id"`
 }
 
+// UpdatePaymentMethodByID updates a payment method, an empty kind keeps the
+// current kind.
 func (q *Queries) UpdatePaymentMethodByID(ctx context.Context, arg UpdatePaymentMethodByIDParams) (int64, error) {
 	return NamedExecRowsAffectedContext(ctx, q.db, updatePaymentMethodByID, arg)
 }
@@ -117,18 +126,19 @@ SELECT
     COUNT(*) > 0 AS can_access
 FROM
     payment_method AS pm
-LEFT JOIN
-    book AS b
+JOIN
+    book_member AS m
 ON
-    pm.book_id = b.id
+    pm.book_id = m.book_id
 WHERE
     pm.id = :id AND
-    b.user_id = :user_id
-`
+    m.user_id = :user_id AND` + memberHasRole
 
 type CheckPaymentMethodAccessParams struct {
 	PaymentMethodID string `db:"id"`
 	UserID          string `db:"user_id"`
+	// Role is the minimum role of the user in the book
+	Role string `db:"role"`
 }
 
 func (q *Queries) CheckPaymentMethodAccess(ctx context.Context, arg CheckPaymentMethodAccessParams) (bool, error) {
@@ -136,3 +146,138 @@ func (q *Queries) CheckPaymentMethodAccess(ctx context.Context, arg CheckPayment
 	err := NamedGetContext(ctx, q.db, &canAccess, checkPaymentMethodAccess, arg)
 	return canAccess, err
 }
+
+const countPaymentMethodUsage = `
+SELECT
+    (SELECT COUNT(*) FROM expense WHERE payment_method_id = :id OR destination_payment_method_id = :id) +
+    (SELECT COUNT(*) FROM recurring_expense WHERE payment_method_id = :id) AS count
+`
+
+type CountPaymentMethodUsageParams struct {
+	ID string `db:"id"`
+}
+
+// CountPaymentMethodUsage returns the number of expenses, including the
+// transfers to it, and recurring expenses of a payment method, which would be
+// deleted with it.
+func (q *Queries) CountPaymentMethodUsage(ctx context.Context, id string) (int64, error) {
+	var count int64
+	err := NamedGetContext(ctx, q.db, &count, countPaymentMethodUsage, CountPaymentMethodUsageParams{ID: id})
+	return count, err
+}
+
+const countTransfersBetweenPaymentMethods = `
+SELECT
+    COUNT(*) AS count
+FROM
+    expense
+WHERE
+    (payment_method_id = :id AND destination_payment_method_id = :other_id) OR
+    (payment_method_id = :other_id AND destination_payment_method_id = :id)
+`
+
+type CountTransfersBetweenPaymentMethodsParams struct {
+	ID      string `db:"id"`
+	OtherID string `db:"other_id"`
+}
+
+// CountTransfersBetweenPaymentMethods returns the number of transfers between
+// two payment methods, in either direction.
+func (q *Queries) CountTransfersBetweenPaymentMethods(ctx context.Context, arg CountTransfersBetweenPaymentMethodsParams) (int64, error) {
+	var count int64
+	err := NamedGetContext(ctx, q.db, &count, countTransfersBetweenPaymentMethods, arg)
+	return count, err
+}
+
+const countSharedExpenseExternalIDs = `
+SELECT
+    COUNT(*) AS count
+FROM
+    expense
+JOIN
+    expense AS other_expense ON other_expense.external_id = expense.external_id
+WHERE
+    expense.payment_method_id = :id AND
+    other_expense.payment_method_id = :other_id
+`
+
+type CountSharedExpenseExternalIDsParams struct {
+	ID      string `db:"id"`
+	OtherID string `db:"other_id"`
+}
+
+// CountSharedExpenseExternalIDs returns the number of external IDs of the
+// expenses of a payment method that are also used by the expenses of another
+// payment method.
+func (q *Queries) CountSharedExpenseExternalIDs(ctx context.Context, arg CountSharedExpenseExternalIDsParams) (int64, error) {
+	var count int64
+	err := NamedGetContext(ctx, q.db, &count, countSharedExpenseExternalIDs, arg)
+	return count, err
+}
+
+const reassignExpensesPaymentMethod = `
+UPDATE
+    expense
+SET
+    payment_method_id = CASE WHEN payment_method_id = :from_id THEN :to_id ELSE payment_method_id END,
+    destination_payment_method_id = CASE WHEN destination_payment_method_id = :from_id THEN :to_id ELSE destination_payment_method_id END,
+    updated_at = :updated_at
+WHERE
+    payment_method_id = :from_id OR
+    destination_payment_method_id = :from_id
+`
+
+type ReassignExpensesPaymentMethodParams struct {
+	FromID    string `db:"from_id"`
+	ToID      string `db:"to_id"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+// ReassignExpensesPaymentMethod moves all the expenses of a payment method to
+// another, both as the payment method and as the destination of transfers.
+func (q *Queries) ReassignExpensesPaymentMethod(ctx context.Context, arg ReassignExpensesPaymentMethodParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, reassignExpensesPaymentMethod, arg)
+}
+
+const reassignRecurringExpensesPaymentMethod = `
+UPDATE
+    recurring_expense
+SET
+    payment_method_id = :to_id,
+    updated_at = :updated_at
+WHERE
+    payment_method_id = :from_id
+`
+
+type ReassignRecurringExpensesPaymentMethodParams struct {
+	FromID    string `db:"from_id"`
+	ToID      string `db:"to_id"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+// ReassignRecurringExpensesPaymentMethod moves all the recurring expenses of a
+// payment method to another.
+func (q *Queries) ReassignRecurringExpensesPaymentMethod(ctx context.Context, arg ReassignRecurringExpensesPaymentMethodParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, reassignRecurringExpensesPaymentMethod, arg)
+}
+
+const setPaymentMethodArchivedAt = `
+UPDATE
+    payment_method
+SET
+    archived_at = :archived_at,
+    updated_at = :updated_at
+WHERE
+    id = :id
+`
+
+type SetPaymentMethodArchivedAtParams struct {
+	// ArchivedAt is nil to unarchive
+	ArchivedAt *string `db:"archived_at"`
+	UpdatedAt  string  `db:"updated_at"`
+	ID         string  `db:"id"`
+}
+
+func (q *Queries) SetPaymentMethodArchivedAt(ctx context.Context, arg SetPaymentMethodArchivedAtParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, setPaymentMethodArchivedAt, arg)
+}
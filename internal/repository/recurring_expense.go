@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+)
+
+const createRecurringExpense = `
+INSERT INTO recurring_expense (
+    id,
+    book_id,
+    category_id,
+    payment_method_id,
+    amount,
+    remark,
+    rule,
+    start_date,
+    end_date,
+    materialized_until,
+    created_at,
+    updated_at
+) VALUES (
+    :id,
+    :book_id,
+    :category_id,
+    :payment_method_id,
+    :amount,
+    :remark,
+    :rule,
+    :start_date,
+    :end_date,
+    '',
+    :created_at,
+    :updated_at
+)
+`
+
+type CreateRecurringExpenseParams struct {
+	ID              string `db:"id"`
+	BookID          string `db:"book_id"`
+	CategoryID      string `db:"category_id"`
+	PaymentMethodID string `db:"payment_method_id"`
+	Amount          int64  `db:"amount"`
+	Remark          string `db:"remark"`
+	Rule            string `db:"rule"`
+	StartDate       string `db:"start_date"`
+	EndDate         string `db:"end_date"`
+	CreatedAt       string `db:"created_at"`
+	UpdatedAt       string `db:"updated_at"`
+}
+
+func (q *Queries) CreateRecurringExpense(ctx context.Context, arg CreateRecurringExpenseParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createRecurringExpense, arg)
+}
+
+const getRecurringExpensesByBookID = `
+SELECT
+    *
+FROM
+    recurring_expense
+WHERE
+    book_id = :book_id
+ORDER BY
+    start_date DESC,
+    created_at DESC
+`
+
+type GetRecurringExpensesByBookIDParams struct {
+	BookID string `db:"book_id"`
+}
+
+func (q *Queries) GetRecurringExpensesByBookID(ctx context.Context, bookID string) ([]RecurringExpense, error) {
+	items := []RecurringExpense{}
+	err := NamedSelectContext(ctx, q.db, &items, getRecurringExpensesByBookID, GetRecurringExpensesByBookIDParams{BookID: bookID})
+	return items, err
+}
+
+const getRecurringExpenseByID = `
+SELECT
+    *
+FROM
+    recurring_expense
+WHERE
+    id = :id
+`
+
+type GetRecurringExpenseByIDParams struct {
+	ID string `db:"id"`
+}
+
+func (q *Queries) GetRecurringExpenseByID(ctx context.Context, id string) ([]RecurringExpense, error) {
+	items := []RecurringExpense{}
+	err := NamedSelectContext(ctx, q.db, &items, getRecurringExpenseByID, GetRecurringExpenseByIDParams{ID: id})
+	return items, err
+}
+
+const getDueRecurringExpenses = `
+SELECT
+    *
+FROM
+    recurring_expense
+WHERE
+    start_date <= :today AND
+    materialized_until < :today AND
+    (end_date = '' OR materialized_until < end_date)
+ORDER BY
+    id ASC
+`
+
+type GetDueRecurringExpensesParams struct {
+	Today string `db:"today"`
+}
+
+// GetDueRecurringExpenses returns the recurring expenses that may have
+// occurrences not yet materialized up to and including today.
+func (q *Queries) GetDueRecurringExpenses(ctx context.Context, today string) ([]RecurringExpense, error) {
+	items := []RecurringExpense{}
+	err := NamedSelectContext(ctx, q.db, &items, getDueRecurringExpenses, GetDueRecurringExpensesParams{Today: today})
+	return items, err
+}
+
+const updateRecurringExpenseByID = `
+UPDATE
+    recurring_expense
+SET
+    category_id = :category_id,
+    payment_method_id = :payment_method_id,
+    amount = :amount,
+    remark = :remark,
+    rule = :rule,
+    start_date = :start_date,
+    end_date = :end_date,
+    updated_at = :updated_at
+WHERE
+    id = :id
+`
+
+type UpdateRecurringExpenseByIDParams struct {
+	CategoryID      string `db:"category_id"`
+	PaymentMethodID string `db:"payment_method_id"`
+	Amount          int64  `db:"amount"`
+	Remark          string `db:"remark"`
+	Rule            string `db:"rule"`
+	StartDate       string `db:"start_date"`
+	EndDate         string `db:"end_date"`
+	UpdatedAt       string `db:"updated_at"`
+	ID              string `db:"id"`
+}
+
+// UpdateRecurringExpenseByID updates a recurring expense.
+//
+// The materialization progress is kept if the schedule changes, so that only
+// the occurrences after it follow the new schedule instead of the past ones
+// being materialized again.
+func (q *Queries) UpdateRecurringExpenseByID(ctx context.Context, arg UpdateRecurringExpenseByIDParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateRecurringExpenseByID, arg)
+}
+
+const updateRecurringExpenseMaterializedUntil = `
+UPDATE
+    recurring_expense
+SET
+    materialized_until = :materialized_until
+WHERE
+    id = :id
+`
+
+type UpdateRecurringExpenseMaterializedUntilParams struct {
+	MaterializedUntil string `db:"materialized_until"`
+	ID                string `db:"id"`
+}
+
+func (q *Queries) UpdateRecurringExpenseMaterializedUntil(ctx context.Context, arg UpdateRecurringExpenseMaterializedUntilParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateRecurringExpenseMaterializedUntil, arg)
+}
+
+const deleteRecurringExpenseByID = `
+DELETE FROM
+    recurring_expense
+WHERE
+    id = :id
+`
+
+type DeleteRecurringExpenseByIDParams struct {
+	ID string `db:"id"`
+}
+
+func (q *Queries) DeleteRecurringExpenseByID(ctx context.Context, id string) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteRecurringExpenseByID, DeleteRecurringExpenseByIDParams{ID: id})
+}
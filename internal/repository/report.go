@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// The columns of the expense summary for each dimension it can be grouped by,
+// the columns of the dimensions not grouped by are NULL.
+const (
+	summaryMonthColumns = `
+    SUBSTR(expense.date, 1, 7) AS month,`
+	summaryNoMonthColumns = `
+    NULL AS month,`
+	summaryCategoryColumns = `
+    category.id AS category_id,
+    category.name AS category_name,
+    category.parent_id AS category_parent_id,`
+	summaryNoCategoryColumns = `
+    NULL AS category_id,
+    NULL AS category_name,
+    NULL AS category_parent_id,`
+	summaryPaymentMethodColumns = `
+    expense.payment_method_id,
+    payment_method.name AS payment_method_name,`
+	summaryNoPaymentMethodColumns = `
+    NULL AS payment_method_id,
+    NULL AS payment_method_name,`
+)
+
+// The expenses are joined to all the ancestors of their category when grouped
+// by category, so that the totals of the categories include their descendants.
+const (
+	summaryCategoryJoin = `
+JOIN
+    category_ancestor ON category_ancestor.category_id = expense.category_id
+JOIN
+    category ON category.id = category_ancestor.ancestor_id`
+	summaryNoCategoryJoin = `
+JOIN
+    category ON category.id = expense.category_id`
+)
+
+const getExpenseSummaryByBookIDFrom = `
+FROM
+    expense%s
+JOIN
+    payment_method ON payment_method.id = expense.payment_method_id
+WHERE
+    expense.book_id = :book_id AND
+    (expense.date >= :from OR :from = '') AND
+    (expense.date <= :to OR :to = '') AND
+` + tagFilter
+
+type GetExpenseSummaryByBookIDParams struct {
+	BookID string `db:"book_id"`
+	// From and To are the inclusive date range, either can be empty
+	From string `db:"from"`
+	To   string `db:"to"`
+	TagFilterParams
+
+	ByMonth         bool `db:"-"`
+	ByCategory      bool `db:"-"`
+	ByPaymentMethod bool `db:"-"`
+}
+
+type ExpenseSummaryRow struct {
+	Type              string  `db:"type"`
+	Month             *string `db:"month"`
+	CategoryID        *string `db:"category_id"`
+	CategoryName      *string `db:"category_name"`
+	CategoryParentID  *string `db:"category_parent_id"`
+	PaymentMethodID   *string `db:"payment_method_id"`
+	PaymentMethodName *string `db:"payment_method_name"`
+	Total             int64   `db:"total"`
+	Count             int64   `db:"count"`
+}
+
+// GetExpenseSummaryByBookID returns the total amount and the number of the
+// expenses of a book in a date range and with the tags, grouped by type and by
+// any combination of month, category and payment method.
+//
+// The total of a category includes the expenses of its descendants, so the
+// totals of the categories overlap when there are subcategories. Transfers are
+// grouped by their source payment method.
+func (q *Queries) GetExpenseSummaryByBookID(ctx context.Context, arg GetExpenseSummaryByBookIDParams) ([]ExpenseSummaryRow, error) {
+	with := ""
+	join := summaryNoCategoryJoin
+	columns := summaryNoMonthColumns
+	groupBy := []string{}
+
+	if arg.ByMonth {
+		columns = summaryMonthColumns
+		groupBy = append(groupBy, "month")
+	}
+
+	if arg.ByCategory {
+		with, join = categoryAncestors, summaryCategoryJoin
+		columns += summaryCategoryColumns
+		groupBy = append(groupBy, "category.id")
+	} else {
+		columns += summaryNoCategoryColumns
+	}
+
+	if arg.ByPaymentMethod {
+		columns += summaryPaymentMethodColumns
+		groupBy = append(groupBy, "expense.payment_method_id")
+	} else {
+		columns += summaryNoPaymentMethodColumns
+	}
+
+	groupBy = append(groupBy, "expense.type")
+
+	query := with + `
+SELECT
+    expense.type,` + columns + `
+    SUM(expense.amount) AS total,
+    COUNT(*) AS count
+` + fmt.Sprintf(getExpenseSummaryByBookIDFrom, join) + `
+GROUP BY
+    ` + strings.Join(groupBy, ",\n    ") + `
+ORDER BY
+    ` + strings.Join(groupBy, ",\n    ") + `
+`
+
+	items := []ExpenseSummaryRow{}
+	err := NamedSelectContext(ctx, q.db, &items, query, arg)
+	return items, err
+}
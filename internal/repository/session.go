@@ -9,30 +9,38 @@ const createSession = `
 INSERT INTO session (
     id,
     user_id,
-    token,
-    csrf_token,
+    token_hash,
+    csrf_token_hash,
+    user_agent,
+    ip,
     expires_at,
+    last_seen_at,
     created_at,
     updated_at
 ) VALUES (
 	:id,
 	:user_id,
-	:token,
-	:csrf_token,
+	:token_hash,
+	:csrf_token_hash,
+	:user_agent,
+	:ip,
 	:expires_at,
 	:created_at,
+	:created_at,
 	:updated_at
 )
 `
 
 type CreateSessionParams struct {
-	ID        string         `db:"id"`
-	UserID    sql.NullString `db:"user_id"`
-	Token     string         `db:"token"`
-	CsrfToken string         `db:"csrf_token"`
-	ExpiresAt string         `db:"expires_at"`
-	CreatedAt string         `db:"created_at"`
-	UpdatedAt string         `db:"updated_at"`
+	ID            string         `db:"id"`
+	UserID        sql.NullString `db:"user_id"`
+	TokenHash     string         `db:"token_hash"`
+	CsrfTokenHash string         `db:"csrf_token_hash"`
+	UserAgent     *string        `db:"user_agent"`
+	IP            *string        `db:"ip"`
+	ExpiresAt     string         `db:"expires_at"`
+	CreatedAt     string         `db:"created_at"`
+	UpdatedAt     string         `db:"updated_at"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (int64, error) {
@@ -45,16 +53,39 @@ SELECT
 FROM
     session
 WHERE
-    token = :token
+    token_hash = :token_hash
 `
 
 type GetSessionByTokenParams struct {
-	Token string `db:"token"`
+	TokenHash string `db:"token_hash"`
+}
+
+func (q *Queries) GetSessionByToken(ctx context.Context, tokenHash string) ([]Session, error) {
+	items := []Session{}
+	err := NamedSelectContext(ctx, q.db, &items, getSessionByToken, GetSessionByTokenParams{TokenHash: tokenHash})
+	return items, err
+}
+
+const getActiveSessionsByUserID = `
+SELECT
+    *
+FROM
+    session
+WHERE
+    user_id = :user_id AND
+    expires_at > :now
+ORDER BY
+    last_seen_at DESC
+`
+
+type GetActiveSessionsByUserIDParams struct {
+	UserID sql.NullString `db:"user_id"`
+	Now    string         `db:"now"`
 }
 
-func (q *Queries) GetSessionByToken(ctx context.Context, token string) ([]Session, error) {
+func (q *Queries) GetActiveSessionsByUserID(ctx context.Context, arg GetActiveSessionsByUserIDParams) ([]Session, error) {
 	items := []Session{}
-	err := NamedSelectContext(ctx, q.db, &items, getSessionByToken, GetSessionByTokenParams{Token: token})
+	err := NamedSelectContext(ctx, q.db, &items, getActiveSessionsByUserID, arg)
 	return items, err
 }
 
@@ -65,19 +96,108 @@ SET
     expires_at = :expires_at,
     updated_at = :updated_at
 WHERE
-    token = :token
+    token_hash = :token_hash
 `
 
 type UpdateSessionByTokenParams struct {
 	ExpiresAt string `db:"expires_at"`
 	UpdatedAt string `db:"updated_at"`
-	Token     string `db:"token"`
+	TokenHash string `db:"token_hash"`
 }
 
 func (q *Queries) UpdateSessionByToken(ctx context.Context, arg UpdateSessionByTokenParams) (int64, error) {
 	return NamedExecRowsAffectedContext(ctx, q.db, updateSessionByToken, arg)
 }
 
+const updateSessionLastSeenAt = `
+UPDATE
+    session
+SET
+    user_agent = :user_agent,
+    ip = :ip,
+    last_seen_at = :last_seen_at
+WHERE
+    id = :id
+`
+
+type UpdateSessionLastSeenAtParams struct {
+	UserAgent  *string `db:"user_agent"`
+	IP         *string `db:"ip"`
+	LastSeenAt string  `db:"last_seen_at"`
+	ID         string  `db:"id"`
+}
+
+func (q *Queries) UpdateSessionLastSeenAt(ctx context.Context, arg UpdateSessionLastSeenAtParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateSessionLastSeenAt, arg)
+}
+
+// updateSessionByIDAndUserID only updates the active sessions, so that an
+// expired session is not found
+const updateSessionByIDAndUserID = `
+UPDATE
+    session
+SET
+    expires_at = :expires_at,
+    updated_at = :updated_at
+WHERE
+    id = :id AND
+    user_id = :user_id AND
+    expires_at > :expires_at
+`
+
+type UpdateSessionByIDAndUserIDParams struct {
+	ExpiresAt string         `db:"expires_at"`
+	UpdatedAt string         `db:"updated_at"`
+	ID        string         `db:"id"`
+	UserID    sql.NullString `db:"user_id"`
+}
+
+func (q *Queries) UpdateSessionByIDAndUserID(ctx context.Context, arg UpdateSessionByIDAndUserIDParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateSessionByIDAndUserID, arg)
+}
+
+const updateSessionPendingUserID = `
+UPDATE
+    session
+SET
+    pending_user_id = :pending_user_id,
+    updated_at = :updated_at
+WHERE
+    token_hash = :token_hash
+`
+
+type UpdateSessionPendingUserIDParams struct {
+	PendingUserID string `db:"pending_user_id"`
+	UpdatedAt     string `db:"updated_at"`
+	TokenHash     string `db:"token_hash"`
+}
+
+// UpdateSessionPendingUserID binds a pre-session to the user waiting for the
+// second factor. The second factor attempts are kept, so that signing in again
+// on the same pre-session does not allow more attempts.
+func (q *Queries) UpdateSessionPendingUserID(ctx context.Context, arg UpdateSessionPendingUserIDParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateSessionPendingUserID, arg)
+}
+
+const incrementSessionSecondFactorAttempts = `
+UPDATE
+    session
+SET
+    second_factor_attempts = second_factor_attempts + 1,
+    updated_at = :updated_at
+WHERE
+    token_hash = :token_hash
+`
+
+type IncrementSessionSecondFactorAttemptsParams struct {
+	UpdatedAt string `db:"updated_at"`
+	TokenHash string `db:"token_hash"`
+}
+
+func (q *Queries) IncrementSessionSecondFactorAttempts(ctx context.Context, arg IncrementSessionSecondFactorAttemptsParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, incrementSessionSecondFactorAttempts, arg)
+}
+
 const updateSessionByUserID = `
 UPDATE
     session
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+)
+
+const createSignInFailure = `
+INSERT INTO sign_in_failure (
+    id,
+    username,
+    ip,
+    created_at
+) VALUES (
+    :id,
+    :username,
+    :ip,
+    :created_at
+)
+`
+
+type CreateSignInFailureParams struct {
+	ID        string  `db:"id"`
+	Username  *string `db:"username"`
+	IP        string  `db:"ip"`
+	CreatedAt string  `db:"created_at"`
+}
+
+func (q *Queries) CreateSignInFailure(ctx context.Context, arg CreateSignInFailureParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createSignInFailure, arg)
+}
+
+const deleteSignInFailureByCreatedAt = `
+DELETE FROM
+    sign_in_failure
+WHERE
+    created_at < :created_at
+`
+
+type DeleteSignInFailureByCreatedAtParams struct {
+	CreatedAt string `db:"created_at"`
+}
+
+func (q *Queries) DeleteSignInFailureByCreatedAt(ctx context.Context, createdAt string) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteSignInFailureByCreatedAt, DeleteSignInFailureByCreatedAtParams{CreatedAt: createdAt})
+}
+
+const getSignInThrottle = `
+SELECT
+    *
+FROM
+    sign_in_throttle
+WHERE
+    kind = :kind AND
+    value = :value
+`
+
+type GetSignInThrottleParams struct {
+	Kind  string `db:"kind"`
+	Value string `db:"value"`
+}
+
+func (q *Queries) GetSignInThrottle(ctx context.Context, arg GetSignInThrottleParams) ([]SignInThrottle, error) {
+	items := []SignInThrottle{}
+	err := NamedSelectContext(ctx, q.db, &items, getSignInThrottle, arg)
+	return items, err
+}
+
+// upsertSignInThrottleFailure counts a failure, starting over if the last
+// failure and lockout are both before the window
+const upsertSignInThrottleFailure = `
+INSERT INTO sign_in_throttle (
+    kind,
+    value,
+    failures,
+    last_failed_at,
+    created_at,
+    updated_at
+) VALUES (
+    :kind,
+    :value,
+    1,
+    :last_failed_at,
+    :last_failed_at,
+    :last_failed_at
+)
+ON CONFLICT (kind, value) DO UPDATE SET
+    failures = CASE
+        WHEN MAX(last_failed_at, COALESCE(locked_until, '')) < :window_start THEN 1
+        ELSE failures + 1
+    END,
+    last_failed_at = excluded.last_failed_at,
+    updated_at = excluded.updated_at
+`
+
+type UpsertSignInThrottleFailureParams struct {
+	Kind         string `db:"kind"`
+	Value        string `db:"value"`
+	LastFailedAt string `db:"last_failed_at"`
+	WindowStart  string `db:"window_start"`
+}
+
+func (q *Queries) UpsertSignInThrottleFailure(ctx context.Context, arg UpsertSignInThrottleFailureParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, upsertSignInThrottleFailure, arg)
+}
+
+// reserveSignInThrottleAttempt counts an attempt in progress unless the
+// throttle is locked, or its attempts in progress would exceed the failures
+// left before the lockout, at least one attempt being allowed once a lockout
+// ends. The attempts in progress of a throttle without any recent update are
+// left over from an interrupted request, and are not counted
+const reserveSignInThrottleAttempt = `
+INSERT INTO sign_in_throttle (
+    kind,
+    value,
+    failures,
+    pending,
+    last_failed_at,
+    created_at,
+    updated_at
+) VALUES (
+    :kind,
+    :value,
+    0,
+    1,
+    '',
+    :now,
+    :now
+)
+ON CONFLICT (kind, value) DO UPDATE SET
+    pending = CASE WHEN updated_at < :stale_before THEN 0 ELSE pending END + 1,
+    updated_at = excluded.updated_at
+WHERE
+    COALESCE(locked_until, '') <= :now AND
+    CASE WHEN updated_at < :stale_before THEN 0 ELSE pending END < MAX(1, :threshold - CASE
+        WHEN MAX(last_failed_at, COALESCE(locked_until, '')) < :window_start THEN 0
+        ELSE failures
+    END)
+`
+
+type ReserveSignInThrottleAttemptParams struct {
+	Kind        string `db:"kind"`
+	Value       string `db:"value"`
+	Now         string `db:"now"`
+	StaleBefore string `db:"stale_before"`
+	WindowStart string `db:"window_start"`
+	Threshold   int64  `db:"threshold"`
+}
+
+// ReserveSignInThrottleAttempt returns 1 if the attempt is reserved, and 0 if
+// the throttle does not allow it.
+func (q *Queries) ReserveSignInThrottleAttempt(ctx context.Context, arg ReserveSignInThrottleAttemptParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, reserveSignInThrottleAttempt, arg)
+}
+
+const releaseSignInThrottleAttempt = `
+UPDATE
+    sign_in_throttle
+SET
+    pending = MAX(pending - 1, 0)
+WHERE
+    kind = :kind AND
+    value = :value
+`
+
+type ReleaseSignInThrottleAttemptParams struct {
+	Kind  string `db:"kind"`
+	Value string `db:"value"`
+}
+
+func (q *Queries) ReleaseSignInThrottleAttempt(ctx context.Context, arg ReleaseSignInThrottleAttemptParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, releaseSignInThrottleAttempt, arg)
+}
+
+const updateSignInThrottleLockedUntil = `
+UPDATE
+    sign_in_throttle
+SET
+    locked_until = :locked_until,
+    updated_at = :updated_at
+WHERE
+    kind = :kind AND
+    value = :value
+`
+
+type UpdateSignInThrottleLockedUntilParams struct {
+	LockedUntil string `db:"locked_until"`
+	UpdatedAt   string `db:"updated_at"`
+	Kind        string `db:"kind"`
+	Value       string `db:"value"`
+}
+
+func (q *Queries) UpdateSignInThrottleLockedUntil(ctx context.Context, arg UpdateSignInThrottleLockedUntilParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateSignInThrottleLockedUntil, arg)
+}
+
+const deleteSignInThrottle = `
+DELETE FROM
+    sign_in_throttle
+WHERE
+    kind = :kind AND
+    value = :value
+`
+
+type DeleteSignInThrottleParams struct {
+	Kind  string `db:"kind"`
+	Value string `db:"value"`
+}
+
+func (q *Queries) DeleteSignInThrottle(ctx context.Context, arg DeleteSignInThrottleParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteSignInThrottle, arg)
+}
+
+// deleteSignInThrottleByWindowStart deletes the throttles which would start
+// over on the next failure
+const deleteSignInThrottleByWindowStart = `
+DELETE FROM
+    sign_in_throttle
+WHERE
+    MAX(last_failed_at, COALESCE(locked_until, '')) < :window_start
+`
+
+type DeleteSignInThrottleByWindowStartParams struct {
+	WindowStart string `db:"window_start"`
+}
+
+func (q *Queries) DeleteSignInThrottleByWindowStart(ctx context.Context, windowStart string) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteSignInThrottleByWindowStart, DeleteSignInThrottleByWindowStartParams{WindowStart: windowStart})
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+)
+
+const createTag = `
+INSERT INTO tag (
+    id,
+    book_id,
+    name,
+    description,
+    created_at,
+    updated_at
+) VALUES (
+    :id,
+    :book_id,
+    :name,
+    :description,
+    :created_at,
+    :updated_at
+)
+`
+
+type CreateTagParams struct {
+	ID          string `db:"id"`
+	BookID      string `db:"book_id"`
+	Name        string `db:"name"`
+	Description string `db:"description"`
+	CreatedAt   string `db:"created_at"`
+	UpdatedAt   string `db:"updated_at"`
+}
+
+func (q *Queries) CreateTag(ctx context.Context, arg CreateTagParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createTag, arg)
+}
+
+const getTagsByBookID = `
+SELECT
+    *
+FROM
+    tag
+WHERE
+    book_id = :book_id
+ORDER BY
+    name ASC
+`
+
+type GetTagsByBookIDParams struct {
+	BookID string `db:"book_id"`
+}
+
+func (q *Queries) GetTagsByBookID(ctx context.Context, bookID string) ([]Tag, error) {
+	items := []Tag{}
+	err := NamedSelectContext(ctx, q.db, &items, getTagsByBookID, GetTagsByBookIDParams{BookID: bookID})
+	return items, err
+}
+
+const getTagByID = `
+SELECT
+    *
+FROM
+    tag
+WHERE
+    id = :id
+`
+
+type GetTagByIDParams struct {
+	ID string `db:"id"`
+}
+
+func (q *Queries) GetTagByID(ctx context.Context, id string) ([]Tag, error) {
+	items := []Tag{}
+	err := NamedSelectContext(ctx, q.db, &items, getTagByID, GetTagByIDParams{ID: id})
+	return items, err
+}
+
+const getTagByName = `
+SELECT
+    *
+FROM
+    tag
+WHERE
+    book_id = :book_id AND
+    name = :name
+`
+
+type GetTagByNameParams struct {
+	BookID string `db:"book_id"`
+	Name   string `db:"name"`
+}
+
+// GetTagByName returns the tag of a book with the name, which is unique per
+// book.
+func (q *Queries) GetTagByName(ctx context.Context, arg GetTagByNameParams) ([]Tag, error) {
+	items := []Tag{}
+	err := NamedSelectContext(ctx, q.db, &items, getTagByName, arg)
+	return items, err
+}
+
+const countTagsByBookIDAndIDs = `
+SELECT
+    COUNT(*) AS count
+FROM
+    tag
+WHERE
+    book_id = :book_id AND
+    id IN (SELECT value FROM json_each(:ids))
+`
+
+type CountTagsByBookIDAndIDsParams struct {
+	BookID string `db:"book_id"`
+	// IDs is a JSON array of tag IDs
+	IDs string `db:"ids"`
+}
+
+// CountTagsByBookIDAndIDs returns the number of tags of a book among the IDs.
+func (q *Queries) CountTagsByBookIDAndIDs(ctx context.Context, arg CountTagsByBookIDAndIDsParams) (int64, error) {
+	var count int64
+	err := NamedGetContext(ctx, q.db, &count, countTagsByBookIDAndIDs, arg)
+	return count, err
+}
+
+const updateTagByID = `
+UPDATE
+    tag
+SET
+    name = :name,
+    description = :description,
+    updated_at = :updated_at
+WHERE
+    id = :id
+`
+
+type UpdateTagByIDParams struct {
+	Name        string `db:"name"`
+	Description string `db:"description"`
+	UpdatedAt   string `db:"updated_at"`
+	ID          string `db:"id"`
+}
+
+func (q *Queries) UpdateTagByID(ctx context.Context, arg UpdateTagByIDParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateTagByID, arg)
+}
+
+const deleteTagByID = `
+DELETE FROM
+    tag
+WHERE
+    id = :id
+`
+
+type DeleteTagByIDParams struct {
+	ID string `db:"id"`
+}
+
+func (q *Queries) DeleteTagByID(ctx context.Context, id string) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteTagByID, DeleteTagByIDParams{ID: id})
+}
+
+const checkTagAccess = `
+SELECT
+    COUNT(*) > 0 AS can_access
+FROM
+    tag AS t
+JOIN
+    book_member AS m
+ON
+    t.book_id = m.book_id
+WHERE
+    t.id = :id AND
+    m.user_id = :user_id AND` + memberHasRole
+
+type CheckTagAccessParams struct {
+	TagID  string `db:"id"`
+	UserID string `db:"user_id"`
+	// Role is the minimum role of the user in the book
+	Role string `db:"role"`
+}
+
+func (q *Queries) CheckTagAccess(ctx context.Context, arg CheckTagAccessParams) (bool, error) {
+	var canAccess bool
+	err := NamedGetContext(ctx, q.db, &canAccess, checkTagAccess, arg)
+	return canAccess, err
+}
+
+const createExpenseTag = `
+INSERT INTO expense_tag (
+    expense_id,
+    tag_id
+) VALUES (
+    :expense_id,
+    :tag_id
+)
+`
+
+type CreateExpenseTagParams struct {
+	ExpenseID string `db:"expense_id"`
+	TagID     string `db:"tag_id"`
+}
+
+func (q *Queries) CreateExpenseTag(ctx context.Context, arg CreateExpenseTagParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createExpenseTag, arg)
+}
+
+const deleteExpenseTagsByExpenseID = `
+DELETE FROM
+    expense_tag
+WHERE
+    expense_id = :expense_id
+`
+
+type DeleteExpenseTagsByExpenseIDParams struct {
+	ExpenseID string `db:"expense_id"`
+}
+
+func (q *Queries) DeleteExpenseTagsByExpenseID(ctx context.Context, expenseID string) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteExpenseTagsByExpenseID, DeleteExpenseTagsByExpenseIDParams{ExpenseID: expenseID})
+}
+
+const getExpenseTagsByExpenseIDs = `
+SELECT
+    expense_tag.expense_id,
+    expense_tag.tag_id
+FROM
+    expense_tag
+JOIN
+    tag ON tag.id = expense_tag.tag_id
+WHERE
+    expense_tag.expense_id IN (SELECT value FROM json_each(:expense_ids))
+ORDER BY
+    tag.name ASC
+`
+
+type GetExpenseTagsByExpenseIDsParams struct {
+	// ExpenseIDs is a JSON array of expense IDs
+	ExpenseIDs string `db:"expense_ids"`
+}
+
+// GetExpenseTagsByExpenseIDs returns the tags of all the expenses at once,
+// ordered by tag name.
+func (q *Queries) GetExpenseTagsByExpenseIDs(ctx context.Context, arg GetExpenseTagsByExpenseIDsParams) ([]ExpenseTag, error) {
+	items := []ExpenseTag{}
+	err := NamedSelectContext(ctx, q.db, &items, getExpenseTagsByExpenseIDs, arg)
+	return items, err
+}
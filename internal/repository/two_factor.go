@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+)
+
+const getUserTOTPByUserID = `
+SELECT
+    *
+FROM
+    user_totp
+WHERE
+    user_id = :user_id
+`
+
+type GetUserTOTPByUserIDParams struct {
+	UserID string `db:"user_id"`
+}
+
+func (q *Queries) GetUserTOTPByUserID(ctx context.Context, userID string) ([]UserTOTP, error) {
+	items := []UserTOTP{}
+	err := NamedSelectContext(ctx, q.db, &items, getUserTOTPByUserID, GetUserTOTPByUserIDParams{UserID: userID})
+	return items, err
+}
+
+// upsertUserTOTP replaces the secret of an enrolment that is not confirmed yet
+const upsertUserTOTP = `
+INSERT INTO user_totp (
+    user_id,
+    secret,
+    created_at,
+    updated_at
+) VALUES (
+    :user_id,
+    :secret,
+    :created_at,
+    :updated_at
+)
+ON CONFLICT (user_id) DO UPDATE SET
+    secret = excluded.secret,
+    enabled_at = NULL,
+    last_step = 0,
+    updated_at = excluded.updated_at
+`
+
+type UpsertUserTOTPParams struct {
+	UserID    string `db:"user_id"`
+	Secret    string `db:"secret"`
+	CreatedAt string `db:"created_at"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+func (q *Queries) UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, upsertUserTOTP, arg)
+}
+
+const enableUserTOTP = `
+UPDATE
+    user_totp
+SET
+    enabled_at = :enabled_at,
+    last_step = :last_step,
+    updated_at = :updated_at
+WHERE
+    user_id = :user_id AND
+    enabled_at IS NULL
+`
+
+type EnableUserTOTPParams struct {
+	EnabledAt string `db:"enabled_at"`
+	LastStep  int64  `db:"last_step"`
+	UpdatedAt string `db:"updated_at"`
+	UserID    string `db:"user_id"`
+}
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, enableUserTOTP, arg)
+}
+
+// updateUserTOTPLastStep only moves the last step forward, so that a code
+// used concurrently is accepted once
+const updateUserTOTPLastStep = `
+UPDATE
+    user_totp
+SET
+    last_step = :last_step,
+    updated_at = :updated_at
+WHERE
+    user_id = :user_id AND
+    last_step < :last_step
+`
+
+type UpdateUserTOTPLastStepParams struct {
+	LastStep  int64  `db:"last_step"`
+	UpdatedAt string `db:"updated_at"`
+	UserID    string `db:"user_id"`
+}
+
+func (q *Queries) UpdateUserTOTPLastStep(ctx context.Context, arg UpdateUserTOTPLastStepParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, updateUserTOTPLastStep, arg)
+}
+
+const deleteUserTOTPByUserID = `
+DELETE FROM
+    user_totp
+WHERE
+    user_id = :user_id
+`
+
+type DeleteUserTOTPByUserIDParams struct {
+	UserID string `db:"user_id"`
+}
+
+func (q *Queries) DeleteUserTOTPByUserID(ctx context.Context, userID string) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteUserTOTPByUserID, DeleteUserTOTPByUserIDParams{UserID: userID})
+}
+
+const createUserRecoveryCode = `
+INSERT INTO user_recovery_code (
+    id,
+    user_id,
+    code_hash,
+    created_at
+) VALUES (
+    :id,
+    :user_id,
+    :code_hash,
+    :created_at
+)
+`
+
+type CreateUserRecoveryCodeParams struct {
+	ID        string `db:"id"`
+	UserID    string `db:"user_id"`
+	CodeHash  string `db:"code_hash"`
+	CreatedAt string `db:"created_at"`
+}
+
+func (q *Queries) CreateUserRecoveryCode(ctx context.Context, arg CreateUserRecoveryCodeParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, createUserRecoveryCode, arg)
+}
+
+const countUnusedUserRecoveryCodes = `
+SELECT
+    COUNT(*)
+FROM
+    user_recovery_code
+WHERE
+    user_id = :user_id AND
+    used_at IS NULL
+`
+
+type CountUnusedUserRecoveryCodesParams struct {
+	UserID string `db:"user_id"`
+}
+
+func (q *Queries) CountUnusedUserRecoveryCodes(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := NamedGetContext(ctx, q.db, &count, countUnusedUserRecoveryCodes, CountUnusedUserRecoveryCodesParams{UserID: userID})
+	return count, err
+}
+
+const useUserRecoveryCode = `
+UPDATE
+    user_recovery_code
+SET
+    used_at = :used_at
+WHERE
+    user_id = :user_id AND
+    code_hash = :code_hash AND
+    used_at IS NULL
+`
+
+type UseUserRecoveryCodeParams struct {
+	UsedAt   string `db:"used_at"`
+	UserID   string `db:"user_id"`
+	CodeHash string `db:"code_hash"`
+}
+
+func (q *Queries) UseUserRecoveryCode(ctx context.Context, arg UseUserRecoveryCodeParams) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, useUserRecoveryCode, arg)
+}
+
+const deleteUserRecoveryCodesByUserID = `
+DELETE FROM
+    user_recovery_code
+WHERE
+    user_id = :user_id
+`
+
+type DeleteUserRecoveryCodesByUserIDParams struct {
+	UserID string `db:"user_id"`
+}
+
+func (q *Queries) DeleteUserRecoveryCodesByUserID(ctx context.Context, userID string) (int64, error) {
+	return NamedExecRowsAffectedContext(ctx, q.db, deleteUserRecoveryCodesByUserID, DeleteUserRecoveryCodesByUserIDParams{UserID: userID})
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/jmoiron/sqlx"
 
 	"github.com/jljl1337/xpense/internal/cron"
+	"github.com/jljl1337/xpense/internal/crypto"
 	"github.com/jljl1337/xpense/internal/db"
 	"github.com/jljl1337/xpense/internal/env"
 	"github.com/jljl1337/xpense/internal/http/handler"
@@ -36,21 +37,40 @@ func NewServer() (*Server, error) {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	// Hash the tokens of the sessions created before they were stored hashed
+	sessionTokenHashKey, err := crypto.LoadOrCreateKey(env.SessionTokenHashKeyPath)
+	if err != nil {
+		dbInstance.Close()
+		return nil, fmt.Errorf("failed to load session token hash key: %w", err)
+	}
+
+	hashedSessions, err := db.HashSessionTokens(dbInstance, func(token string) string {
+		return crypto.HashTokenWithKey(token, sessionTokenHashKey)
+	})
+	if err != nil {
+		dbInstance.Close()
+		return nil, fmt.Errorf("failed to hash session tokens: %w", err)
+	}
+	if hashedSessions > 0 {
+		slog.Info(fmt.Sprintf("Hashed the tokens of %d existing sessions", hashedSessions))
+	}
+
 	// Serve the API
 	mux := http.NewServeMux()
 
 	apiMux := http.NewServeMux()
 
-	endpointService := service.NewEndpointService(dbInstance)
+	endpointService := service.NewEndpointService(dbInstance, sessionTokenHashKey)
 	endpointHandler := handler.NewEndpointHandler(endpointService)
 	endpointHandler.RegisterRoutes(apiMux)
 
-	middlewareService := service.NewMiddlewareService(dbInstance)
+	middlewareService := service.NewMiddlewareService(dbInstance, sessionTokenHashKey)
 	middlewareProvider := middleware.NewMiddlewareProvider(middlewareService)
 
 	stack := middleware.CreateStack(
 		middlewareProvider.CORS(),
 		middlewareProvider.Logging(),
+		middlewareProvider.SignInThrottle(),
 		middlewareProvider.Auth(),
 	)
 
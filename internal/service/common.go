@@ -19,3 +19,28 @@ func checkPassword(password string) (bool, error) {
 
 	return r.MatchString(password), nil
 }
+
+func checkCurrency(currency string) (bool, error) {
+	r, err := regexp.Compile("^[A-Z]{3}$")
+	if err != nil {
+		return false, err
+	}
+
+	return r.MatchString(currency), nil
+}
+
+// nullableString returns nil for an empty string, and a pointer to s otherwise.
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// derefString returns an empty string for nil, and the value of s otherwise.
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
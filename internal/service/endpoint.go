@@ -4,10 +4,13 @@ import "github.com/jmoiron/sqlx"
 
 type EndpointService struct {
 	db *sqlx.DB
+	// sessionTokenHashKey is the key of the session token hashes
+	sessionTokenHashKey []byte
 }
 
-func NewEndpointService(db *sqlx.DB) *EndpointService {
+func NewEndpointService(db *sqlx.DB, sessionTokenHashKey []byte) *EndpointService {
 	return &EndpointService{
-		db: db,
+		db:                  db,
+		sessionTokenHashKey: sessionTokenHashKey,
 	}
 }
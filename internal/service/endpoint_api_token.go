@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jljl1337/xpense/internal/crypto"
+	"github.com/jljl1337/xpense/internal/env"
+	"github.com/jljl1337/xpense/internal/format"
+	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+const (
+	// APITokenScopeReadOnly only allows the requests not changing anything
+	APITokenScopeReadOnly  = "read-only"
+	APITokenScopeReadWrite = "read-write"
+)
+
+func IsValidAPITokenScope(scope string) bool {
+	switch scope {
+	case APITokenScopeReadOnly, APITokenScopeReadWrite:
+		return true
+	default:
+		return false
+	}
+}
+
+// CreatedAPIToken is a new API token with the token itself, which is only
+// returned on creation as it is stored hashed.
+type CreatedAPIToken struct {
+	repository.APIToken
+	Token string `json:"token"`
+}
+
+// CreateAPIToken creates an API token for the user, expiring at expiresAt in
+// RFC 3339 format unless it is empty.
+func (s *EndpointService) CreateAPIToken(ctx context.Context, userID, name, scope, expiresAt string) (*CreatedAPIToken, error) {
+	if !IsValidAPITokenScope(scope) {
+		return nil, NewServiceError(ErrCodeUnprocessable, "scope must be read-only or read-write")
+	}
+
+	now := time.Now()
+
+	var expiresAtISO8601 *string
+	if expiresAt != "" {
+		parsed, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, NewServiceError(ErrCodeUnprocessable, "invalid expiry time format")
+		}
+
+		if !parsed.After(now) {
+			return nil, NewServiceError(ErrCodeUnprocessable, "expiry time must be in the future")
+		}
+
+		value := format.TimeToISO8601(parsed)
+		expiresAtISO8601 = &value
+	}
+
+	token := generator.NewToken(env.APITokenLength, env.APITokenCharset)
+	currentTime := format.TimeToISO8601(now)
+
+	apiToken := repository.APIToken{
+		ID:        generator.NewULID(),
+		UserID:    userID,
+		Name:      name,
+		TokenHash: crypto.HashToken(token),
+		Scope:     scope,
+		ExpiresAt: expiresAtISO8601,
+		CreatedAt: currentTime,
+		UpdatedAt: currentTime,
+	}
+
+	queries := repository.New(s.db)
+
+	_, err := queries.CreateAPIToken(ctx, repository.CreateAPITokenParams{
+		ID:        apiToken.ID,
+		UserID:    apiToken.UserID,
+		Name:      apiToken.Name,
+		TokenHash: apiToken.TokenHash,
+		Scope:     apiToken.Scope,
+		ExpiresAt: apiToken.ExpiresAt,
+		CreatedAt: apiToken.CreatedAt,
+		UpdatedAt: apiToken.UpdatedAt,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to create API token: %v", err)
+	}
+
+	return &CreatedAPIToken{
+		APIToken: apiToken,
+		Token:    token,
+	}, nil
+}
+
+// GetAPITokensByUserID retrieves the API tokens of the user, including the
+// expired ones, the latest first.
+//
+// It returns an empty slice if the user has no API tokens.
+func (s *EndpointService) GetAPITokensByUserID(ctx context.Context, userID string) ([]repository.APIToken, error) {
+	queries := repository.New(s.db)
+
+	apiTokens, err := queries.GetAPITokensByUserID(ctx, userID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get API tokens: %v", err)
+	}
+
+	return apiTokens, nil
+}
+
+// DeleteAPITokenByID revokes an API token of the user.
+func (s *EndpointService) DeleteAPITokenByID(ctx context.Context, userID, apiTokenID string) error {
+	queries := repository.New(s.db)
+
+	rows, err := queries.DeleteAPITokenByID(ctx, repository.DeleteAPITokenByIDParams{
+		ID:     apiTokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete API token: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple API tokens deleted, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeNotFound, "API token not found")
+	}
+
+	return nil
+}
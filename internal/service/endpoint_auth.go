@@ -66,23 +66,25 @@ func (s *EndpointService) SignUp(ctx context.Context, username, password string)
 
 // GetPreSession creates a pre-session with no associated user.
 // It returns a non-empty session token and CSRF token.
-func (s *EndpointService) GetPreSession(ctx context.Context) (string, string, error) {
+func (s *EndpointService) GetPreSession(ctx context.Context, userAgent, ip string) (string, string, error) {
 	queries := repository.New(s.db)
 
 	sessionID := generator.NewULID()
 	sessionToken := generator.NewToken(env.SessionTokenLength, env.SessionTokenCharset)
-	CSRFToken := generator.NewToken(env.CSRFTokenLength, env.CSRFTokenCharset)
+	CSRFToken := sessionCSRFToken(sessionToken, s.sessionTokenHashKey)
 	currentTime := generator.NowISO8601()
 	expiresAt := format.TimeToISO8601(time.Now().Add(time.Duration(env.PreSessionLifetimeMin) * time.Minute))
 
 	if _, err := queries.CreateSession(ctx, repository.CreateSessionParams{
-		ID:        sessionID,
-		UserID:    sql.NullString{Valid: false},
-		Token:     sessionToken,
-		CsrfToken: CSRFToken,
-		ExpiresAt: expiresAt,
-		CreatedAt: currentTime,
-		UpdatedAt: currentTime,
+		ID:            sessionID,
+		UserID:        sql.NullString{Valid: false},
+		TokenHash:     hashSessionToken(sessionToken, s.sessionTokenHashKey),
+		CsrfTokenHash: crypto.HashTokenWithKey(CSRFToken, s.sessionTokenHashKey),
+		UserAgent:     nullableString(userAgent),
+		IP:            nullableString(ip),
+		ExpiresAt:     expiresAt,
+		CreatedAt:     currentTime,
+		UpdatedAt:     currentTime,
 	}); err != nil {
 		return "", "", NewServiceErrorf(ErrCodeInternal, "failed to create pre-session: %v", err)
 	}
@@ -90,68 +92,51 @@ func (s *EndpointService) GetPreSession(ctx context.Context) (string, string, er
 	return sessionToken, CSRFToken, nil
 }
 
+// SignInResult is the result of a sign-in with valid credentials.
+type SignInResult struct {
+	SessionToken string
+	CSRFToken    string
+	// SecondFactorRequired is true if the user has two-factor authentication
+	// enabled, the session is then the pre-session waiting for the second
+	// factor
+	SecondFactorRequired bool
+}
+
 // SignIn authenticates a user and creates a new session.
-// It returns non-empty session token and CSRF token if the credentials are valid.
-func (s *EndpointService) SignIn(ctx context.Context, preSessionToken, preSessionCSRFToken, username, password string) (string, string, error) {
+// If the user has two-factor authentication enabled, the pre-session is bound
+// to the user instead and the sign-in is finished by SignInSecondFactor.
+func (s *EndpointService) SignIn(ctx context.Context, preSessionToken, preSessionCSRFToken, username, password, userAgent, ip string) (*SignInResult, error) {
 	queries := repository.New(s.db)
 
 	// Validate pre-session
-	sessions, err := queries.GetSessionByToken(ctx, preSessionToken)
-
+	session, err := getValidPreSession(ctx, queries, preSessionToken, preSessionCSRFToken, s.sessionTokenHashKey)
 	if err != nil {
-		return "", "", NewServiceErrorf(ErrCodeInternal, "failed to get pre-session: %v", err)
-	}
-
-	if len(sessions) > 1 {
-		return "", "", NewServiceError(ErrCodeInternal, "multiple sessions found with the same token")
-	}
-
-	if len(sessions) < 1 {
-		return "", "", NewServiceError(ErrCodeUnauthorized, "invalid credentials")
-	}
-
-	session := sessions[0]
-
-	// Check if the session is already associated with a user
-	if session.UserID.Valid {
-		return "", "", NewServiceError(ErrCodeUnauthorized, "invalid credentials")
-	}
-
-	// CSRF token does not match
-	if preSessionCSRFToken != "" && session.CsrfToken != preSessionCSRFToken {
-		return "", "", NewServiceError(ErrCodeUnauthorized, "invalid credentials")
-	}
-
-	// Session expired
-	now := time.Now()
-	nowISO8601 := format.TimeToISO8601(now)
-	if session.ExpiresAt < nowISO8601 {
-		return "", "", NewServiceError(ErrCodeUnauthorized, "session expired")
+		return nil, err
 	}
 
 	// Validate credentials
 	users, err := queries.GetUserByUsername(ctx, username)
 	if err != nil {
-		return "", "", NewServiceErrorf(ErrCodeInternal, "failed to get user by username: %v", err)
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get user by username: %v", err)
 	}
 
 	if len(users) > 1 {
-		return "", "", NewServiceError(ErrCodeInternal, "multiple users found with the same username")
+		return nil, NewServiceError(ErrCodeInternal, "multiple users found with the same username")
 	}
 
 	if len(users) < 1 {
-		return "", "", NewServiceError(ErrCodeUnauthorized, "invalid credentials")
+		return nil, NewServiceError(ErrCodeUnauthorized, "invalid credentials")
 	}
 
 	user := users[0]
 
 	if !crypto.CheckPasswordHash(password, user.PasswordHash) {
-		return "", "", NewServiceError(ErrCodeUnauthorized, "invalid credentials")
+		return nil, NewServiceError(ErrCodeUnauthorized, "invalid credentials")
 	}
 
 	cost, err := crypto.Cost(user.PasswordHash)
 	if err != nil {
-		return "", "", NewServiceErrorf(ErrCodeInternal, "failed to get password hash cost: %v", err)
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get password hash cost: %v", err)
 	}
 
 	// Rehash password if the cost is lower than the current standard
@@ -160,7 +145,7 @@ func (s *EndpointService) SignIn(ctx context.Context, preSessionToken, preSessio
 	if cost < env.PasswordBcryptCost {
 		newHash, err := crypto.HashPassword(password, env.PasswordBcryptCost)
 		if err != nil {
-			return "", "", NewServiceErrorf(ErrCodeInternal, "failed to hash password: %v", err)
+			return nil, NewServiceErrorf(ErrCodeInternal, "failed to hash password: %v", err)
 		}
 
 		rows, err := queries.UpdateUserPassword(ctx, repository.UpdateUserPasswordParams{
@@ -169,24 +154,171 @@ func (s *EndpointService) SignIn(ctx context.Context, preSessionToken, preSessio
 			ID:           user.ID,
 		})
 		if err != nil {
-			return "", "", NewServiceErrorf(ErrCodeInternal, "failed to update user password hash: %v", err)
+			return nil, NewServiceErrorf(ErrCodeInternal, "failed to update user password hash: %v", err)
+		} else if rows < 1 {
+			return nil, NewServiceError(ErrCodeInternal, "no user updated with the new password hash")
+		} else if rows > 1 {
+			return nil, NewServiceError(ErrCodeInternal, "multiple users updated with the same ID")
+		}
+	}
+
+	// Wait for the second factor on the pre-session if required
+	totp, err := getEnabledUserTOTP(ctx, queries, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp != nil {
+		rows, err := queries.UpdateSessionPendingUserID(ctx, repository.UpdateSessionPendingUserIDParams{
+			PendingUserID: user.ID,
+			UpdatedAt:     currentTime,
+			TokenHash:     session.TokenHash,
+		})
+		if err != nil {
+			return nil, NewServiceErrorf(ErrCodeInternal, "failed to update pre-session: %v", err)
 		} else if rows < 1 {
-			return "", "", NewServiceError(ErrCodeInternal, "no user updated with the new password hash")
+			return nil, NewServiceError(ErrCodeInternal, "no pre-session updated")
 		} else if rows > 1 {
-			return "", "", NewServiceError(ErrCodeInternal, "multiple users updated with the same ID")
+			return nil, NewServiceError(ErrCodeInternal, "multiple pre-sessions updated with the same token")
+		}
+
+		return &SignInResult{
+			SessionToken:         preSessionToken,
+			CSRFToken:            sessionCSRFToken(preSessionToken, s.sessionTokenHashKey),
+			SecondFactorRequired: true,
+		}, nil
+	}
+
+	sessionToken, CSRFToken, err := createUserSession(ctx, queries, preSessionToken, user.ID, userAgent, ip, s.sessionTokenHashKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignInResult{
+		SessionToken: sessionToken,
+		CSRFToken:    CSRFToken,
+	}, nil
+}
+
+// SignInSecondFactor finishes the sign-in of a pre-session bound to a user by
+// SignIn, with either a TOTP code or a recovery code. The pre-session is
+// deactivated after too many invalid attempts.
+func (s *EndpointService) SignInSecondFactor(ctx context.Context, preSessionToken, preSessionCSRFToken, code, recoveryCode, userAgent, ip string) (*SignInResult, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	// Validate pre-session
+	session, err := getValidPreSession(ctx, queries, preSessionToken, preSessionCSRFToken, s.sessionTokenHashKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.PendingUserID == nil {
+		return nil, NewServiceError(ErrCodeUnauthorized, "invalid credentials")
+	}
+
+	if session.SecondFactorAttempts >= int64(env.SecondFactorMaxAttempts) {
+		return nil, NewServiceError(ErrCodeUnauthorized, "too many attempts, sign in again")
+	}
+
+	userID := *session.PendingUserID
+
+	// Validate the second factor
+	valid := false
+	if code != "" {
+		valid, err = useTOTPCode(ctx, queries, userID, code)
+	} else {
+		valid, err = useRecoveryCode(ctx, queries, userID, recoveryCode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		if err := recordSecondFactorFailure(ctx, queries, session); err != nil {
+			return nil, err
 		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+		}
+
+		return nil, NewServiceError(ErrCodeUnauthorized, "invalid second factor")
+	}
+
+	sessionToken, CSRFToken, err := createUserSession(ctx, queries, preSessionToken, userID, userAgent, ip, s.sessionTokenHashKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return &SignInResult{
+		SessionToken: sessionToken,
+		CSRFToken:    CSRFToken,
+	}, nil
+}
+
+// getValidPreSession returns the pre-session of the token, if it is not
+// expired and the CSRF token matches.
+func getValidPreSession(ctx context.Context, queries *repository.Queries, preSessionToken, preSessionCSRFToken string, sessionTokenHashKey []byte) (*repository.Session, error) {
+	sessions, err := queries.GetSessionByToken(ctx, hashSessionToken(preSessionToken, sessionTokenHashKey))
+
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get pre-session: %v", err)
 	}
 
+	if len(sessions) > 1 {
+		return nil, NewServiceError(ErrCodeInternal, "multiple sessions found with the same token")
+	}
+
+	if len(sessions) < 1 {
+		return nil, NewServiceError(ErrCodeUnauthorized, "invalid credentials")
+	}
+
+	session := sessions[0]
+
+	// Check if the session is already associated with a user
+	if session.UserID.Valid {
+		return nil, NewServiceError(ErrCodeUnauthorized, "invalid credentials")
+	}
+
+	// CSRF token does not match
+	if preSessionCSRFToken != "" && !checkSessionCSRFToken(session, preSessionToken, preSessionCSRFToken, sessionTokenHashKey) {
+		return nil, NewServiceError(ErrCodeUnauthorized, "invalid credentials")
+	}
+
+	// Session expired
+	if session.ExpiresAt < generator.NowISO8601() {
+		return nil, NewServiceError(ErrCodeUnauthorized, "session expired")
+	}
+
+	return &session, nil
+}
+
+// createUserSession deactivates the pre-session and creates a new session
+// associated with the user for the client, returning its session token and
+// CSRF token.
+func createUserSession(ctx context.Context, queries *repository.Queries, preSessionToken, userID, userAgent, ip string, sessionTokenHashKey []byte) (string, string, error) {
 	sessionID := generator.NewULID()
 	sessionToken := generator.NewToken(env.SessionTokenLength, env.SessionTokenCharset)
-	CSRFToken := generator.NewToken(env.CSRFTokenLength, env.CSRFTokenCharset)
-	expiresAt := format.TimeToISO8601(time.Now().Add(time.Duration(env.SessionLifetimeMin) * time.Hour))
+	CSRFToken := sessionCSRFToken(sessionToken, sessionTokenHashKey)
+	now := time.Now()
+	currentTime := format.TimeToISO8601(now)
+	expiresAt := format.TimeToISO8601(now.Add(time.Duration(env.SessionLifetimeMin) * time.Hour))
 
 	// Deactivate the pre-session
 	rows, err := queries.UpdateSessionByToken(ctx, repository.UpdateSessionByTokenParams{
-		Token:     preSessionToken,
-		ExpiresAt: nowISO8601,
-		UpdatedAt: nowISO8601,
+		TokenHash: hashSessionToken(preSessionToken, sessionTokenHashKey),
+		ExpiresAt: currentTime,
+		UpdatedAt: currentTime,
 	})
 	if err != nil {
 		return "", "", NewServiceErrorf(ErrCodeInternal, "failed to update pre-session: %v", err)
@@ -198,13 +330,15 @@ func (s *EndpointService) SignIn(ctx context.Context, preSessionToken, preSessio
 
 	// Create a new session associated with the user
 	rows, err = queries.CreateSession(ctx, repository.CreateSessionParams{
-		ID:        sessionID,
-		UserID:    sql.NullString{String: user.ID, Valid: true},
-		Token:     sessionToken,
-		CsrfToken: CSRFToken,
-		ExpiresAt: expiresAt,
-		CreatedAt: currentTime,
-		UpdatedAt: currentTime,
+		ID:            sessionID,
+		UserID:        sql.NullString{String: userID, Valid: true},
+		TokenHash:     hashSessionToken(sessionToken, sessionTokenHashKey),
+		CsrfTokenHash: crypto.HashTokenWithKey(CSRFToken, sessionTokenHashKey),
+		UserAgent:     nullableString(userAgent),
+		IP:            nullableString(ip),
+		ExpiresAt:     expiresAt,
+		CreatedAt:     currentTime,
+		UpdatedAt:     currentTime,
 	})
 	if err != nil {
 		return "", "", NewServiceErrorf(ErrCodeInternal, "failed to create session: %v", err)
@@ -217,12 +351,39 @@ func (s *EndpointService) SignIn(ctx context.Context, preSessionToken, preSessio
 	return sessionToken, CSRFToken, nil
 }
 
+// recordSecondFactorFailure counts an invalid second factor attempt of the
+// pre-session, deactivating it when the attempts run out.
+func recordSecondFactorFailure(ctx context.Context, queries *repository.Queries, session *repository.Session) error {
+	currentTime := generator.NowISO8601()
+
+	if _, err := queries.IncrementSessionSecondFactorAttempts(ctx, repository.IncrementSessionSecondFactorAttemptsParams{
+		UpdatedAt: currentTime,
+		TokenHash: session.TokenHash,
+	}); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to update pre-session: %v", err)
+	}
+
+	if session.SecondFactorAttempts+1 < int64(env.SecondFactorMaxAttempts) {
+		return nil
+	}
+
+	if _, err := queries.UpdateSessionByToken(ctx, repository.UpdateSessionByTokenParams{
+		TokenHash: session.TokenHash,
+		ExpiresAt: currentTime,
+		UpdatedAt: currentTime,
+	}); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to deactivate pre-session: %v", err)
+	}
+
+	return nil
+}
+
 func (s *EndpointService) SignOut(ctx context.Context, sessionToken string) error {
 	queries := repository.New(s.db)
 
 	now := generator.NowISO8601()
 	rows, err := queries.UpdateSessionByToken(ctx, repository.UpdateSessionByTokenParams{
-		Token:     sessionToken,
+		TokenHash: hashSessionToken(sessionToken, s.sessionTokenHashKey),
 		ExpiresAt: now,
 		UpdatedAt: now,
 	})
@@ -257,10 +418,82 @@ func (s *EndpointService) SignOutAllSession(ctx context.Context, userID string)
 	return nil
 }
 
+// UserSession is an active session of a user, with the client it was last
+// seen from.
+type UserSession struct {
+	ID         string  `json:"id"`
+	UserAgent  *string `json:"userAgent"`
+	IP         *string `json:"ip"`
+	CreatedAt  string  `json:"createdAt"`
+	LastSeenAt string  `json:"lastSeenAt"`
+	ExpiresAt  string  `json:"expiresAt"`
+	// Current is true for the session of the request
+	Current bool `json:"current"`
+}
+
+// GetSessionsByUserID returns the active sessions of the user, marking the one
+// of the session token, which is empty if the request is not from a session.
+func (s *EndpointService) GetSessionsByUserID(ctx context.Context, userID, sessionToken string) ([]UserSession, error) {
+	queries := repository.New(s.db)
+
+	sessions, err := queries.GetActiveSessionsByUserID(ctx, repository.GetActiveSessionsByUserIDParams{
+		UserID: sql.NullString{String: userID, Valid: true},
+		Now:    generator.NowISO8601(),
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get sessions: %v", err)
+	}
+
+	currentTokenHash := ""
+	if sessionToken != "" {
+		currentTokenHash = hashSessionToken(sessionToken, s.sessionTokenHashKey)
+	}
+
+	userSessions := make([]UserSession, 0, len(sessions))
+	for _, session := range sessions {
+		userSessions = append(userSessions, UserSession{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.TokenHash == currentTokenHash,
+		})
+	}
+
+	return userSessions, nil
+}
+
+// RevokeSessionByID signs out an active session of the user.
+func (s *EndpointService) RevokeSessionByID(ctx context.Context, userID, sessionID string) error {
+	queries := repository.New(s.db)
+
+	now := generator.NowISO8601()
+	rows, err := queries.UpdateSessionByIDAndUserID(ctx, repository.UpdateSessionByIDAndUserIDParams{
+		ExpiresAt: now,
+		UpdatedAt: now,
+		ID:        sessionID,
+		UserID:    sql.NullString{String: userID, Valid: true},
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to revoke session: %v", err)
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeNotFound, "session not found")
+	}
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple sessions revoked")
+	}
+
+	return nil
+}
+
 func (s *EndpointService) CSRFToken(ctx context.Context, sessionToken string) (string, error) {
 	queries := repository.New(s.db)
 
-	sessions, err := queries.GetSessionByToken(ctx, sessionToken)
+	sessions, err := queries.GetSessionByToken(ctx, hashSessionToken(sessionToken, s.sessionTokenHashKey))
 
 	if err != nil {
 		return "", NewServiceErrorf(ErrCodeInternal, "failed to get session: %v", err)
@@ -281,5 +514,5 @@ func (s *EndpointService) CSRFToken(ctx context.Context, sessionToken string) (s
 		return "", NewServiceError(ErrCodeUnauthorized, "unauthorized")
 	}
 
-	return session.CsrfToken, nil
+	return sessionCSRFToken(sessionToken, s.sessionTokenHashKey), nil
 }
@@ -4,26 +4,61 @@ import (
 	"context"
 
 	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/money"
 	"github.com/jljl1337/xpense/internal/repository"
 )
 
-func (s *EndpointService) CreateBook(ctx context.Context, userID, name, description string) error {
-	queries := repository.New(s.db)
+// CreateBook creates a new book owned by the user.
+//
+// The currency is the base currency of the book, it cannot be changed later.
+func (s *EndpointService) CreateBook(ctx context.Context, userID, name, description, currency string) error {
+	currencyValid, err := checkCurrency(currency)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to validate currency: %v", err)
+	}
+	if !currencyValid {
+		return NewServiceError(ErrCodeUnprocessable, "invalid currency format")
+	}
 
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	bookID := generator.NewULID()
 	currentTime := generator.NowISO8601()
 
-	_, err := queries.CreateBook(ctx, repository.CreateBookParams{
-		ID:          generator.NewULID(),
+	_, err = queries.CreateBook(ctx, repository.CreateBookParams{
+		ID:          bookID,
 		UserID:      userID,
 		Name:        name,
 		Description: description,
 		CreatedAt:   currentTime,
 		UpdatedAt:   currentTime,
+		Currency:    currency,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to create book: %v", err)
 	}
 
+	_, err = queries.CreateBookMember(ctx, repository.CreateBookMemberParams{
+		BookID:    bookID,
+		UserID:    userID,
+		Role:      BookRoleOwner,
+		CreatedAt: currentTime,
+		UpdatedAt: currentTime,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to create book member: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
 	return nil
 }
 
@@ -38,7 +73,8 @@ func (s *EndpointService) GetBooksCountByUserID(ctx context.Context, userID stri
 	return countResult, nil
 }
 
-// GetBooksByUserID retrieves a paginated list of books for a specific user.
+// GetBooksByUserID retrieves a paginated list of the books the user is a member
+// of.
 //
 // It returns an empty slice if no books are found.
 func (s *EndpointService) GetBooksByUserID(ctx context.Context, userID string, page int64, pageSize int64) ([]repository.Book, error) {
@@ -66,6 +102,7 @@ func (s *EndpointService) GetBookByID(ctx context.Context, userID, bookID string
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: bookID,
 		UserID: userID,
+		Role:   BookRoleViewer,
 	})
 	if err != nil {
 		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -94,7 +131,7 @@ func (s *EndpointService) GetBookByID(ctx context.Context, userID, bookID string
 	return &book, nil
 }
 
-// UpdateBookByID updates a book's name and description if the user has access to it.
+// UpdateBookByID updates a book's name and description if the user is its owner.
 func (s *EndpointService) UpdateBookByID(ctx context.Context, userID, bookID, name, description string) error {
 	queries := repository.New(s.db)
 
@@ -102,6 +139,7 @@ func (s *EndpointService) UpdateBookByID(ctx context.Context, userID, bookID, na
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: bookID,
 		UserID: userID,
+		Role:   BookRoleOwner,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -133,7 +171,7 @@ func (s *EndpointService) UpdateBookByID(ctx context.Context, userID, bookID, na
 	return nil
 }
 
-// DeleteBookByID deletes a book by its ID if the user has access to it.
+// DeleteBookByID deletes a book by its ID if the user is its owner.
 func (s *EndpointService) DeleteBookByID(ctx context.Context, userID, bookID string) error {
 	queries := repository.New(s.db)
 
@@ -141,6 +179,7 @@ func (s *EndpointService) DeleteBookByID(ctx context.Context, userID, bookID str
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: bookID,
 		UserID: userID,
+		Role:   BookRoleOwner,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -166,3 +205,38 @@ func (s *EndpointService) DeleteBookByID(ctx context.Context, userID, bookID str
 
 	return nil
 }
+
+// getBookCurrency returns the currency of a book, without checking access.
+func (s *EndpointService) getBookCurrency(ctx context.Context, bookID string) (string, error) {
+	queries := repository.New(s.db)
+
+	books, err := queries.GetBookByID(ctx, bookID)
+	if err != nil {
+		return "", NewServiceErrorf(ErrCodeInternal, "failed to get book by ID: %v", err)
+	}
+
+	if len(books) > 1 {
+		return "", NewServiceError(ErrCodeInternal, "multiple books found with the same ID")
+	}
+
+	if len(books) < 1 {
+		return "", NewServiceError(ErrCodeNotFound, "book not found or access denied")
+	}
+
+	return books[0].Currency, nil
+}
+
+// getBookMinorUnits returns the amount in minor units of the book currency.
+func (s *EndpointService) getBookMinorUnits(ctx context.Context, bookID string, amount money.Decimal) (int64, error) {
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return 0, err
+	}
+
+	minorUnits, err := amount.MinorUnits(money.Scale(bookCurrency))
+	if err != nil {
+		return 0, NewServiceErrorf(ErrCodeUnprocessable, "invalid amount: %v", err)
+	}
+
+	return minorUnits, nil
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jljl1337/xpense/internal/env"
+	"github.com/jljl1337/xpense/internal/format"
+	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+// CreateBookInvite creates an invite code to join a book with the role if the
+// user is the owner of the book, and returns the invite.
+//
+// The role cannot be owner, and the invite can be redeemed once before it
+// expires.
+func (s *EndpointService) CreateBookInvite(ctx context.Context, userID, bookID, role string) (*repository.BookInvite, error) {
+	if role != BookRoleEditor && role != BookRoleViewer {
+		return nil, NewServiceError(ErrCodeUnprocessable, "role must be editor or viewer")
+	}
+
+	queries := repository.New(s.db)
+
+	// Check if the user is the owner of the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleOwner,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeNotFound, "book not found or access denied")
+	}
+
+	now := time.Now()
+
+	invite := repository.BookInvite{
+		ID:        generator.NewULID(),
+		BookID:    bookID,
+		Code:      generator.NewToken(env.BookInviteCodeLength, env.BookInviteCodeCharset),
+		Role:      role,
+		CreatedBy: userID,
+		ExpiresAt: format.TimeToISO8601(now.Add(time.Duration(env.BookInviteLifetimeMin) * time.Minute)),
+		CreatedAt: format.TimeToISO8601(now),
+	}
+
+	_, err = queries.CreateBookInvite(ctx, repository.CreateBookInviteParams{
+		ID:        invite.ID,
+		BookID:    invite.BookID,
+		Code:      invite.Code,
+		Role:      invite.Role,
+		CreatedBy: invite.CreatedBy,
+		ExpiresAt: invite.ExpiresAt,
+		CreatedAt: invite.CreatedAt,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to create book invite: %v", err)
+	}
+
+	return &invite, nil
+}
+
+// GetBookInvitesByBookID retrieves the pending invites of a book if the user is
+// the owner of the book, the latest first.
+//
+// It returns an empty slice if there are no pending invites.
+func (s *EndpointService) GetBookInvitesByBookID(ctx context.Context, userID, bookID string) ([]repository.BookInvite, error) {
+	queries := repository.New(s.db)
+
+	// Check if the user is the owner of the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleOwner,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeNotFound, "book not found or access denied")
+	}
+
+	invites, err := queries.GetPendingBookInvitesByBookID(ctx, repository.GetPendingBookInvitesByBookIDParams{
+		BookID: bookID,
+		Now:    generator.NowISO8601(),
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get book invites: %v", err)
+	}
+
+	return invites, nil
+}
+
+// DeleteBookInviteByID revokes an invite of a book if the user is the owner of
+// the book.
+func (s *EndpointService) DeleteBookInviteByID(ctx context.Context, userID, bookID, inviteID string) error {
+	queries := repository.New(s.db)
+
+	// Check if the user is the owner of the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleOwner,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return NewServiceError(ErrCodeNotFound, "book not found or access denied")
+	}
+
+	invites, err := queries.GetBookInviteByID(ctx, inviteID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to get book invite by ID: %v", err)
+	}
+
+	if len(invites) > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple book invites found with the same ID")
+	}
+
+	if len(invites) < 1 || invites[0].BookID != bookID {
+		return NewServiceError(ErrCodeNotFound, "book invite not found")
+	}
+
+	rows, err := queries.DeleteBookInviteByID(ctx, inviteID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete book invite: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple book invites deleted, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "no book invite deleted")
+	}
+
+	return nil
+}
+
+// RedeemBookInvite adds the user to the book of the invite with its role, and
+// returns the ID of the book. The invite is deleted once redeemed.
+func (s *EndpointService) RedeemBookInvite(ctx context.Context, userID, code string) (string, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	invites, err := queries.GetBookInviteByCode(ctx, code)
+	if err != nil {
+		return "", NewServiceErrorf(ErrCodeInternal, "failed to get book invite by code: %v", err)
+	}
+
+	if len(invites) > 1 {
+		return "", NewServiceError(ErrCodeInternal, "multiple book invites found with the same code")
+	}
+
+	currentTime := generator.NowISO8601()
+
+	if len(invites) < 1 || invites[0].ExpiresAt < currentTime {
+		return "", NewServiceError(ErrCodeNotFound, "invite not found or expired")
+	}
+
+	invite := invites[0]
+
+	// The invite is claimed before adding the member, so that a concurrent
+	// redemption of the same code cannot add another one
+	rows, err := queries.DeleteBookInviteByID(ctx, invite.ID)
+	if err != nil {
+		return "", NewServiceErrorf(ErrCodeInternal, "failed to delete book invite: %v", err)
+	}
+
+	if rows > 1 {
+		return "", NewServiceError(ErrCodeInternal, "multiple book invites deleted, data integrity issue")
+	}
+
+	if rows < 1 {
+		return "", NewServiceError(ErrCodeConflict, "invite already redeemed")
+	}
+
+	rows, err = queries.CreateBookMember(ctx, repository.CreateBookMemberParams{
+		BookID:    invite.BookID,
+		UserID:    userID,
+		Role:      invite.Role,
+		CreatedAt: currentTime,
+		UpdatedAt: currentTime,
+	})
+	if err != nil {
+		return "", NewServiceErrorf(ErrCodeInternal, "failed to create book member: %v", err)
+	}
+
+	if rows < 1 {
+		return "", NewServiceError(ErrCodeConflict, "user is already a member of the book")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return invite.BookID, nil
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+// The roles of the members of a book, each role can do everything the roles
+// after it can.
+const (
+	// BookRoleOwner can also update and delete the book and manage its
+	// members, a book has exactly one owner
+	BookRoleOwner = "owner"
+	// BookRoleEditor can also create, update and delete the content of the book
+	BookRoleEditor = "editor"
+	// BookRoleViewer can read the book and its content
+	BookRoleViewer = "viewer"
+)
+
+func IsValidBookRole(role string) bool {
+	switch role {
+	case BookRoleOwner, BookRoleEditor, BookRoleViewer:
+		return true
+	default:
+		return false
+	}
+}
+
+// AddBookMember adds the user with the username to a book with the role if the
+// user is the owner of the book.
+//
+// The role cannot be owner, the ownership is transferred by changing the role
+// of an existing member instead.
+func (s *EndpointService) AddBookMember(ctx context.Context, userID, bookID, username, role string) error {
+	if role != BookRoleEditor && role != BookRoleViewer {
+		return NewServiceError(ErrCodeUnprocessable, "role must be editor or viewer")
+	}
+
+	queries := repository.New(s.db)
+
+	// Check if the user is the owner of the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleOwner,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return NewServiceError(ErrCodeNotFound, "book not found or access denied")
+	}
+
+	users, err := queries.GetUserByUsername(ctx, username)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to get user by username: %v", err)
+	}
+
+	if len(users) > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple users found with the same username")
+	}
+
+	if len(users) < 1 {
+		return NewServiceError(ErrCodeUnprocessable, "user not found")
+	}
+
+	currentTime := generator.NowISO8601()
+
+	rows, err := queries.CreateBookMember(ctx, repository.CreateBookMemberParams{
+		BookID:    bookID,
+		UserID:    users[0].ID,
+		Role:      role,
+		CreatedAt: currentTime,
+		UpdatedAt: currentTime,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to create book member: %v", err)
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeConflict, "user is already a member of the book")
+	}
+
+	return nil
+}
+
+// GetBookMembersByBookID retrieves the members of a book with their username
+// if the user is a member of the book, the owner first.
+func (s *EndpointService) GetBookMembersByBookID(ctx context.Context, userID, bookID string) ([]repository.BookMemberRow, error) {
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleViewer,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeNotFound, "book not found or access denied")
+	}
+
+	members, err := queries.GetBookMembersByBookID(ctx, bookID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get book members: %v", err)
+	}
+
+	return members, nil
+}
+
+// UpdateBookMemberRole changes the role of a member of a book if the user is
+// the owner of the book.
+//
+// Changing the role of a member to owner transfers the ownership of the book
+// to the member, the previous owner becoming an editor. The owner cannot
+// change their own role otherwise.
+func (s *EndpointService) UpdateBookMemberRole(ctx context.Context, userID, bookID, memberUserID, role string) error {
+	if !IsValidBookRole(role) {
+		return NewServiceError(ErrCodeUnprocessable, "invalid role")
+	}
+
+	if memberUserID == userID {
+		return NewServiceError(ErrCodeUnprocessable, "owner cannot change their own role, transfer the ownership instead")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	// Check if the user is the owner of the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleOwner,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return NewServiceError(ErrCodeNotFound, "book not found or access denied")
+	}
+
+	currentTime := generator.NowISO8601()
+
+	rows, err := queries.UpdateBookMemberRole(ctx, repository.UpdateBookMemberRoleParams{
+		Role:      role,
+		UpdatedAt: currentTime,
+		BookID:    bookID,
+		UserID:    memberUserID,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to update book member: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple book members updated, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeNotFound, "book member not found")
+	}
+
+	if role == BookRoleOwner {
+		// Keep exactly one owner
+		_, err := queries.UpdateBookMemberRole(ctx, repository.UpdateBookMemberRoleParams{
+			Role:      BookRoleEditor,
+			UpdatedAt: currentTime,
+			BookID:    bookID,
+			UserID:    userID,
+		})
+		if err != nil {
+			return NewServiceErrorf(ErrCodeInternal, "failed to update book member: %v", err)
+		}
+
+		_, err = queries.UpdateBookUserID(ctx, repository.UpdateBookUserIDParams{
+			UserID:    memberUserID,
+			UpdatedAt: currentTime,
+			ID:        bookID,
+		})
+		if err != nil {
+			return NewServiceErrorf(ErrCodeInternal, "failed to update book owner: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// RemoveBookMember removes a member from a book if the user is the owner of
+// the book, or if the member is the user leaving the book. The owner cannot
+// leave the book, it has to be deleted or its ownership transferred first.
+func (s *EndpointService) RemoveBookMember(ctx context.Context, userID, bookID, memberUserID string) error {
+	role := BookRoleOwner
+	if memberUserID == userID {
+		role = BookRoleViewer
+	}
+
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   role,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return NewServiceError(ErrCodeNotFound, "book not found or access denied")
+	}
+
+	members, err := queries.GetBookMember(ctx, repository.GetBookMemberParams{
+		BookID: bookID,
+		UserID: memberUserID,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to get book member: %v", err)
+	}
+
+	if len(members) > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple book members found with the same user")
+	}
+
+	if len(members) < 1 {
+		return NewServiceError(ErrCodeNotFound, "book member not found")
+	}
+
+	if members[0].Role == BookRoleOwner {
+		return NewServiceError(ErrCodeUnprocessable, "owner cannot leave the book, transfer the ownership first")
+	}
+
+	rows, err := queries.DeleteBookMember(ctx, repository.DeleteBookMemberParams{
+		BookID: bookID,
+		UserID: memberUserID,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete book member: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple book members deleted, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "no book member deleted")
+	}
+
+	return nil
+}
@@ -0,0 +1,595 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/jljl1337/xpense/internal/env"
+	"github.com/jljl1337/xpense/internal/format"
+	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/money"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+const (
+	BudgetPeriodWeekly  = "weekly"
+	BudgetPeriodMonthly = "monthly"
+	BudgetPeriodYearly  = "yearly"
+)
+
+func IsValidBudgetPeriod(period string) bool {
+	switch period {
+	case BudgetPeriodWeekly, BudgetPeriodMonthly, BudgetPeriodYearly:
+		return true
+	default:
+		return false
+	}
+}
+
+const (
+	// DefaultBudgetStatusPeriods is the number of periods of the budget status
+	// if not given, including the current one
+	DefaultBudgetStatusPeriods = 6
+	MaxBudgetStatusPeriods     = 120
+)
+
+// Budget is a budget with its amount as a decimal in the book currency.
+type Budget struct {
+	repository.Budget
+	Amount money.Decimal `json:"amount"`
+}
+
+func newBudget(budget repository.Budget, bookCurrency string) Budget {
+	return Budget{
+		Budget: budget,
+		Amount: money.New(budget.Amount, money.Scale(bookCurrency)),
+	}
+}
+
+// BudgetParams are the fields of a budget set by the user.
+type BudgetParams struct {
+	// CategoryID is empty for a budget of all the expenses of the book
+	CategoryID string
+	Amount     money.Decimal
+	Period     string
+	// Rollover carries the remaining amount of a period, or the overspending,
+	// over to the next period
+	Rollover bool
+}
+
+// CreateBudget creates a new budget if the user has access to the book and the
+// category.
+func (s *EndpointService) CreateBudget(ctx context.Context, userID, bookID string, params BudgetParams) error {
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleEditor,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return NewServiceError(ErrCodeUnprocessable, "book not found or access denied")
+	}
+
+	amount, err := s.checkBudgetParams(ctx, bookID, params)
+	if err != nil {
+		return err
+	}
+
+	currentTime := generator.NowISO8601()
+
+	_, err = queries.CreateBudget(ctx, repository.CreateBudgetParams{
+		ID:         generator.NewULID(),
+		BookID:     bookID,
+		CategoryID: nullableString(params.CategoryID),
+		Amount:     amount,
+		Period:     params.Period,
+		Rollover:   params.Rollover,
+		CreatedAt:  currentTime,
+		UpdatedAt:  currentTime,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to create budget: %v", err)
+	}
+
+	return nil
+}
+
+// GetBudgetsByBookID retrieves all budgets for a specific book.
+//
+// It returns an empty slice if no budgets are found in the book.
+func (s *EndpointService) GetBudgetsByBookID(ctx context.Context, userID, bookID string) ([]Budget, error) {
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleViewer,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeUnprocessable, "book not found or access denied")
+	}
+
+	budgets, err := queries.GetBudgetsByBookID(ctx, bookID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get budgets by book ID: %v", err)
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Budget, 0, len(budgets))
+	for _, budget := range budgets {
+		result = append(result, newBudget(budget, bookCurrency))
+	}
+
+	return result, nil
+}
+
+// GetBudgetByID retrieves a budget by its ID if the user has access to the
+// book.
+func (s *EndpointService) GetBudgetByID(ctx context.Context, userID, budgetID string) (*Budget, error) {
+	budget, err := s.getAccessibleBudget(ctx, userID, budgetID, BookRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, budget.BookID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newBudget(*budget, bookCurrency)
+	return &result, nil
+}
+
+// UpdateBudgetByID updates a budget if the user has access to the book and the
+// category.
+func (s *EndpointService) UpdateBudgetByID(ctx context.Context, userID, budgetID string, params BudgetParams) error {
+	budget, err := s.getAccessibleBudget(ctx, userID, budgetID, BookRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	amount, err := s.checkBudgetParams(ctx, budget.BookID, params)
+	if err != nil {
+		return err
+	}
+
+	queries := repository.New(s.db)
+
+	rows, err := queries.UpdateBudgetByID(ctx, repository.UpdateBudgetByIDParams{
+		ID:         budgetID,
+		CategoryID: nullableString(params.CategoryID),
+		Amount:     amount,
+		Period:     params.Period,
+		Rollover:   params.Rollover,
+		UpdatedAt:  generator.NowISO8601(),
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to update budget: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple budgets updated, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "no budget updated")
+	}
+
+	return nil
+}
+
+// DeleteBudgetByID deletes a budget if the user has access to the book.
+func (s *EndpointService) DeleteBudgetByID(ctx context.Context, userID, budgetID string) error {
+	if _, err := s.getAccessibleBudget(ctx, userID, budgetID, BookRoleEditor); err != nil {
+		return err
+	}
+
+	queries := repository.New(s.db)
+
+	rows, err := queries.DeleteBudgetByID(ctx, budgetID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete budget: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple budgets deleted, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "no budget deleted")
+	}
+
+	return nil
+}
+
+// BudgetPeriodStatus is the spending of a budget in a period.
+type BudgetPeriodStatus struct {
+	// Start and End are the inclusive dates of the period
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Rollover is the remaining amount carried over from the previous period,
+	// negative for an overspending
+	Rollover  money.Decimal `json:"rollover"`
+	Available money.Decimal `json:"available"`
+	Spent     money.Decimal `json:"spent"`
+	Remaining money.Decimal `json:"remaining"`
+	// PercentageUsed is the spent amount in percent of the available amount,
+	// null if nothing is available
+	PercentageUsed *float64 `json:"percentageUsed"`
+}
+
+type BudgetStatus struct {
+	Budget
+	// Periods are ordered from the oldest to the current period
+	Periods []BudgetPeriodStatus `json:"periods"`
+}
+
+// GetBudgetStatusesByBookID returns the spending of every budget of a book in
+// the current period and the previous ones, periods being the total number of
+// periods, if the user has access to the book.
+//
+// The spending of a budget of a category includes its subcategories. Rollover
+// starts from the period the budget was created in, the periods before it have
+// no rollover.
+func (s *EndpointService) GetBudgetStatusesByBookID(ctx context.Context, userID, bookID string, periods int) ([]BudgetStatus, error) {
+	budgets, err := s.GetBudgetsByBookID(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	bookScale := money.Scale(bookCurrency)
+	today := time.Now().UTC()
+
+	queries := repository.New(s.db)
+
+	statuses := make([]BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		budgetPeriods, err := getBudgetPeriods(ctx, queries, budget.Budget, today, periods)
+		if err != nil {
+			return nil, err
+		}
+
+		status := BudgetStatus{
+			Budget:  budget,
+			Periods: make([]BudgetPeriodStatus, 0, len(budgetPeriods)),
+		}
+
+		for _, period := range budgetPeriods {
+			var percentageUsed *float64
+			if period.Available > 0 {
+				percentage := math.Round(float64(period.Spent)/float64(period.Available)*10000) / 100
+				percentageUsed = &percentage
+			}
+
+			status.Periods = append(status.Periods, BudgetPeriodStatus{
+				Start:          period.Start.Format(time.DateOnly),
+				End:            period.End.Format(time.DateOnly),
+				Rollover:       money.New(period.Rollover, bookScale),
+				Available:      money.New(period.Available, bookScale),
+				Spent:          money.New(period.Spent, bookScale),
+				Remaining:      money.New(period.Available-period.Spent, bookScale),
+				PercentageUsed: percentageUsed,
+			})
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// budgetPeriod is the spending of a budget in a period, in minor units.
+type budgetPeriod struct {
+	// Start and End are the inclusive dates of the period
+	Start     time.Time
+	End       time.Time
+	Rollover  int64
+	Available int64
+	Spent     int64
+}
+
+// getBudgetPeriods returns the spending of a budget in the period containing
+// today and the previous ones, periods being the total number of periods,
+// oldest first.
+func getBudgetPeriods(ctx context.Context, queries *repository.Queries, budget repository.Budget, today time.Time, periods int) ([]budgetPeriod, error) {
+	createdAt, err := format.ISO8601ToTime(budget.CreatedAt)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to parse budget creation time: %v", err)
+	}
+
+	// The spending is needed from the creation to carry it over
+	currentStart := budgetPeriodStart(budget.Period, today)
+	firstStart := addBudgetPeriods(budget.Period, currentStart, 1-periods)
+	createdStart := budgetPeriodStart(budget.Period, createdAt)
+
+	from := firstStart
+	if budget.Rollover && createdStart.Before(from) {
+		from = createdStart
+	}
+
+	to := addBudgetPeriods(budget.Period, currentStart, 1).AddDate(0, 0, -1)
+
+	rows, err := queries.GetBudgetSpendingByDate(ctx, repository.GetBudgetSpendingByDateParams{
+		BookID:     budget.BookID,
+		CategoryID: derefString(budget.CategoryID),
+		From:       from.Format(time.DateOnly),
+		To:         to.Format(time.DateOnly),
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get budget spending: %v", err)
+	}
+
+	spent := map[time.Time]int64{}
+	for _, row := range rows {
+		date, err := time.Parse(time.DateOnly, row.Date)
+		if err != nil {
+			return nil, NewServiceErrorf(ErrCodeInternal, "failed to parse expense date: %v", err)
+		}
+		spent[budgetPeriodStart(budget.Period, date)] += row.Total
+	}
+
+	result := make([]budgetPeriod, 0, periods)
+
+	var rollover int64
+	for start := from; !start.After(currentStart); start = addBudgetPeriods(budget.Period, start, 1) {
+		period := budgetPeriod{
+			Start:     start,
+			End:       addBudgetPeriods(budget.Period, start, 1).AddDate(0, 0, -1),
+			Rollover:  rollover,
+			Available: budget.Amount + rollover,
+			Spent:     spent[start],
+		}
+
+		if budget.Rollover && !start.Before(createdStart) {
+			rollover = period.Available - period.Spent
+		}
+
+		if !start.Before(firstStart) {
+			result = append(result, period)
+		}
+	}
+
+	return result, nil
+}
+
+// BudgetAlert is an alert of a budget with its amounts as decimals in the book
+// currency and its delivery attempts.
+type BudgetAlert struct {
+	repository.BudgetAlert
+	Spent      money.Decimal                    `json:"spent"`
+	Available  money.Decimal                    `json:"available"`
+	Deliveries []repository.BudgetAlertDelivery `json:"deliveries"`
+}
+
+// GetBudgetAlertsByBudgetID retrieves the alerts of a budget with their
+// delivery attempts if the user has access to the book, the latest period
+// first.
+func (s *EndpointService) GetBudgetAlertsByBudgetID(ctx context.Context, userID, budgetID string) ([]BudgetAlert, error) {
+	budget, err := s.getAccessibleBudget(ctx, userID, budgetID, BookRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, budget.BookID)
+	if err != nil {
+		return nil, err
+	}
+
+	bookScale := money.Scale(bookCurrency)
+
+	queries := repository.New(s.db)
+
+	alerts, err := queries.GetBudgetAlertsByBudgetID(ctx, budgetID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get budget alerts: %v", err)
+	}
+
+	deliveries, err := queries.GetBudgetAlertDeliveriesByBudgetID(ctx, budgetID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get budget alert deliveries: %v", err)
+	}
+
+	deliveriesByAlertID := map[string][]repository.BudgetAlertDelivery{}
+	for _, delivery := range deliveries {
+		deliveriesByAlertID[delivery.BudgetAlertID] = append(deliveriesByAlertID[delivery.BudgetAlertID], delivery)
+	}
+
+	result := make([]BudgetAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		alertDeliveries := deliveriesByAlertID[alert.ID]
+		if alertDeliveries == nil {
+			alertDeliveries = []repository.BudgetAlertDelivery{}
+		}
+
+		result = append(result, BudgetAlert{
+			BudgetAlert: alert,
+			Spent:       money.New(alert.Spent, bookScale),
+			Available:   money.New(alert.Available, bookScale),
+			Deliveries:  alertDeliveries,
+		})
+	}
+
+	return result, nil
+}
+
+// CreateBudgetAlerts creates an alert for every threshold reached by the
+// budgets of a book in their current period, unless it has already been
+// created in the period. It does nothing if the alerts are not configured.
+//
+// It is called in the transaction writing the expenses, including the
+// occurrences of recurring expenses materialized by the cron job, so that the
+// alerts are only created if the expenses are, and they are delivered by the
+// cron job.
+func CreateBudgetAlerts(ctx context.Context, queries *repository.Queries, bookID string) error {
+	if env.BudgetAlertWebhookURL == "" || len(env.BudgetAlertThresholds) < 1 {
+		return nil
+	}
+
+	budgets, err := queries.GetBudgetsByBookID(ctx, bookID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to get budgets by book ID: %v", err)
+	}
+
+	today := time.Now().UTC()
+	currentTime := generator.NowISO8601()
+
+	for _, budget := range budgets {
+		periods, err := getBudgetPeriods(ctx, queries, budget, today, 1)
+		if err != nil {
+			return err
+		}
+
+		period := periods[len(periods)-1]
+		if period.Spent <= 0 {
+			continue
+		}
+
+		for _, threshold := range env.BudgetAlertThresholds {
+			// Everything spent is over the threshold if nothing is available
+			if period.Available > 0 && period.Spent*100 < threshold*period.Available {
+				continue
+			}
+
+			_, err := queries.CreateBudgetAlert(ctx, repository.CreateBudgetAlertParams{
+				ID:          generator.NewULID(),
+				BudgetID:    budget.ID,
+				PeriodStart: period.Start.Format(time.DateOnly),
+				PeriodEnd:   period.End.Format(time.DateOnly),
+				Threshold:   threshold,
+				Spent:       period.Spent,
+				Available:   period.Available,
+				CreatedAt:   currentTime,
+			})
+			if err != nil {
+				return NewServiceErrorf(ErrCodeInternal, "failed to create budget alert: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// budgetPeriodStart returns the first date of the period containing the date,
+// weeks starting on Monday.
+func budgetPeriodStart(period string, date time.Time) time.Time {
+	year, month, day := date.Date()
+
+	switch period {
+	case BudgetPeriodWeekly:
+		daysSinceMonday := (int(date.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+	case BudgetPeriodYearly:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// addBudgetPeriods returns the start of the period n periods after the period
+// starting at start.
+func addBudgetPeriods(period string, start time.Time, n int) time.Time {
+	switch period {
+	case BudgetPeriodWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case BudgetPeriodYearly:
+		return start.AddDate(n, 0, 0)
+	default:
+		return start.AddDate(0, n, 0)
+	}
+}
+
+// getAccessibleBudget retrieves a budget by its ID and checks if the user has
+// at least the role in its book.
+func (s *EndpointService) getAccessibleBudget(ctx context.Context, userID, budgetID, role string) (*repository.Budget, error) {
+	queries := repository.New(s.db)
+
+	budgets, err := queries.GetBudgetByID(ctx, budgetID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get budget by ID: %v", err)
+	}
+
+	if len(budgets) > 1 {
+		return nil, NewServiceError(ErrCodeInternal, "multiple budgets found with the same ID")
+	}
+
+	if len(budgets) < 1 {
+		return nil, NewServiceError(ErrCodeNotFound, "budget not found or access denied")
+	}
+
+	budget := budgets[0]
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: budget.BookID,
+		UserID: userID,
+		Role:   role,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeNotFound, "budget not found or access denied")
+	}
+
+	return &budget, nil
+}
+
+// checkBudgetParams checks the period, that the amount is positive and that
+// the category belongs to the book, and returns the amount in minor units.
+func (s *EndpointService) checkBudgetParams(ctx context.Context, bookID string, params BudgetParams) (int64, error) {
+	if !IsValidBudgetPeriod(params.Period) {
+		return 0, NewServiceError(ErrCodeUnprocessable, "invalid period")
+	}
+
+	amount, err := s.getBookMinorUnits(ctx, bookID, params.Amount)
+	if err != nil {
+		return 0, err
+	}
+
+	if amount <= 0 {
+		return 0, NewServiceError(ErrCodeUnprocessable, "amount must be positive")
+	}
+
+	if params.CategoryID == "" {
+		return amount, nil
+	}
+
+	queries := repository.New(s.db)
+
+	categories, err := queries.GetCategoryByID(ctx, params.CategoryID)
+	if err != nil {
+		return 0, NewServiceErrorf(ErrCodeInternal, "failed to get category by ID: %v", err)
+	}
+
+	if len(categories) > 1 {
+		return 0, NewServiceError(ErrCodeInternal, "multiple categories found with the same ID")
+	}
+
+	if len(categories) < 1 || categories[0].BookID != bookID {
+		return 0, NewServiceError(ErrCodeUnprocessable, "category not found or does not belong to the book")
+	}
+
+	return amount, nil
+}
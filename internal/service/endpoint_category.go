@@ -7,14 +7,16 @@ import (
 	"github.com/jljl1337/xpense/internal/repository"
 )
 
-// CreateCategory creates a new category if the user has access to the book.
-func (s *EndpointService) CreateCategory(ctx context.Context, userID, bookID, name, description string) error {
+// CreateCategory creates a new category if the user has access to the book,
+// under the parent category of the same book unless parentID is empty.
+func (s *EndpointService) CreateCategory(ctx context.Context, userID, bookID, name, description, parentID string) error {
 	queries := repository.New(s.db)
 
 	// Check if the user has access to the book
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: bookID,
 		UserID: userID,
+		Role:   BookRoleEditor,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -24,6 +26,10 @@ func (s *EndpointService) CreateCategory(ctx context.Context, userID, bookID, na
 		return NewServiceError(ErrCodeUnprocessable, "book not found or access denied")
 	}
 
+	if err := s.checkCategoryParent(ctx, bookID, "", parentID); err != nil {
+		return err
+	}
+
 	currentTime := generator.NowISO8601()
 
 	_, err = queries.CreateCategory(ctx, repository.CreateCategoryParams{
@@ -33,6 +39,7 @@ func (s *EndpointService) CreateCategory(ctx context.Context, userID, bookID, na
 		Description: description,
 		CreatedAt:   currentTime,
 		UpdatedAt:   currentTime,
+		ParentID:    nullableString(parentID),
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to create category: %v", err)
@@ -41,16 +48,26 @@ func (s *EndpointService) CreateCategory(ctx context.Context, userID, bookID, na
 	return nil
 }
 
-// GetCategoriesByBookID retrieves all categories for a specific book.
+// CategoryNode is a category with its subcategories.
+type CategoryNode struct {
+	repository.Category
+	Children []CategoryNode `json:"children"`
+}
+
+// GetCategoriesByBookID retrieves all categories for a specific book as a
+// tree, the root categories and the children of each category are ordered by
+// name. Archived categories are left out unless includeArchived is true, their
+// subcategories becoming roots.
 //
 // It returns an empty slice if no categories are found in the book.
-func (s *EndpointService) GetCategoriesByBookID(ctx context.Context, userID, bookID string) ([]repository.Category, error) {
+func (s *EndpointService) GetCategoriesByBookID(ctx context.Context, userID, bookID string, includeArchived bool) ([]CategoryNode, error) {
 	queries := repository.New(s.db)
 
 	// Check if the user has access to the book
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: bookID,
 		UserID: userID,
+		Role:   BookRoleViewer,
 	})
 	if err != nil {
 		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -60,16 +77,55 @@ func (s *EndpointService) GetCategoriesByBookID(ctx context.Context, userID, boo
 		return nil, NewServiceError(ErrCodeUnprocessable, "book not found or access denied")
 	}
 
-	categories, err := queries.GetCategoriesByBookID(ctx, bookID)
+	categories, err := queries.GetCategoriesByBookID(ctx, repository.GetCategoriesByBookIDParams{
+		BookID:          bookID,
+		IncludeArchived: includeArchived,
+	})
 	if err != nil {
 		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get categories by book ID: %v", err)
 	}
 
-	return categories, nil
+	// Group the categories by parent, the ones without a listed parent are roots
+	listed := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		listed[category.ID] = true
+	}
+
+	children := map[string][]repository.Category{}
+	roots := []repository.Category{}
+	for _, category := range categories {
+		if category.ParentID != nil && listed[*category.ParentID] {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+
+	return categoryTree(roots, children), nil
+}
+
+// categoryTree returns the nodes of the categories with their descendants, in
+// the order of the categories.
+func categoryTree(categories []repository.Category, children map[string][]repository.Category) []CategoryNode {
+	nodes := make([]CategoryNode, 0, len(categories))
+	for _, category := range categories {
+		nodes = append(nodes, CategoryNode{
+			Category: category,
+			Children: categoryTree(children[category.ID], children),
+		})
+	}
+
+	return nodes
 }
 
 // GetCategoryByID retrieves a category by its ID if the user has access to the book.
 func (s *EndpointService) GetCategoryByID(ctx context.Context, userID, categoryID string) (*repository.Category, error) {
+	return s.getAccessibleCategory(ctx, userID, categoryID, BookRoleViewer)
+}
+
+// getAccessibleCategory retrieves a category by its ID if the user has at
+// least the role in its book.
+func (s *EndpointService) getAccessibleCategory(ctx context.Context, userID, categoryID, role string) (*repository.Category, error) {
 	queries := repository.New(s.db)
 
 	// Get the category to find the book ID
@@ -92,6 +148,7 @@ func (s *EndpointService) GetCategoryByID(ctx context.Context, userID, categoryI
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: category.BookID,
 		UserID: userID,
+		Role:   role,
 	})
 	if err != nil {
 		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -105,26 +162,32 @@ func (s *EndpointService) GetCategoryByID(ctx context.Context, userID, categoryI
 }
 
 // UpdateCategoryByID updates a category if the user has access to the book.
-func (s *EndpointService) UpdateCategoryByID(ctx context.Context, userID, categoryID, name, description string) error {
-	queries := repository.New(s.db)
-
-	// Check if the user has access to the category
-	canAccess, err := queries.CheckCategoryAccess(ctx, repository.CheckCategoryAccessParams{
-		CategoryID: categoryID,
-		UserID:     userID,
-	})
+//
+// The category is moved under the parent category if parentID is not nil, or
+// to the root if it is empty. The parent cannot be the category itself or one
+// of its descendants.
+func (s *EndpointService) UpdateCategoryByID(ctx context.Context, userID, categoryID, name, description string, parentID *string) error {
+	category, err := s.getAccessibleCategory(ctx, userID, categoryID, BookRoleEditor)
 	if err != nil {
-		return NewServiceErrorf(ErrCodeInternal, "failed to check category access: %v", err)
+		return err
 	}
 
-	if !canAccess {
-		return NewServiceError(ErrCodeNotFound, "category not found or access denied")
+	newParentID := category.ParentID
+	if parentID != nil && *parentID != derefString(category.ParentID) {
+		if err := s.checkCategoryParent(ctx, category.BookID, categoryID, *parentID); err != nil {
+			return err
+		}
+
+		newParentID = nullableString(*parentID)
 	}
 
+	queries := repository.New(s.db)
+
 	rows, err := queries.UpdateCategoryByID(ctx, repository.UpdateCategoryByIDParams{
 		ID:          categoryID,
 		Name:        name,
 		Description: description,
+		ParentID:    newParentID,
 		UpdatedAt:   generator.NowISO8601(),
 	})
 	if err != nil {
@@ -142,14 +205,24 @@ func (s *EndpointService) UpdateCategoryByID(ctx context.Context, userID, catego
 	return nil
 }
 
-// DeleteCategoryByID deletes a category if the user has access to the book.
-func (s *EndpointService) DeleteCategoryByID(ctx context.Context, userID, categoryID string) error {
+// DeleteCategoryByID deletes a category if the user has access to the book, its
+// subcategories become root categories.
+//
+// The category cannot be deleted while it has expenses, recurring expenses or
+// budgets, unless reassignTo is not empty, in which case it is merged into the
+// reassignTo category instead.
+func (s *EndpointService) DeleteCategoryByID(ctx context.Context, userID, categoryID, reassignTo string) error {
+	if reassignTo != "" {
+		return s.MergeCategory(ctx, userID, categoryID, reassignTo)
+	}
+
 	queries := repository.New(s.db)
 
 	// Check if the user has access to the category
 	canAccess, err := queries.CheckCategoryAccess(ctx, repository.CheckCategoryAccessParams{
 		CategoryID: categoryID,
 		UserID:     userID,
+		Role:       BookRoleEditor,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to check category access: %v", err)
@@ -159,6 +232,24 @@ func (s *EndpointService) DeleteCategoryByID(ctx context.Context, userID, catego
 		return NewServiceError(ErrCodeNotFound, "category not found or access denied")
 	}
 
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries = repository.New(tx)
+
+	// Refuse to cascade the deletion to the expenses and budgets
+	count, err := queries.CountCategoryUsage(ctx, categoryID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to count category usage: %v", err)
+	}
+
+	if count > 0 {
+		return NewServiceErrorf(ErrCodeConflict, "category is used by %d expenses, recurring expenses or budgets, reassign them to another category first", count)
+	}
+
 	rows, err := queries.DeleteCategoryByID(ctx, categoryID)
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to delete category: %v", err)
@@ -172,5 +263,207 @@ func (s *EndpointService) DeleteCategoryByID(ctx context.Context, userID, catego
 		return NewServiceError(ErrCodeInternal, "no category deleted")
 	}
 
+	if err := tx.Commit(); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// MergeCategory moves the expenses, recurring expenses, budgets and
+// subcategories of a category to the target category of the same book, and deletes the category,
+// in a single transaction. The target cannot be the category itself or one of
+// its descendants.
+func (s *EndpointService) MergeCategory(ctx context.Context, userID, categoryID, targetID string) error {
+	category, err := s.getAccessibleCategory(ctx, userID, categoryID, BookRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	queries := repository.New(s.db)
+
+	targets, err := queries.GetCategoryByID(ctx, targetID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to get target category by ID: %v", err)
+	}
+
+	if len(targets) > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple categories found with the same ID")
+	}
+
+	if len(targets) < 1 || targets[0].BookID != category.BookID {
+		return NewServiceError(ErrCodeUnprocessable, "target category not found or does not belong to the book")
+	}
+
+	isDescendant, err := queries.CheckCategoryDescendant(ctx, repository.CheckCategoryDescendantParams{
+		CategoryID:   categoryID,
+		DescendantID: targetID,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to check category descendant: %v", err)
+	}
+
+	if isDescendant {
+		return NewServiceError(ErrCodeUnprocessable, "target category cannot be the category itself or one of its subcategories")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries = repository.New(tx)
+	currentTime := generator.NowISO8601()
+
+	// Move everything referencing the category before deleting it
+	_, err = queries.ReassignExpensesCategory(ctx, repository.ReassignExpensesCategoryParams{
+		FromID:    categoryID,
+		ToID:      targetID,
+		UpdatedAt: currentTime,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to reassign expenses: %v", err)
+	}
+
+	_, err = queries.ReassignRecurringExpensesCategory(ctx, repository.ReassignRecurringExpensesCategoryParams{
+		FromID:    categoryID,
+		ToID:      targetID,
+		UpdatedAt: currentTime,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to reassign recurring expenses: %v", err)
+	}
+
+	_, err = queries.ReassignBudgetsCategory(ctx, repository.ReassignBudgetsCategoryParams{
+		FromID:    categoryID,
+		ToID:      targetID,
+		UpdatedAt: currentTime,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to reassign budgets: %v", err)
+	}
+
+	_, err = queries.ReassignSubcategories(ctx, repository.ReassignSubcategoriesParams{
+		FromID:    categoryID,
+		ToID:      targetID,
+		UpdatedAt: currentTime,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to reassign subcategories: %v", err)
+	}
+
+	rows, err := queries.DeleteCategoryByID(ctx, categoryID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete category: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple categories deleted, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "no category deleted")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// ArchiveCategoryByID archives a category if the user has access to the book.
+//
+// An archived category is hidden from the listings and cannot be used by new
+// expenses, but it is kept for the existing ones.
+func (s *EndpointService) ArchiveCategoryByID(ctx context.Context, userID, categoryID string) error {
+	currentTime := generator.NowISO8601()
+	return s.setCategoryArchivedAt(ctx, userID, categoryID, &currentTime)
+}
+
+// UnarchiveCategoryByID unarchives a category if the user has access to the
+// book.
+func (s *EndpointService) UnarchiveCategoryByID(ctx context.Context, userID, categoryID string) error {
+	return s.setCategoryArchivedAt(ctx, userID, categoryID, nil)
+}
+
+// setCategoryArchivedAt archives or unarchives a category, nothing is changed
+// if it is already in that state.
+func (s *EndpointService) setCategoryArchivedAt(ctx context.Context, userID, categoryID string, archivedAt *string) error {
+	category, err := s.getAccessibleCategory(ctx, userID, categoryID, BookRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	if (category.ArchivedAt != nil) == (archivedAt != nil) {
+		return nil
+	}
+
+	queries := repository.New(s.db)
+
+	rows, err := queries.SetCategoryArchivedAt(ctx, repository.SetCategoryArchivedAtParams{
+		ID:         categoryID,
+		ArchivedAt: archivedAt,
+		UpdatedAt:  generator.NowISO8601(),
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to update category: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple categories updated, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "no category updated")
+	}
+
+	return nil
+}
+
+// checkCategoryParent checks that the parent category belongs to the book and
+// is neither the category nor one of its descendants, an empty parentID being
+// the root.
+func (s *EndpointService) checkCategoryParent(ctx context.Context, bookID, categoryID, parentID string) error {
+	if parentID == "" {
+		return nil
+	}
+
+	queries := repository.New(s.db)
+
+	parents, err := queries.GetCategoryByID(ctx, parentID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to get parent category by ID: %v", err)
+	}
+
+	if len(parents) > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple categories found with the same ID")
+	}
+
+	if len(parents) < 1 || parents[0].BookID != bookID {
+		return NewServiceError(ErrCodeUnprocessable, "parent category not found or does not belong to the book")
+	}
+
+	if parents[0].ArchivedAt != nil {
+		return NewServiceError(ErrCodeUnprocessable, "parent category is archived")
+	}
+
+	if categoryID == "" {
+		return nil
+	}
+
+	isDescendant, err := queries.CheckCategoryDescendant(ctx, repository.CheckCategoryDescendantParams{
+		CategoryID:   categoryID,
+		DescendantID: parentID,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to check category descendant: %v", err)
+	}
+
+	if isDescendant {
+		return NewServiceError(ErrCodeUnprocessable, "parent category cannot be the category itself or one of its subcategories")
+	}
+
 	return nil
 }
@@ -2,50 +2,304 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/money"
 	"github.com/jljl1337/xpense/internal/repository"
 )
 
-// CreateExpense creates a new expense if the user has access to the book,
-// category, and payment method.
-func (s *EndpointService) CreateExpense(ctx context.Context, userID, bookID, categoryID, paymentMethodID, date string, amount float64, remark string) error {
-	queries := repository.New(s.db)
+const (
+	ExpenseTypeExpense  = "expense"
+	ExpenseTypeIncome   = "income"
+	ExpenseTypeTransfer = "transfer"
+)
+
+// IsValidExpenseType reports whether expenseType is one of the entry types.
+func IsValidExpenseType(expenseType string) bool {
+	switch expenseType {
+	case ExpenseTypeExpense, ExpenseTypeIncome, ExpenseTypeTransfer:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValidExpenseDate reports whether date is a valid YYYY-MM-DD date.
+func IsValidExpenseDate(date string) bool {
+	_, err := time.Parse("2006-01-02", date)
+	return err == nil
+}
 
+// Expense is an expense, income or transfer with its amounts as decimals in
+// their currencies, instead of the minor units stored in the database.
+type Expense struct {
+	repository.Expense
+	Amount         money.Decimal  `json:"amount"`
+	OriginalAmount *money.Decimal `json:"originalAmount"`
+	TagIDs         []string       `json:"tagIDs"`
+}
+
+// newExpense returns the expense without its tags, which are set by
+// loadExpenseTags.
+func newExpense(expense repository.Expense, bookCurrency string) Expense {
+	result := Expense{
+		Expense: expense,
+		Amount:  money.New(expense.Amount, money.Scale(bookCurrency)),
+		TagIDs:  []string{},
+	}
+
+	if expense.OriginalCurrency != nil && expense.OriginalAmount != nil {
+		originalAmount := money.New(*expense.OriginalAmount, money.Scale(*expense.OriginalCurrency))
+		result.OriginalAmount = &originalAmount
+	}
+
+	return result
+}
+
+// ExpenseParams are the user provided fields of an expense, income or
+// transfer.
+type ExpenseParams struct {
+	CategoryID      string
+	PaymentMethodID string
+	Date            string
+	// Amount in the book currency, nil to convert it from the original amount
+	Amount *money.Decimal
+	Remark string
+	// Type empty keeps the current type and destination payment method on
+	// update
+	Type string
+	// DestinationPaymentMethodID is required for transfers, and must be empty
+	// otherwise
+	DestinationPaymentMethodID string
+	// OriginalCurrency and OriginalAmount are optional, for entries paid in a
+	// currency other than the book currency. OriginalCurrency nil keeps the
+	// current original currency and amount on update
+	OriginalCurrency *string
+	OriginalAmount   *money.Decimal
+	// TagIDs are the tags of the entry, nil keeps the current tags on update
+	TagIDs []string
+}
+
+// CreateExpense creates a new expense, income or transfer if the user has
+// access to the book, category, and payment methods.
+func (s *EndpointService) CreateExpense(ctx context.Context, userID, bookID string, params ExpenseParams) error {
 	// Check if the user has access to the book, category, and payment method
-	err := s.checkBookCategoryPaymentMethod(ctx, userID, bookID, categoryID, paymentMethodID)
+	err := s.checkBookCategoryPaymentMethod(ctx, userID, bookID, params.CategoryID, params.PaymentMethodID, "", "")
+	if err != nil {
+		return err
+	}
+
+	// Check if the tags belong to the book
+	if err := s.checkExpenseTags(ctx, bookID, params.TagIDs); err != nil {
+		return err
+	}
+
+	// Check the type and the destination payment method
+	err = s.checkExpenseTypeDestination(ctx, bookID, params.PaymentMethodID, params.Type, params.DestinationPaymentMethodID, "")
+	if err != nil {
+		return err
+	}
+
+	// Resolve the amounts in minor units
+	amount, originalAmount, err := s.resolveExpenseAmount(ctx, bookID, params)
 	if err != nil {
 		return err
 	}
 
-	// Create the expense
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	// Create the expense with its tags
+	expenseID := generator.NewULID()
 	currentTime := generator.NowISO8601()
 
 	_, err = queries.CreateExpense(ctx, repository.CreateExpenseParams{
-		ID:              generator.NewULID(),
-		BookID:          bookID,
-		CategoryID:      categoryID,
-		PaymentMethodID: paymentMethodID,
-		Date:            date,
-		Amount:          amount,
-		Remark:          remark,
-		CreatedAt:       currentTime,
-		UpdatedAt:       currentTime,
+		ID:                         expenseID,
+		BookID:                     bookID,
+		CategoryID:                 params.CategoryID,
+		PaymentMethodID:            params.PaymentMethodID,
+		Date:                       params.Date,
+		Amount:                     amount,
+		Remark:                     params.Remark,
+		CreatedAt:                  currentTime,
+		UpdatedAt:                  currentTime,
+		Type:                       params.Type,
+		DestinationPaymentMethodID: nullableString(params.DestinationPaymentMethodID),
+		OriginalCurrency:           nullableString(derefString(params.OriginalCurrency)),
+		OriginalAmount:             originalAmount,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to create expense: %v", err)
 	}
 
+	if err := setExpenseTags(ctx, queries, expenseID, params.TagIDs); err != nil {
+		return err
+	}
+
+	if err := CreateBudgetAlerts(ctx, queries, bookID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
 	return nil
 }
 
-func (s *EndpointService) GetExpensesCountByBookID(ctx context.Context, userID, bookID, categoryID, paymentMethodID, remark string) (int64, error) {
+// ExpenseFilter filters the expenses of a book, empty fields match everything.
+type ExpenseFilter struct {
+	CategoryID string
+	// PaymentMethodID matches both the payment method and the destination
+	// payment method of transfers
+	PaymentMethodID string
+	// Remark matches the expenses with remarks containing it
+	Remark string
+	Type   string
+	// DateFrom and DateTo are inclusive
+	DateFrom string
+	DateTo   string
+	// AmountMin and AmountMax are inclusive, nil matches everything
+	AmountMin *money.Decimal
+	AmountMax *money.Decimal
+	// Search matches the expenses with remarks containing all its terms, see
+	// searchQuery for the syntax
+	Search string
+	TagFilter
+}
+
+// params returns the repository parameters of the filter, with the amounts in
+// minor units of the book currency.
+func (f ExpenseFilter) params(bookID, bookCurrency string) (repository.ExpenseFilterParams, error) {
+	params := repository.ExpenseFilterParams{
+		BookID:          bookID,
+		CategoryID:      f.CategoryID,
+		PaymentMethodID: f.PaymentMethodID,
+		Remark:          f.Remark,
+		Type:            f.Type,
+		DateFrom:        f.DateFrom,
+		DateTo:          f.DateTo,
+		Search:          searchQuery(f.Search),
+		TagFilterParams: f.TagFilter.params(),
+	}
+
+	bookScale := money.Scale(bookCurrency)
+
+	if f.AmountMin != nil {
+		amountMin, err := f.AmountMin.MinorUnits(bookScale)
+		if err != nil {
+			return repository.ExpenseFilterParams{}, NewServiceErrorf(ErrCodeUnprocessable, "invalid minimum amount: %v", err)
+		}
+		params.AmountMin = &amountMin
+	}
+
+	if f.AmountMax != nil {
+		amountMax, err := f.AmountMax.MinorUnits(bookScale)
+		if err != nil {
+			return repository.ExpenseFilterParams{}, NewServiceErrorf(ErrCodeUnprocessable, "invalid maximum amount: %v", err)
+		}
+		params.AmountMax = &amountMax
+	}
+
+	return params, nil
+}
+
+// searchQuery converts a search to an FTS5 query matching the remarks with all
+// its terms, ignoring case and diacritics.
+//
+// Terms are separated by whitespace, double quotes group terms into a phrase,
+// and a trailing * matches a prefix, e.g. `"bus ticket" coff*`. All the other
+// characters are taken literally.
+func searchQuery(search string) string {
+	terms := []string{}
+
+	for search != "" {
+		search = strings.TrimLeftFunc(search, unicode.IsSpace)
+
+		var term string
+		if phrase, ok := strings.CutPrefix(search, `"`); ok {
+			end := strings.IndexByte(phrase, '"')
+			if end < 0 {
+				end = len(phrase)
+				search = ""
+			} else {
+				search = phrase[end+1:]
+			}
+			term = phrase[:end]
+		} else {
+			end := strings.IndexFunc(search, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+			if end < 0 {
+				end = len(search)
+			}
+			term, search = search[:end], search[end:]
+		}
+
+		prefix := false
+		if rest, ok := strings.CutPrefix(search, "*"); ok {
+			prefix, search = true, rest
+		}
+		if trimmed, ok := strings.CutSuffix(term, "*"); ok {
+			prefix, term = true, trimmed
+		}
+
+		if strings.TrimSpace(term) == "" {
+			continue
+		}
+
+		term = `"` + term + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+const (
+	ExpenseSortDate      = "date"
+	ExpenseSortAmount    = "amount"
+	ExpenseSortCreated   = "created"
+	ExpenseSortRelevance = "relevance"
+)
+
+func IsValidExpenseSort(sort string) bool {
+	switch sort {
+	case ExpenseSortDate, ExpenseSortAmount, ExpenseSortCreated, ExpenseSortRelevance:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExpenseSort is the order of the listed expenses, ties are broken by the date,
+// the last update time and the ID.
+type ExpenseSort struct {
+	// By is one of date, amount, created or relevance, the relevance to the
+	// search of the filter. It defaults to date
+	By        string
+	Ascending bool
+}
+
+func (s *EndpointService) GetExpensesCountByBookID(ctx context.Context, userID, bookID string, filter ExpenseFilter) (int64, error) {
 	queries := repository.New(s.db)
 
 	// Check if the user has access to the book
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: bookID,
 		UserID: userID,
+		Role:   BookRoleViewer,
 	})
 	if err != nil {
 		return 0, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -55,12 +309,17 @@ func (s *EndpointService) GetExpensesCountByBookID(ctx context.Context, userID,
 		return 0, NewServiceError(ErrCodeNotFound, "book not found or access denied")
 	}
 
-	countResult, err := queries.GetExpenseCountByBookID(ctx, repository.GetExpenseCountByBookIDParams{
-		BookID:          bookID,
-		CategoryID:      categoryID,
-		PaymentMethodID: paymentMethodID,
-		Remark:          remark,
-	})
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return 0, err
+	}
+
+	filterParams, err := filter.params(bookID, bookCurrency)
+	if err != nil {
+		return 0, err
+	}
+
+	countResult, err := queries.GetExpenseCountByBookID(ctx, filterParams)
 	if err != nil {
 		return 0, NewServiceErrorf(ErrCodeInternal, "failed to get expenses count: %v", err)
 	}
@@ -71,13 +330,14 @@ func (s *EndpointService) GetExpensesCountByBookID(ctx context.Context, userID,
 // GetExpensesByBookID retrieves all expenses for a specific book with pagination.
 //
 // It returns an empty slice if no expenses are found in the book.
-func (s *EndpointService) GetExpensesByBookID(ctx context.Context, userID, bookID, categoryID, paymentMethodID, remark string, page int64, pageSize int64) ([]repository.Expense, error) {
+func (s *EndpointService) GetExpensesByBookID(ctx context.Context, userID, bookID string, filter ExpenseFilter, sort ExpenseSort, page int64, pageSize int64) ([]Expense, error) {
 	queries := repository.New(s.db)
 
 	// Check if the user has access to the book
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: bookID,
 		UserID: userID,
+		Role:   BookRoleViewer,
 	})
 	if err != nil {
 		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -87,25 +347,162 @@ func (s *EndpointService) GetExpensesByBookID(ctx context.Context, userID, bookI
 		return nil, NewServiceError(ErrCodeUnprocessable, "book not found or access denied")
 	}
 
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	filterParams, err := filter.params(bookID, bookCurrency)
+	if err != nil {
+		return nil, err
+	}
+
 	offset := (page - 1) * pageSize
 	limit := pageSize
 	expenses, err := queries.GetExpensesByBookID(ctx, repository.GetExpensesByBookIDParams{
-		BookID:          bookID,
-		CategoryID:      categoryID,
-		PaymentMethodID: paymentMethodID,
-		Remark:          remark,
-		Offset:          offset,
-		Limit:           limit,
+		ExpenseFilterParams: filterParams,
+		Offset:              offset,
+		Limit:               limit,
+		Sort:                sort.By,
+		Ascending:           sort.Ascending,
 	})
 	if err != nil {
 		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get expenses by book ID: %v", err)
 	}
 
-	return expenses, nil
+	result := make([]Expense, 0, len(expenses))
+	for _, expense := range expenses {
+		result = append(result, newExpense(expense, bookCurrency))
+	}
+
+	if err := loadExpenseTags(ctx, queries, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+type ExpensePage struct {
+	Expenses []Expense `json:"expenses"`
+	// NextCursor is null on the last page
+	NextCursor *string `json:"nextCursor"`
+}
+
+// expenseCursor is the position of an expense in the order of date, last
+// update time and ID, encoded as base64 JSON to be opaque to clients.
+type expenseCursor struct {
+	Date      string `json:"d"`
+	UpdatedAt string `json:"u"`
+	ID        string `json:"i"`
+}
+
+func encodeExpenseCursor(expense repository.Expense) string {
+	data, _ := json.Marshal(expenseCursor{
+		Date:      expense.Date,
+		UpdatedAt: expense.UpdatedAt,
+		ID:        expense.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeExpenseCursor(cursor string) (expenseCursor, error) {
+	var decoded expenseCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return expenseCursor{}, err
+	}
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return expenseCursor{}, err
+	}
+
+	if decoded.ID == "" {
+		return expenseCursor{}, errors.New("missing ID")
+	}
+
+	// Otherwise the cursor would be before or after every expense
+	if !IsValidExpenseDate(decoded.Date) || decoded.UpdatedAt == "" {
+		return expenseCursor{}, errors.New("invalid date or update time")
+	}
+
+	return decoded, nil
+}
+
+// GetExpensesByBookIDAfterCursor retrieves a page of the expenses of a book
+// ordered by date, after the cursor returned with the previous page, or the
+// first page if the cursor is empty.
+func (s *EndpointService) GetExpensesByBookIDAfterCursor(ctx context.Context, userID, bookID string, filter ExpenseFilter, ascending bool, cursor string, pageSize int64) (*ExpensePage, error) {
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleViewer,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeUnprocessable, "book not found or access denied")
+	}
+
+	var after expenseCursor
+	if cursor != "" {
+		after, err = decodeExpenseCursor(cursor)
+		if err != nil {
+			return nil, NewServiceError(ErrCodeBadRequest, "invalid cursor")
+		}
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	filterParams, err := filter.params(bookID, bookCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get one more expense to know if there is a next page
+	expenses, err := queries.GetExpensesByBookIDAfterCursor(ctx, repository.GetExpensesByBookIDAfterCursorParams{
+		ExpenseFilterParams: filterParams,
+		CursorDate:          after.Date,
+		CursorUpdatedAt:     after.UpdatedAt,
+		CursorID:            after.ID,
+		Limit:               pageSize + 1,
+		Ascending:           ascending,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get expenses by book ID: %v", err)
+	}
+
+	page := &ExpensePage{
+		Expenses: make([]Expense, 0, len(expenses)),
+	}
+
+	if int64(len(expenses)) > pageSize {
+		expenses = expenses[:pageSize]
+		nextCursor := encodeExpenseCursor(expenses[len(expenses)-1])
+		page.NextCursor = &nextCursor
+	}
+
+	for _, expense := range expenses {
+		page.Expenses = append(page.Expenses, newExpense(expense, bookCurrency))
+	}
+
+	if err := loadExpenseTags(ctx, queries, page.Expenses); err != nil {
+		return nil, err
+	}
+
+	return page, nil
 }
 
 // GetExpenseByID retrieves an expense by its ID if the user has access to the book.
-func (s *EndpointService) GetExpenseByID(ctx context.Context, userID, expenseID string) (*repository.Expense, error) {
+func (s *EndpointService) GetExpenseByID(ctx context.Context, userID, expenseID string) (*Expense, error) {
 	queries := repository.New(s.db)
 
 	expenses, err := queries.GetExpenseByID(ctx, expenseID)
@@ -127,6 +524,7 @@ func (s *EndpointService) GetExpenseByID(ctx context.Context, userID, expenseID
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: expense.BookID,
 		UserID: userID,
+		Role:   BookRoleViewer,
 	})
 	if err != nil {
 		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -136,12 +534,22 @@ func (s *EndpointService) GetExpenseByID(ctx context.Context, userID, expenseID
 		return nil, NewServiceError(ErrCodeNotFound, "expense not found or access denied")
 	}
 
-	return &expense, nil
+	bookCurrency, err := s.getBookCurrency(ctx, expense.BookID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Expense{newExpense(expense, bookCurrency)}
+	if err := loadExpenseTags(ctx, queries, result); err != nil {
+		return nil, err
+	}
+
+	return &result[0], nil
 }
 
-// UpdateExpense updates an existing expense if the user has access to the book,
-// category, and payment method.
-func (s *EndpointService) UpdateExpense(ctx context.Context, userID, expenseID, categoryID, paymentMethodID, date string, amount float64, remark string) error {
+// UpdateExpense updates an existing expense, income or transfer if the user has
+// access to the book, category, and payment methods.
+func (s *EndpointService) UpdateExpense(ctx context.Context, userID, expenseID string, params ExpenseParams) error {
 	queries := repository.New(s.db)
 
 	// Get the expense to find the book ID
@@ -160,21 +568,65 @@ func (s *EndpointService) UpdateExpense(ctx context.Context, userID, expenseID,
 
 	expense := expenses[0]
 
+	// Keep the current type and original amount if they are not given
+	if params.Type == "" {
+		params.Type = expense.Type
+		params.DestinationPaymentMethodID = derefString(expense.DestinationPaymentMethodID)
+	}
+
+	if params.OriginalCurrency == nil {
+		params.OriginalCurrency = expense.OriginalCurrency
+		params.OriginalAmount = nil
+		if expense.OriginalCurrency != nil && expense.OriginalAmount != nil {
+			originalAmount := money.New(*expense.OriginalAmount, money.Scale(*expense.OriginalCurrency))
+			params.OriginalAmount = &originalAmount
+		}
+	}
+
 	// Check if the user has access to the book, category, and payment method
-	err = s.checkBookCategoryPaymentMethod(ctx, userID, expense.BookID, categoryID, paymentMethodID)
+	err = s.checkBookCategoryPaymentMethod(ctx, userID, expense.BookID, params.CategoryID, params.PaymentMethodID, expense.CategoryID, expense.PaymentMethodID)
 	if err != nil {
 		return err
 	}
 
-	// Update the expense
+	// Check if the tags belong to the book
+	if err := s.checkExpenseTags(ctx, expense.BookID, params.TagIDs); err != nil {
+		return err
+	}
+
+	// Check the type and the destination payment method
+	err = s.checkExpenseTypeDestination(ctx, expense.BookID, params.PaymentMethodID, params.Type, params.DestinationPaymentMethodID, derefString(expense.DestinationPaymentMethodID))
+	if err != nil {
+		return err
+	}
+
+	// Resolve the amounts in minor units
+	amount, originalAmount, err := s.resolveExpenseAmount(ctx, expense.BookID, params)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries = repository.New(tx)
+
+	// Update the expense and its tags
 	rows, err := queries.UpdateExpenseByID(ctx, repository.UpdateExpenseByIDParams{
-		ID:              expenseID,
-		CategoryID:      categoryID,
-		PaymentMethodID: paymentMethodID,
-		Date:            date,
-		Amount:          amount,
-		Remark:          remark,
-		UpdatedAt:       generator.NowISO8601(),
+		ID:                         expenseID,
+		CategoryID:                 params.CategoryID,
+		PaymentMethodID:            params.PaymentMethodID,
+		Date:                       params.Date,
+		Amount:                     amount,
+		Remark:                     params.Remark,
+		Type:                       params.Type,
+		UpdatedAt:                  generator.NowISO8601(),
+		DestinationPaymentMethodID: nullableString(params.DestinationPaymentMethodID),
+		OriginalCurrency:           nullableString(derefString(params.OriginalCurrency)),
+		OriginalAmount:             originalAmount,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to update expense: %v", err)
@@ -188,6 +640,20 @@ func (s *EndpointService) UpdateExpense(ctx context.Context, userID, expenseID,
 		return NewServiceError(ErrCodeInternal, "expense not updated")
 	}
 
+	if params.TagIDs != nil {
+		if err := setExpenseTags(ctx, queries, expenseID, params.TagIDs); err != nil {
+			return err
+		}
+	}
+
+	if err := CreateBudgetAlerts(ctx, queries, expense.BookID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
 	return nil
 }
 
@@ -216,6 +682,7 @@ func (s *EndpointService) DeleteExpenseByID(ctx context.Context, userID, expense
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: expense.BookID,
 		UserID: userID,
+		Role:   BookRoleEditor,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -245,14 +712,17 @@ func (s *EndpointService) DeleteExpenseByID(ctx context.Context, userID, expense
 // checkBookCategoryPaymentMethod checks if the user has access to the book,
 // category, and payment method.
 //
-// It also checks if the category and payment method belong to the book.
-func (s *EndpointService) checkBookCategoryPaymentMethod(ctx context.Context, userID, bookID, categoryID, paymentMethodID string) error {
+// It also checks if the category and payment method belong to the book, and
+// are not archived unless they are the current ones of the entry being
+// updated, which are empty for a new entry.
+func (s *EndpointService) checkBookCategoryPaymentMethod(ctx context.Context, userID, bookID, categoryID, paymentMethodID, currentCategoryID, currentPaymentMethodID string) error {
 	queries := repository.New(s.db)
 
 	// Check if the user has access to the book
 	canAccessBook, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: bookID,
 		UserID: userID,
+		Role:   BookRoleEditor,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -282,6 +752,10 @@ func (s *EndpointService) checkBookCategoryPaymentMethod(ctx context.Context, us
 		return NewServiceError(ErrCodeUnprocessable, "category does not belong to the book")
 	}
 
+	if category.ArchivedAt != nil && categoryID != currentCategoryID {
+		return NewServiceError(ErrCodeUnprocessable, "category is archived")
+	}
+
 	// Check if the payment method belongs to the book
 	paymentMethod, err := queries.GetPaymentMethodByID(ctx, paymentMethodID)
 	if err != nil {
@@ -302,5 +776,131 @@ func (s *EndpointService) checkBookCategoryPaymentMethod(ctx context.Context, us
 		return NewServiceError(ErrCodeUnprocessable, "payment method does not belong to the book")
 	}
 
+	if pm.ArchivedAt != nil && paymentMethodID != currentPaymentMethodID {
+		return NewServiceError(ErrCodeUnprocessable, "payment method is archived")
+	}
+
 	return nil
 }
+
+// checkExpenseTypeDestination checks the type of an entry, and that the
+// destination payment method of a transfer belongs to the book and differs
+// from the source payment method. The destination cannot be archived unless it
+// is the current one of the entry being updated, which is empty for a new
+// entry.
+func (s *EndpointService) checkExpenseTypeDestination(ctx context.Context, bookID, paymentMethodID, expenseType, destinationPaymentMethodID, currentDestinationPaymentMethodID string) error {
+	queries := repository.New(s.db)
+
+	if !IsValidExpenseType(expenseType) {
+		return NewServiceError(ErrCodeUnprocessable, "invalid type")
+	}
+
+	if expenseType != ExpenseTypeTransfer {
+		if destinationPaymentMethodID != "" {
+			return NewServiceError(ErrCodeUnprocessable, "destination payment method is only allowed for transfers")
+		}
+		return nil
+	}
+
+	if destinationPaymentMethodID == "" {
+		return NewServiceError(ErrCodeUnprocessable, "destination payment method is required for transfers")
+	}
+
+	if destinationPaymentMethodID == paymentMethodID {
+		return NewServiceError(ErrCodeUnprocessable, "destination payment method must be different from the payment method")
+	}
+
+	paymentMethods, err := queries.GetPaymentMethodByID(ctx, destinationPaymentMethodID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to get payment method by ID: %v", err)
+	}
+
+	if len(paymentMethods) > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple payment methods found with the same ID")
+	}
+
+	if len(paymentMethods) < 1 {
+		return NewServiceError(ErrCodeUnprocessable, "destination payment method not found or access denied")
+	}
+
+	if paymentMethods[0].BookID != bookID {
+		return NewServiceError(ErrCodeUnprocessable, "destination payment method does not belong to the book")
+	}
+
+	if paymentMethods[0].ArchivedAt != nil && destinationPaymentMethodID != currentDestinationPaymentMethodID {
+		return NewServiceError(ErrCodeUnprocessable, "destination payment method is archived")
+	}
+
+	return nil
+}
+
+// resolveExpenseAmount returns the amount of an entry in minor units of the
+// book currency, and the original amount in minor units of the original
+// currency if any.
+//
+// If the amount is not given, it is converted from the original amount with
+// the exchange rate of the entry date.
+func (s *EndpointService) resolveExpenseAmount(ctx context.Context, bookID string, params ExpenseParams) (int64, *int64, error) {
+	originalCurrency := derefString(params.OriginalCurrency)
+
+	if originalCurrency == "" && params.OriginalAmount != nil {
+		return 0, nil, NewServiceError(ErrCodeUnprocessable, "original currency is required with an original amount")
+	}
+
+	if originalCurrency == "" && params.Amount == nil {
+		return 0, nil, NewServiceError(ErrCodeUnprocessable, "amount is required")
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	bookScale := money.Scale(bookCurrency)
+
+	if originalCurrency == "" {
+		amount, err := params.Amount.MinorUnits(bookScale)
+		if err != nil {
+			return 0, nil, NewServiceErrorf(ErrCodeUnprocessable, "invalid amount: %v", err)
+		}
+		return amount, nil, nil
+	}
+
+	currencyValid, err := checkCurrency(originalCurrency)
+	if err != nil {
+		return 0, nil, NewServiceErrorf(ErrCodeInternal, "failed to validate original currency: %v", err)
+	}
+	if !currencyValid {
+		return 0, nil, NewServiceError(ErrCodeUnprocessable, "invalid original currency format")
+	}
+
+	if params.OriginalAmount == nil {
+		return 0, nil, NewServiceError(ErrCodeUnprocessable, "original amount is required with an original currency")
+	}
+
+	originalAmount, err := params.OriginalAmount.MinorUnits(money.Scale(originalCurrency))
+	if err != nil {
+		return 0, nil, NewServiceErrorf(ErrCodeUnprocessable, "invalid original amount: %v", err)
+	}
+
+	amountDecimal := params.Amount
+	if amountDecimal == nil {
+		rate, err := s.getExchangeRate(ctx, originalCurrency, bookCurrency, params.Date)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		converted, err := params.OriginalAmount.MulRat(rate, bookScale)
+		if err != nil {
+			return 0, nil, NewServiceErrorf(ErrCodeUnprocessable, "invalid converted amount: %v", err)
+		}
+		amountDecimal = &converted
+	}
+
+	amount, err := amountDecimal.MinorUnits(bookScale)
+	if err != nil {
+		return 0, nil, NewServiceErrorf(ErrCodeUnprocessable, "invalid amount: %v", err)
+	}
+
+	return amount, &originalAmount, nil
+}
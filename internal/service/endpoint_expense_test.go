@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jljl1337/xpense/internal/db"
+	"github.com/jljl1337/xpense/internal/money"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+// newTestDB returns a migrated database in a temporary directory, skipping the
+// test if SQLite is built without FTS5.
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	database, err := db.NewDB(filepath.Join(t.TempDir(), "db.db"), "5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	if err := db.Migrate(database); err != nil {
+		if strings.Contains(err.Error(), "fts5") {
+			t.Skip("SQLite is built without FTS5, run the tests with -tags sqlite_fts5")
+		}
+		t.Fatal(err)
+	}
+
+	return database
+}
+
+func TestExpenseCursor(t *testing.T) {
+	expense := repository.Expense{
+		ID:        "01HZY0000000000000000000AA",
+		Date:      "2024-03-01",
+		UpdatedAt: "2024-03-01T10:00:00.000Z",
+	}
+
+	decoded, err := decodeExpenseCursor(encodeExpenseCursor(expense))
+	if err != nil {
+		t.Fatalf("decodeExpenseCursor returned error: %v", err)
+	}
+
+	want := expenseCursor{Date: expense.Date, UpdatedAt: expense.UpdatedAt, ID: expense.ID}
+	if decoded != want {
+		t.Errorf("decodeExpenseCursor = %+v, want %+v", decoded, want)
+	}
+
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	for _, cursor := range []string{
+		"not base64!",
+		base64.StdEncoding.EncodeToString([]byte(`{"d":"2024-03-01","u":"x","i":"a"}`)),
+		encode(""),
+		encode("null"),
+		encode("[]"),
+		encode(`{"d":"2024-03-01","u":"x"`),
+		encode(`{"d":"2024-03-01","u":"x","i":"a"} {}`),
+		encode(`{"d":"2024-03-01","u":"x","i":1}`),
+		encode(`{"d":"2024-03-01","u":"x"}`),
+		encode(`{"d":"2024-03-01","u":"x","i":""}`),
+		encode(`{"u":"x","i":"a"}`),
+		encode(`{"d":"2024-13-01","u":"x","i":"a"}`),
+		encode(`{"d":"2024-03-01","i":"a"}`),
+	} {
+		if decoded, err := decodeExpenseCursor(cursor); err == nil {
+			t.Errorf("decodeExpenseCursor(%q) = %+v, want error", cursor, decoded)
+		}
+	}
+}
+
+func TestGetExpensesByBookIDAfterCursor(t *testing.T) {
+	database := newTestDB(t)
+	s := NewEndpointService(database, []byte("key"))
+	ctx := context.Background()
+
+	if err := s.SignUp(ctx, "alice", "password1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var userID, bookID, categoryID, paymentMethodID string
+	mustGet := func(dest *string, query string, args ...any) {
+		t.Helper()
+		if err := database.Get(dest, query, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mustGet(&userID, "SELECT id FROM user")
+	if err := s.CreateBook(ctx, userID, "Book", "", "USD"); err != nil {
+		t.Fatal(err)
+	}
+	mustGet(&bookID, "SELECT id FROM book")
+	if err := s.CreateCategory(ctx, userID, bookID, "Food", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	mustGet(&categoryID, "SELECT id FROM category")
+	if err := s.CreatePaymentMethod(ctx, userID, bookID, "Cash", "", PaymentMethodKindAsset); err != nil {
+		t.Fatal(err)
+	}
+	mustGet(&paymentMethodID, "SELECT id FROM payment_method")
+
+	createExpense := func(date string) {
+		t.Helper()
+
+		amount := money.New(100, 2)
+		if err := s.CreateExpense(ctx, userID, bookID, ExpenseParams{
+			CategoryID:      categoryID,
+			PaymentMethodID: paymentMethodID,
+			Date:            date,
+			Amount:          &amount,
+			Type:            ExpenseTypeExpense,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Most expenses share the same date and update time, so that only the IDs
+	// order them
+	for range 7 {
+		createExpense("2024-03-02")
+	}
+	createExpense("2024-03-01")
+	createExpense("2024-03-03")
+
+	if _, err := database.Exec("UPDATE expense SET updated_at = '2024-03-05T00:00:00.000Z'"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec("UPDATE expense SET updated_at = '2024-03-06T00:00:00.000Z' WHERE id = (SELECT MAX(id) FROM expense WHERE date = '2024-03-02')"); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	if err := database.Select(&want, "SELECT id FROM expense ORDER BY date, updated_at, id"); err != nil {
+		t.Fatal(err)
+	}
+
+	readAll := func(ascending bool, pageSize int64) []string {
+		t.Helper()
+
+		ids := []string{}
+		cursor := ""
+		for range 20 {
+			page, err := s.GetExpensesByBookIDAfterCursor(ctx, userID, bookID, ExpenseFilter{}, ascending, cursor, pageSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if int64(len(page.Expenses)) > pageSize {
+				t.Errorf("page of %d expenses, want at most %d", len(page.Expenses), pageSize)
+			}
+
+			for _, expense := range page.Expenses {
+				ids = append(ids, expense.ID)
+			}
+
+			if page.NextCursor == nil {
+				return ids
+			}
+			cursor = *page.NextCursor
+		}
+
+		t.Fatal("too many pages")
+		return nil
+	}
+
+	for _, pageSize := range []int64{1, 2, 3, int64(len(want)), int64(len(want)) + 1} {
+		if got := readAll(true, pageSize); !slices.Equal(got, want) {
+			t.Errorf("ascending pages of %d = %q, want %q", pageSize, got, want)
+		}
+
+		reversed := slices.Clone(want)
+		slices.Reverse(reversed)
+		if got := readAll(false, pageSize); !slices.Equal(got, reversed) {
+			t.Errorf("descending pages of %d = %q, want %q", pageSize, got, reversed)
+		}
+	}
+
+	// A malformed cursor is a bad request rather than the first page
+	_, err := s.GetExpensesByBookIDAfterCursor(ctx, userID, bookID, ExpenseFilter{}, false, "bm90IGEgY3Vyc29y", 2)
+	if serviceErr, ok := err.(*ServiceError); !ok || serviceErr.Code != ErrCodeBadRequest {
+		t.Errorf("GetExpensesByBookIDAfterCursor with malformed cursor returned %v, want bad request", err)
+	}
+}
+
+func TestUpdateExpenseKeepsMissingFields(t *testing.T) {
+	database := newTestDB(t)
+	s := NewEndpointService(database, []byte("key"))
+	ctx := context.Background()
+
+	if err := s.SignUp(ctx, "alice", "password1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var userID, bookID, categoryID, paymentMethodID, expenseID string
+	mustGet := func(dest *string, query string, args ...any) {
+		t.Helper()
+		if err := database.Get(dest, query, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mustGet(&userID, "SELECT id FROM user")
+	if err := s.CreateBook(ctx, userID, "Book", "", "USD"); err != nil {
+		t.Fatal(err)
+	}
+	mustGet(&bookID, "SELECT id FROM book")
+	if err := s.CreateCategory(ctx, userID, bookID, "Salary", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	mustGet(&categoryID, "SELECT id FROM category")
+	if err := s.CreatePaymentMethod(ctx, userID, bookID, "Cash", "", PaymentMethodKindAsset); err != nil {
+		t.Fatal(err)
+	}
+	mustGet(&paymentMethodID, "SELECT id FROM payment_method")
+
+	amount := money.New(745, 2)
+	originalAmount := money.New(1000, 0)
+	if err := s.CreateExpense(ctx, userID, bookID, ExpenseParams{
+		CategoryID:       categoryID,
+		PaymentMethodID:  paymentMethodID,
+		Date:             "2024-03-01",
+		Amount:           &amount,
+		Type:             ExpenseTypeIncome,
+		OriginalCurrency: ptr("JPY"),
+		OriginalAmount:   &originalAmount,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	mustGet(&expenseID, "SELECT id FROM expense")
+
+	// An update without the type and the original amount keeps them
+	amount = money.New(800, 2)
+	if err := s.UpdateExpense(ctx, userID, expenseID, ExpenseParams{
+		CategoryID:      categoryID,
+		PaymentMethodID: paymentMethodID,
+		Date:            "2024-03-02",
+		Amount:          &amount,
+		Remark:          "edited",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expense, err := s.GetExpenseByID(ctx, userID, expenseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expense.Type != ExpenseTypeIncome || expense.Amount != amount || expense.Remark != "edited" {
+		t.Errorf("UpdateExpense = %s %s %q, want %s %s %q", expense.Type, expense.Amount, expense.Remark, ExpenseTypeIncome, amount, "edited")
+	}
+
+	if derefString(expense.OriginalCurrency) != "JPY" || expense.OriginalAmount == nil || *expense.OriginalAmount != originalAmount {
+		t.Errorf("UpdateExpense original amount = %v %v, want JPY %s", expense.OriginalCurrency, expense.OriginalAmount, originalAmount)
+	}
+
+	// An empty original currency removes the original amount
+	if err := s.UpdateExpense(ctx, userID, expenseID, ExpenseParams{
+		CategoryID:       categoryID,
+		PaymentMethodID:  paymentMethodID,
+		Date:             "2024-03-02",
+		Amount:           &amount,
+		OriginalCurrency: ptr(""),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expense, err = s.GetExpenseByID(ctx, userID, expenseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expense.OriginalCurrency != nil || expense.OriginalAmount != nil {
+		t.Errorf("UpdateExpense original amount = %v %v, want none", expense.OriginalCurrency, expense.OriginalAmount)
+	}
+}
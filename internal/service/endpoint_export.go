@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"io"
+	"iter"
+
+	"github.com/jljl1337/xpense/internal/journal"
+	"github.com/jljl1337/xpense/internal/money"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+// ExportedExpense is an expense with the names of its category and payment
+// methods, and its amounts as decimals in their currencies.
+type ExportedExpense struct {
+	ID                         string         `json:"id"`
+	Date                       string         `json:"date"`
+	Type                       string         `json:"type"`
+	CategoryID                 string         `json:"categoryID"`
+	Category                   string         `json:"category"`
+	PaymentMethodID            string         `json:"paymentMethodID"`
+	PaymentMethod              string         `json:"paymentMethod"`
+	DestinationPaymentMethodID *string        `json:"destinationPaymentMethodID"`
+	DestinationPaymentMethod   *string        `json:"destinationPaymentMethod"`
+	Amount                     money.Decimal  `json:"amount"`
+	Currency                   string         `json:"currency"`
+	OriginalCurrency           *string        `json:"originalCurrency"`
+	OriginalAmount             *money.Decimal `json:"originalAmount"`
+	Remark                     string         `json:"remark"`
+}
+
+// ExportExpenses returns an iterator over all the filtered expenses of a book
+// in chronological order if the user has access to the book.
+//
+// The expenses are read from the database while iterating, so that books of
+// any size can be exported without loading them into memory.
+func (s *EndpointService) ExportExpenses(ctx context.Context, userID, bookID string, filter ExpenseFilter) (iter.Seq2[ExportedExpense, error], error) {
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleViewer,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeNotFound, "book not found or access denied")
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	filterParams, err := filter.params(bookID, bookCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	bookScale := money.Scale(bookCurrency)
+
+	return func(yield func(ExportedExpense, error) bool) {
+		for row, err := range queries.GetExpenseExportRowsByBookID(ctx, filterParams) {
+			if err != nil {
+				yield(ExportedExpense{}, NewServiceErrorf(ErrCodeInternal, "failed to get expenses for export: %v", err))
+				return
+			}
+
+			expense := ExportedExpense{
+				ID:                         row.ID,
+				Date:                       row.Date,
+				Type:                       row.Type,
+				CategoryID:                 row.CategoryID,
+				Category:                   row.CategoryName,
+				PaymentMethodID:            row.PaymentMethodID,
+				PaymentMethod:              row.PaymentMethodName,
+				DestinationPaymentMethodID: row.DestinationPaymentMethodID,
+				DestinationPaymentMethod:   row.DestinationPaymentMethodName,
+				Amount:                     money.New(row.Amount, bookScale),
+				Currency:                   bookCurrency,
+				OriginalCurrency:           row.OriginalCurrency,
+				Remark:                     row.Remark,
+			}
+
+			if row.OriginalCurrency != nil && row.OriginalAmount != nil {
+				originalAmount := money.New(*row.OriginalAmount, money.Scale(*row.OriginalCurrency))
+				expense.OriginalAmount = &originalAmount
+			}
+
+			if !yield(expense, nil) {
+				return
+			}
+		}
+	}, nil
+}
+
+// ExportJournal returns a function writing all the filtered expenses of a book
+// as a plain text accounting journal if the user has access to the book.
+//
+// Categories are mapped to expense and income accounts, and payment methods to
+// asset or liability accounts depending on their kind.
+func (s *EndpointService) ExportJournal(ctx context.Context, userID, bookID string, filter ExpenseFilter, format journal.Format) (func(w io.Writer) error, error) {
+	expenses, err := s.ExportExpenses(ctx, userID, bookID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := repository.New(s.db)
+
+	books, err := queries.GetBookByID(ctx, bookID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get book by ID: %v", err)
+	}
+
+	if len(books) > 1 {
+		return nil, NewServiceError(ErrCodeInternal, "multiple books found with the same ID")
+	}
+
+	if len(books) < 1 {
+		return nil, NewServiceError(ErrCodeNotFound, "book not found")
+	}
+
+	book := books[0]
+
+	categories, err := queries.GetCategoriesByBookID(ctx, repository.GetCategoriesByBookIDParams{
+		BookID:          bookID,
+		IncludeArchived: true,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get categories by book ID: %v", err)
+	}
+
+	paymentMethods, err := queries.GetPaymentMethodsByBookID(ctx, repository.GetPaymentMethodsByBookIDParams{
+		BookID:          bookID,
+		IncludeArchived: true,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get payment methods by book ID: %v", err)
+	}
+
+	journalCategories := make([]journal.Category, 0, len(categories))
+	for _, category := range categories {
+		journalCategories = append(journalCategories, journal.Category{ID: category.ID, Name: category.Name})
+	}
+
+	journalPaymentMethods := make([]journal.PaymentMethod, 0, len(paymentMethods))
+	for _, paymentMethod := range paymentMethods {
+		journalPaymentMethods = append(journalPaymentMethods, journal.PaymentMethod{
+			ID:        paymentMethod.ID,
+			Name:      paymentMethod.Name,
+			Liability: paymentMethod.Kind == PaymentMethodKindLiability,
+		})
+	}
+
+	accounts := journal.NewAccounts(format, journalCategories, journalPaymentMethods)
+
+	return func(w io.Writer) error {
+		writer := journal.NewWriter(w, format)
+
+		if err := writer.WriteHeader(book.Name, book.Currency, accounts.All()); err != nil {
+			return err
+		}
+
+		for expense, err := range expenses {
+			if err != nil {
+				return err
+			}
+
+			transaction := journal.Transaction{
+				Date:               expense.Date,
+				Description:        expense.Remark,
+				DestinationAccount: accounts.Expense(expense.CategoryID),
+				SourceAccount:      accounts.PaymentMethod(expense.PaymentMethodID),
+				Amount:             expense.Amount,
+				Currency:           expense.Currency,
+				OriginalAmount:     expense.OriginalAmount,
+			}
+
+			if expense.OriginalCurrency != nil {
+				transaction.OriginalCurrency = *expense.OriginalCurrency
+			}
+
+			switch expense.Type {
+			case ExpenseTypeIncome:
+				transaction.DestinationAccount = accounts.PaymentMethod(expense.PaymentMethodID)
+				transaction.SourceAccount = accounts.Income(expense.CategoryID)
+			case ExpenseTypeTransfer:
+				if expense.DestinationPaymentMethodID != nil {
+					transaction.DestinationAccount = accounts.PaymentMethod(*expense.DestinationPaymentMethodID)
+				}
+			}
+
+			if err := writer.WriteTransaction(transaction); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, nil
+}
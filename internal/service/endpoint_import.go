@@ -0,0 +1,549 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/importer"
+	"github.com/jljl1337/xpense/internal/money"
+	"github.com/jljl1337/xpense/internal/ofx"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+type ImportResult struct {
+	DryRun                bool             `json:"dryRun"`
+	Imported              int              `json:"imported"`
+	CreatedCategories     []string         `json:"createdCategories"`
+	CreatedPaymentMethods []string         `json:"createdPaymentMethods"`
+	Errors                []ImportRowError `json:"errors"`
+}
+
+// ImportExpenses creates an expense for every row if the user has access to
+// the book.
+//
+// Categories are matched by their full path like "Food > Groceries", or by
+// their name if no other category has it, and payment methods by name. They are
+// created if they do not exist yet, a category with its missing ancestors.
+// Every row is validated first, and nothing is imported if any row is invalid
+// or if it is a dry run, the returned result lists the errors of all invalid
+// rows.
+func (s *EndpointService) ImportExpenses(ctx context.Context, userID, bookID string, rows []importer.Row, dryRun bool) (*ImportResult, error) {
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleEditor,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeNotFound, "book not found or access denied")
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{
+		DryRun:                dryRun,
+		CreatedCategories:     []string{},
+		CreatedPaymentMethods: []string{},
+		Errors:                []ImportRowError{},
+	}
+
+	// Validate every row before touching the database
+	amounts := make([]int64, len(rows))
+	for i, row := range rows {
+		amount, err := checkImportRow(row, bookCurrency)
+		if err != nil {
+			result.Errors = append(result.Errors, ImportRowError{Line: row.Line, Message: err.Error()})
+			continue
+		}
+		amounts[i] = amount
+	}
+
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries = repository.New(tx)
+
+	// Archived categories and payment methods are matched too, so that importing
+	// old expenses does not create duplicates of them
+	categories, err := queries.GetCategoriesByBookID(ctx, repository.GetCategoriesByBookIDParams{
+		BookID:          bookID,
+		IncludeArchived: true,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get categories by book ID: %v", err)
+	}
+
+	categoryIDs := newImportCategories(categories)
+
+	// Subcategory names are not unique, so the rows naming several categories
+	// are invalid
+	for _, row := range rows {
+		categoryID, err := categoryIDs.match(row.Category)
+		if err == nil && categoryID == "" {
+			_, _, err = categoryIDs.parent(row.Category)
+		}
+
+		if err != nil {
+			result.Errors = append(result.Errors, ImportRowError{Line: row.Line, Message: err.Error()})
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	paymentMethods, err := queries.GetPaymentMethodsByBookID(ctx, repository.GetPaymentMethodsByBookIDParams{
+		BookID:          bookID,
+		IncludeArchived: true,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get payment methods by book ID: %v", err)
+	}
+
+	paymentMethodIDs := make(map[string]string, len(paymentMethods))
+	for _, paymentMethod := range paymentMethods {
+		paymentMethodIDs[paymentMethod.Name] = paymentMethod.ID
+	}
+
+	for i, row := range rows {
+		currentTime := generator.NowISO8601()
+
+		// Create the category and payment method if they do not exist yet. A
+		// name can become ambiguous with the categories created by the
+		// previous rows
+		categoryID, err := categoryIDs.match(row.Category)
+		if err != nil {
+			result.Errors = append(result.Errors, ImportRowError{Line: row.Line, Message: err.Error()})
+			continue
+		}
+
+		if categoryID == "" {
+			parentID, existing, err := categoryIDs.parent(row.Category)
+			if err != nil {
+				result.Errors = append(result.Errors, ImportRowError{Line: row.Line, Message: err.Error()})
+				continue
+			}
+
+			// Create the missing categories of the path, each under the
+			// previous one
+			names := strings.Split(row.Category, importCategoryPathSeparator)
+			for j := existing; j < len(names); j++ {
+				categoryID = generator.NewULID()
+				_, err := queries.CreateCategory(ctx, repository.CreateCategoryParams{
+					ID:          categoryID,
+					BookID:      bookID,
+					Name:        names[j],
+					Description: "",
+					CreatedAt:   currentTime,
+					UpdatedAt:   currentTime,
+					ParentID:    nullableString(parentID),
+				})
+				if err != nil {
+					return nil, NewServiceErrorf(ErrCodeInternal, "failed to create category: %v", err)
+				}
+
+				path := strings.Join(names[:j+1], importCategoryPathSeparator)
+				categoryIDs.add(path, names[j], categoryID)
+				result.CreatedCategories = append(result.CreatedCategories, path)
+				parentID = categoryID
+			}
+		}
+
+		paymentMethodID, ok := paymentMethodIDs[row.PaymentMethod]
+		if !ok {
+			paymentMethodID = generator.NewULID()
+			_, err := queries.CreatePaymentMethod(ctx, repository.CreatePaymentMethodParams{
+				ID:          paymentMethodID,
+				BookID:      bookID,
+				Name:        row.PaymentMethod,
+				Description: "",
+				CreatedAt:   currentTime,
+				UpdatedAt:   currentTime,
+				Kind:        PaymentMethodKindAsset,
+			})
+			if err != nil {
+				return nil, NewServiceErrorf(ErrCodeInternal, "failed to create payment method: %v", err)
+			}
+
+			paymentMethodIDs[row.PaymentMethod] = paymentMethodID
+			result.CreatedPaymentMethods = append(result.CreatedPaymentMethods, row.PaymentMethod)
+		}
+
+		_, err = queries.CreateExpense(ctx, repository.CreateExpenseParams{
+			ID:              generator.NewULID(),
+			BookID:          bookID,
+			CategoryID:      categoryID,
+			PaymentMethodID: paymentMethodID,
+			Date:            row.Date,
+			Amount:          amounts[i],
+			Remark:          row.Remark,
+			CreatedAt:       currentTime,
+			UpdatedAt:       currentTime,
+			Type:            ExpenseTypeExpense,
+		})
+		if err != nil {
+			return nil, NewServiceErrorf(ErrCodeInternal, "failed to create expense: %v", err)
+		}
+
+		result.Imported++
+	}
+
+	// Nothing is imported if a row became invalid
+	if len(result.Errors) > 0 {
+		return &ImportResult{
+			DryRun:                dryRun,
+			CreatedCategories:     []string{},
+			CreatedPaymentMethods: []string{},
+			Errors:                result.Errors,
+		}, nil
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := CreateBudgetAlerts(ctx, queries, bookID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return result, nil
+}
+
+// importCategoryPathSeparator separates the names of a category and its
+// ancestors in its full path.
+const importCategoryPathSeparator = " > "
+
+// importCategories are the IDs of the categories of a book by full path and by
+// name.
+type importCategories struct {
+	paths map[string][]string
+	names map[string][]string
+}
+
+func newImportCategories(categories []repository.Category) *importCategories {
+	byID := make(map[string]repository.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	c := &importCategories{
+		paths: make(map[string][]string, len(categories)),
+		names: make(map[string][]string, len(categories)),
+	}
+
+	for _, category := range categories {
+		// The length is bounded in case of a cycle
+		names := []string{category.Name}
+		parentID := category.ParentID
+		for parentID != nil && len(names) <= len(categories) {
+			parent, ok := byID[*parentID]
+			if !ok {
+				break
+			}
+			names = append(names, parent.Name)
+			parentID = parent.ParentID
+		}
+		slices.Reverse(names)
+
+		c.add(strings.Join(names, importCategoryPathSeparator), category.Name, category.ID)
+	}
+
+	return c
+}
+
+func (c *importCategories) add(path, name, id string) {
+	c.paths[path] = append(c.paths[path], id)
+	c.names[name] = append(c.names[name], id)
+}
+
+// match returns the ID of the category with the full path or name given, or
+// an empty ID if there is none. It is an error if several categories match.
+func (c *importCategories) match(category string) (string, error) {
+	ids := c.paths[category]
+	if len(ids) == 0 {
+		ids = c.names[category]
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", nil
+	case 1:
+		return ids[0], nil
+	}
+
+	paths := []string{}
+	for path, pathIDs := range c.paths {
+		for _, id := range pathIDs {
+			if slices.Contains(ids, id) {
+				paths = append(paths, strconv.Quote(path))
+			}
+		}
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+
+	if len(paths) < 2 {
+		return "", fmt.Errorf("category %q matches several categories with the same full path", category)
+	}
+
+	return "", fmt.Errorf("category %q is ambiguous, use one of the full paths %s", category, strings.Join(paths, ", "))
+}
+
+// parent returns the ID of the closest existing ancestor in the full path of a
+// category that does not exist, and the number of names of the path that
+// exist. The ID is empty if none of the ancestors exist. It is an error if
+// several categories have the full path of the ancestor.
+func (c *importCategories) parent(category string) (string, int, error) {
+	names := strings.Split(category, importCategoryPathSeparator)
+	if slices.Contains(names, "") {
+		return "", 0, fmt.Errorf("category %q has an empty name in its path", category)
+	}
+
+	for i := len(names) - 1; i > 0; i-- {
+		path := strings.Join(names[:i], importCategoryPathSeparator)
+
+		switch ids := c.paths[path]; len(ids) {
+		case 0:
+			continue
+		case 1:
+			return ids[0], i, nil
+		default:
+			return "", 0, fmt.Errorf("category %q matches several categories with the same full path", path)
+		}
+	}
+
+	return "", 0, nil
+}
+
+// checkImportRow validates an imported row with the same rules as creating an
+// expense, and returns the amount in minor units of the book currency.
+func checkImportRow(row importer.Row, bookCurrency string) (int64, error) {
+	if row.Category == "" || row.PaymentMethod == "" {
+		return 0, errors.New("category and payment method are required")
+	}
+
+	if !IsValidExpenseDate(row.Date) {
+		return 0, errors.New("date must be a valid YYYY-MM-DD")
+	}
+
+	if row.Amount == "" {
+		return 0, errors.New("amount is required")
+	}
+
+	amount, err := money.Parse(row.Amount)
+	if err != nil {
+		return 0, err
+	}
+
+	minorUnits, err := amount.MinorUnits(money.Scale(bookCurrency))
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount: %v", err)
+	}
+
+	return minorUnits, nil
+}
+
+type OFXImportTransaction struct {
+	FITID  string        `json:"fitID"`
+	Date   string        `json:"date"`
+	Type   string        `json:"type"`
+	Amount money.Decimal `json:"amount"`
+	Remark string        `json:"remark"`
+	// ExpenseID is the ID of the created expense for new transactions, and of
+	// the existing expense otherwise
+	ExpenseID string `json:"expenseID"`
+}
+
+type OFXImportResult struct {
+	DryRun      bool                   `json:"dryRun"`
+	New         []OFXImportTransaction `json:"new"`
+	Skipped     []OFXImportTransaction `json:"skipped"`
+	Conflicting []OFXImportTransaction `json:"conflicting"`
+}
+
+// ImportOFX creates an expense for every new transaction of a bank statement
+// if the user has access to the book, category, and payment method.
+//
+// Debits are imported as expenses and credits as incomes, in the category and
+// payment method given. The FITID of the transactions is kept, transactions
+// already imported to the payment method are skipped if they are unchanged, and
+// reported as conflicting otherwise. Nothing is imported if it is a dry run.
+func (s *EndpointService) ImportOFX(ctx context.Context, userID, bookID, categoryID, paymentMethodID string, statement *ofx.Statement, dryRun bool) (*OFXImportResult, error) {
+	// Check if the user has access to the book, category, and payment method
+	err := s.checkBookCategoryPaymentMethod(ctx, userID, bookID, categoryID, paymentMethodID, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OFXImportResult{
+		DryRun:      dryRun,
+		New:         []OFXImportTransaction{},
+		Skipped:     []OFXImportTransaction{},
+		Conflicting: []OFXImportTransaction{},
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	for _, transaction := range statement.Transactions {
+		if !IsValidExpenseDate(transaction.Date) {
+			return nil, NewServiceErrorf(ErrCodeUnprocessable, "invalid date of transaction %s", transaction.FITID)
+		}
+
+		item := OFXImportTransaction{
+			FITID:  transaction.FITID,
+			Date:   transaction.Date,
+			Type:   ExpenseTypeIncome,
+			Amount: transaction.Amount.Abs(),
+			Remark: ofxRemark(transaction),
+		}
+
+		if transaction.Amount.IsNegative() {
+			item.Type = ExpenseTypeExpense
+		}
+
+		// Amounts in another currency are converted to the book currency
+		params := ExpenseParams{
+			CategoryID:      categoryID,
+			PaymentMethodID: paymentMethodID,
+			Date:            item.Date,
+			Amount:          &item.Amount,
+			Remark:          item.Remark,
+			Type:            item.Type,
+		}
+
+		if statement.Currency != "" && statement.Currency != bookCurrency {
+			params.Amount = nil
+			params.OriginalCurrency = &statement.Currency
+			params.OriginalAmount = &item.Amount
+		}
+
+		amount, originalAmount, err := s.resolveExpenseAmount(ctx, bookID, params)
+		if err != nil {
+			return nil, err
+		}
+
+		// Check if the transaction has been imported before
+		existingExpenses, err := queries.GetExpenseByExternalID(ctx, repository.GetExpenseByExternalIDParams{
+			PaymentMethodID: paymentMethodID,
+			ExternalID:      transaction.FITID,
+		})
+		if err != nil {
+			return nil, NewServiceErrorf(ErrCodeInternal, "failed to get expense by external ID: %v", err)
+		}
+
+		if len(existingExpenses) > 1 {
+			return nil, NewServiceError(ErrCodeInternal, "multiple expenses found with the same external ID")
+		}
+
+		if len(existingExpenses) == 1 {
+			existing := existingExpenses[0]
+			item.ExpenseID = existing.ID
+
+			// Converted amounts depend on the rates, so the original amount is
+			// compared instead if any
+			sameAmount := existing.Amount == amount
+			if originalAmount != nil {
+				sameAmount = existing.OriginalAmount != nil && *existing.OriginalAmount == *originalAmount
+			}
+
+			if existing.Date == item.Date && existing.Type == item.Type && sameAmount {
+				result.Skipped = append(result.Skipped, item)
+			} else {
+				result.Conflicting = append(result.Conflicting, item)
+			}
+			continue
+		}
+
+		currentTime := generator.NowISO8601()
+		item.ExpenseID = generator.NewULID()
+
+		_, err = queries.CreateExpense(ctx, repository.CreateExpenseParams{
+			ID:               item.ExpenseID,
+			BookID:           bookID,
+			CategoryID:       categoryID,
+			PaymentMethodID:  paymentMethodID,
+			Date:             item.Date,
+			Amount:           amount,
+			Remark:           item.Remark,
+			CreatedAt:        currentTime,
+			UpdatedAt:        currentTime,
+			Type:             item.Type,
+			OriginalCurrency: params.OriginalCurrency,
+			OriginalAmount:   originalAmount,
+			ExternalID:       &transaction.FITID,
+		})
+		if err != nil {
+			return nil, NewServiceErrorf(ErrCodeInternal, "failed to create expense: %v", err)
+		}
+
+		result.New = append(result.New, item)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := CreateBudgetAlerts(ctx, queries, bookID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return result, nil
+}
+
+// ofxRemark returns the name and the memo of a transaction as a remark.
+func ofxRemark(transaction ofx.Transaction) string {
+	if transaction.Memo == "" || transaction.Memo == transaction.Name {
+		return transaction.Name
+	}
+
+	if transaction.Name == "" {
+		return transaction.Memo
+	}
+
+	return transaction.Name + " - " + transaction.Memo
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/jljl1337/xpense/internal/importer"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+func TestImportCategoriesMatch(t *testing.T) {
+	categories := newImportCategories([]repository.Category{
+		{ID: "food", Name: "Food"},
+		{ID: "drinks", Name: "Drinks"},
+		{ID: "food-snacks", Name: "Snacks", ParentID: ptr("food")},
+		{ID: "drinks-snacks", Name: "Snacks", ParentID: ptr("drinks")},
+		{ID: "groceries", Name: "Groceries", ParentID: ptr("food")},
+		{ID: "fruit", Name: "Fruit", ParentID: ptr("groceries")},
+		{ID: "travel", Name: "Travel"},
+		{ID: "travel-2", Name: "Travel"},
+		{ID: "rent", Name: "Rent"},
+		{ID: "home-rent", Name: "Rent", ParentID: ptr("home")},
+		{ID: "home", Name: "Home"},
+	})
+
+	tests := []struct {
+		category string
+		want     string
+		wantErr  bool
+	}{
+		{category: "Food", want: "food"},
+		{category: "Groceries", want: "groceries"},
+		{category: "Food > Groceries", want: "groceries"},
+		{category: "Food > Groceries > Fruit", want: "fruit"},
+		{category: "Fruit", want: "fruit"},
+		{category: "Food > Snacks", want: "food-snacks"},
+		{category: "Drinks > Snacks", want: "drinks-snacks"},
+		{category: "Snacks", wantErr: true},
+		{category: "Travel", wantErr: true},
+		// The full path of the root category wins over the subcategory name
+		{category: "Rent", want: "rent"},
+		{category: "Home > Rent", want: "home-rent"},
+		{category: "Groceries > Fruit", want: ""},
+		{category: "Unknown", want: ""},
+	}
+
+	for _, tt := range tests {
+		got, err := categories.match(tt.category)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("match(%q) = %q, want error", tt.category, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("match(%q) returned error: %v", tt.category, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("match(%q) = %q, want %q", tt.category, got, tt.want)
+		}
+	}
+
+	// Categories created during the import are matched by name
+	categories.add("Books", "Books", "books")
+	if got, err := categories.match("Books"); err != nil || got != "books" {
+		t.Errorf("match(%q) = %q, %v, want %q", "Books", got, err, "books")
+	}
+}
+
+func TestImportCategoriesParent(t *testing.T) {
+	categories := newImportCategories([]repository.Category{
+		{ID: "food", Name: "Food"},
+		{ID: "groceries", Name: "Groceries", ParentID: ptr("food")},
+		{ID: "travel", Name: "Travel"},
+		{ID: "travel-2", Name: "Travel"},
+	})
+
+	tests := []struct {
+		category     string
+		wantParentID string
+		wantExisting int
+		wantErr      bool
+	}{
+		{category: "Books", wantParentID: "", wantExisting: 0},
+		{category: "Books > Comics", wantParentID: "", wantExisting: 0},
+		{category: "Food > Snacks", wantParentID: "food", wantExisting: 1},
+		{category: "Food > Groceries > Fruit", wantParentID: "groceries", wantExisting: 2},
+		{category: "Food > Groceries > Fruit > Apples", wantParentID: "groceries", wantExisting: 2},
+		// Paths start from a root category
+		{category: "Groceries > Fruit", wantParentID: "", wantExisting: 0},
+		{category: "Travel > Flights", wantErr: true},
+		{category: "Food >  > Fruit", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		parentID, existing, err := categories.parent(tt.category)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parent(%q) = %q, %d, want error", tt.category, parentID, existing)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parent(%q) returned error: %v", tt.category, err)
+			continue
+		}
+
+		if parentID != tt.wantParentID || existing != tt.wantExisting {
+			t.Errorf("parent(%q) = %q, %d, want %q, %d", tt.category, parentID, existing, tt.wantParentID, tt.wantExisting)
+		}
+	}
+}
+
+func TestImportExpensesCategoryPaths(t *testing.T) {
+	database := newTestDB(t)
+	s := NewEndpointService(database, []byte("key"))
+	ctx := context.Background()
+
+	if err := s.SignUp(ctx, "alice", "password1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var userID, bookID string
+	if err := database.Get(&userID, "SELECT id FROM user"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateBook(ctx, userID, "Book", "", "USD"); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Get(&bookID, "SELECT id FROM book"); err != nil {
+		t.Fatal(err)
+	}
+
+	row := func(line int, category string) importer.Row {
+		return importer.Row{Line: line, Date: "2024-03-01", Amount: "1.00", Category: category, PaymentMethod: "Cash"}
+	}
+
+	result, err := s.ImportExpenses(ctx, userID, bookID, []importer.Row{
+		row(2, "Food > Groceries"),
+		row(3, "Food"),
+		row(4, "Groceries"),
+		row(5, "Food > Groceries > Fruit"),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Errors) > 0 || result.Imported != 4 {
+		t.Fatalf("ImportExpenses = %d imported, errors %+v", result.Imported, result.Errors)
+	}
+
+	if want := []string{"Food", "Food > Groceries", "Food > Groceries > Fruit"}; !slices.Equal(result.CreatedCategories, want) {
+		t.Errorf("ImportExpenses created categories %q, want %q", result.CreatedCategories, want)
+	}
+
+	categories, err := repository.New(database).GetCategoriesByBookID(ctx, repository.GetCategoriesByBookIDParams{BookID: bookID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rows of a path are in the created subcategory, which can be
+	// matched by its name or path by the next imports
+	paths := newImportCategories(categories)
+	for path, count := range map[string]int{"Food": 1, "Food > Groceries": 2, "Food > Groceries > Fruit": 1} {
+		id, err := paths.match(path)
+		if err != nil || id == "" {
+			t.Errorf("match(%q) = %q, %v, want a category", path, id, err)
+			continue
+		}
+
+		var got int
+		if err := database.Get(&got, "SELECT COUNT(*) FROM expense WHERE category_id = ?", id); err != nil {
+			t.Fatal(err)
+		}
+		if got != count {
+			t.Errorf("%d expenses in %q, want %d", got, path, count)
+		}
+	}
+
+	if len(categories) != 3 {
+		t.Errorf("%d categories, want 3", len(categories))
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -7,14 +7,31 @@ import (
 	"github.com/jljl1337/xpense/internal/repository"
 )
 
+const (
+	PaymentMethodKindAsset     = "asset"
+	PaymentMethodKindLiability = "liability"
+)
+
+// IsValidPaymentMethodKind reports whether kind is one of the payment method
+// kinds, e.g. a bank account is an asset and a credit card is a liability.
+func IsValidPaymentMethodKind(kind string) bool {
+	switch kind {
+	case PaymentMethodKindAsset, PaymentMethodKindLiability:
+		return true
+	default:
+		return false
+	}
+}
+
 // CreatePaymentMethod creates a new payment method if the user has access to the book.
-func (s *EndpointService) CreatePaymentMethod(ctx context.Context, userID, bookID, name, description string) error {
+func (s *EndpointService) CreatePaymentMethod(ctx context.Context, userID, bookID, name, description, kind string) error {
 	queries := repository.New(s.db)
 
 	// Check if the user has access to the book
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: bookID,
 		UserID: userID,
+		Role:   BookRoleEditor,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -33,6 +50,7 @@ func (s *EndpointService) CreatePaymentMethod(ctx context.Context, userID, bookI
 		Description: description,
 		CreatedAt:   currentTime,
 		UpdatedAt:   currentTime,
+		Kind:        kind,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to create payment method: %v", err)
@@ -41,16 +59,18 @@ func (s *EndpointService) CreatePaymentMethod(ctx context.Context, userID, bookI
 	return nil
 }
 
-// GetPaymentMethodsByBookID retrieves all payment methods for a specific book.
+// GetPaymentMethodsByBookID retrieves all payment methods for a specific book,
+// archived ones are left out unless includeArchived is true.
 //
 // It returns an empty slice if no payment methods are found in the book.
-func (s *EndpointService) GetPaymentMethodsByBookID(ctx context.Context, userID, bookID string) ([]repository.PaymentMethod, error) {
+func (s *EndpointService) GetPaymentMethodsByBookID(ctx context.Context, userID, bookID string, includeArchived bool) ([]repository.PaymentMethod, error) {
 	queries := repository.New(s.db)
 
 	// Check if the user has access to the book
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: bookID,
 		UserID: userID,
+		Role:   BookRoleViewer,
 	})
 	if err != nil {
 		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -60,7 +80,10 @@ func (s *EndpointService) GetPaymentMethodsByBookID(ctx context.Context, userID,
 		return nil, NewServiceError(ErrCodeUnprocessable, "book not found or access denied")
 	}
 
-	paymentMethods, err := queries.GetPaymentMethodsByBookID(ctx, bookID)
+	paymentMethods, err := queries.GetPaymentMethodsByBookID(ctx, repository.GetPaymentMethodsByBookIDParams{
+		BookID:          bookID,
+		IncludeArchived: includeArchived,
+	})
 	if err != nil {
 		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get payment methods by book ID: %v", err)
 	}
@@ -70,6 +93,12 @@ func (s *EndpointService) GetPaymentMethodsByBookID(ctx context.Context, userID,
 
 // GetPaymentMethodByID retrieves a payment method by its ID if the user has access to the book.
 func (s *EndpointService) GetPaymentMethodByID(ctx context.Context, userID, paymentMethodID string) (*repository.PaymentMethod, error) {
+	return s.getAccessiblePaymentMethod(ctx, userID, paymentMethodID, BookRoleViewer)
+}
+
+// getAccessiblePaymentMethod retrieves a payment method by its ID if the user
+// has at least the role in its book.
+func (s *EndpointService) getAccessiblePaymentMethod(ctx context.Context, userID, paymentMethodID, role string) (*repository.PaymentMethod, error) {
 	queries := repository.New(s.db)
 
 	// Get the payment method to find the book ID
@@ -92,6 +121,7 @@ func (s *EndpointService) GetPaymentMethodByID(ctx context.Context, userID, paym
 	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
 		BookID: paymentMethod.BookID,
 		UserID: userID,
+		Role:   role,
 	})
 	if err != nil {
 		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
@@ -105,13 +135,16 @@ func (s *EndpointService) GetPaymentMethodByID(ctx context.Context, userID, paym
 }
 
 // UpdatePaymentMethodByID updates a payment method if the user has access to the book.
-func (s *EndpointService) UpdatePaymentMethodByID(ctx context.Context, userID, paymentMethodID, name, description string) error {
+//
+// An empty kind keeps the current kind.
+func (s *EndpointService) UpdatePaymentMethodByID(ctx context.Context, userID, paymentMethodID, name, description, kind string) error {
 	queries := repository.New(s.db)
 
 	// Check if the user has access to the payment method
 	canAccess, err := queries.CheckPaymentMethodAccess(ctx, repository.CheckPaymentMethodAccessParams{
 		PaymentMethodID: paymentMethodID,
 		UserID:          userID,
+		Role:            BookRoleEditor,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to check payment method access: %v", err)
@@ -125,6 +158,7 @@ func (s *EndpointService) UpdatePaymentMethodByID(ctx context.Context, userID, p
 		ID:          paymentMethodID,
 		Name:        name,
 		Description: description,
+		Kind:        kind,
 		UpdatedAt:   generator.NowISO8601(),
 	})
 	if err != nil {
@@ -143,13 +177,22 @@ func (s *EndpointService) UpdatePaymentMethodByID(ctx context.Context, userID, p
 }
 
 // DeletePaymentMethodByID deletes a payment method if the user has access to the book.
-func (s *EndpointService) DeletePaymentMethodByID(ctx context.Context, userID, paymentMethodID string) error {
+//
+// The payment method cannot be deleted while it has expenses, transfers or
+// recurring expenses, unless reassignTo is not empty, in which case it is
+// merged into the reassignTo payment method instead.
+func (s *EndpointService) DeletePaymentMethodByID(ctx context.Context, userID, paymentMethodID, reassignTo string) error {
+	if reassignTo != "" {
+		return s.MergePaymentMethod(ctx, userID, paymentMethodID, reassignTo)
+	}
+
 	queries := repository.New(s.db)
 
 	// Check if the user has access to the payment method
 	canAccess, err := queries.CheckPaymentMethodAccess(ctx, repository.CheckPaymentMethodAccessParams{
 		PaymentMethodID: paymentMethodID,
 		UserID:          userID,
+		Role:            BookRoleEditor,
 	})
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to check payment method access: %v", err)
@@ -159,6 +202,24 @@ func (s *EndpointService) DeletePaymentMethodByID(ctx context.Context, userID, p
 		return NewServiceError(ErrCodeNotFound, "payment method not found or access denied")
 	}
 
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries = repository.New(tx)
+
+	// Refuse to cascade the deletion to the expenses
+	count, err := queries.CountPaymentMethodUsage(ctx, paymentMethodID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to count payment method usage: %v", err)
+	}
+
+	if count > 0 {
+		return NewServiceErrorf(ErrCodeConflict, "payment method is used by %d expenses or recurring expenses, reassign them to another payment method first", count)
+	}
+
 	rows, err := queries.DeletePaymentMethodByID(ctx, paymentMethodID)
 	if err != nil {
 		return NewServiceErrorf(ErrCodeInternal, "failed to delete payment method: %v", err)
@@ -172,5 +233,165 @@ func (s *EndpointService) DeletePaymentMethodByID(ctx context.Context, userID, p
 		return NewServiceError(ErrCodeInternal, "no payment method deleted")
 	}
 
+	if err := tx.Commit(); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// MergePaymentMethod moves the expenses, transfers and recurring expenses of a
+// payment method to the target payment method of the same book, and deletes
+// the payment method, in a single transaction.
+//
+// The payment methods cannot be merged if there are transfers between them,
+// as those would become transfers to the same payment method, or if both have
+// expenses imported from bank transactions with the same FITID.
+func (s *EndpointService) MergePaymentMethod(ctx context.Context, userID, paymentMethodID, targetID string) error {
+	paymentMethod, err := s.getAccessiblePaymentMethod(ctx, userID, paymentMethodID, BookRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	if targetID == paymentMethodID {
+		return NewServiceError(ErrCodeUnprocessable, "target payment method cannot be the payment method itself")
+	}
+
+	queries := repository.New(s.db)
+
+	targets, err := queries.GetPaymentMethodByID(ctx, targetID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to get target payment method by ID: %v", err)
+	}
+
+	if len(targets) > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple payment methods found with the same ID")
+	}
+
+	if len(targets) < 1 || targets[0].BookID != paymentMethod.BookID {
+		return NewServiceError(ErrCodeUnprocessable, "target payment method not found or does not belong to the book")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries = repository.New(tx)
+
+	count, err := queries.CountTransfersBetweenPaymentMethods(ctx, repository.CountTransfersBetweenPaymentMethodsParams{
+		ID:      paymentMethodID,
+		OtherID: targetID,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to count transfers between payment methods: %v", err)
+	}
+
+	if count > 0 {
+		return NewServiceErrorf(ErrCodeConflict, "payment methods have %d transfers between them, delete them first", count)
+	}
+
+	// External IDs are unique per payment method
+	count, err = queries.CountSharedExpenseExternalIDs(ctx, repository.CountSharedExpenseExternalIDsParams{
+		ID:      paymentMethodID,
+		OtherID: targetID,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to count shared external IDs: %v", err)
+	}
+
+	if count > 0 {
+		return NewServiceErrorf(ErrCodeConflict, "payment methods have %d imported transactions with the same external ID, delete the duplicates first", count)
+	}
+
+	currentTime := generator.NowISO8601()
+
+	// Move everything referencing the payment method before deleting it
+	_, err = queries.ReassignExpensesPaymentMethod(ctx, repository.ReassignExpensesPaymentMethodParams{
+		FromID:    paymentMethodID,
+		ToID:      targetID,
+		UpdatedAt: currentTime,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to reassign expenses: %v", err)
+	}
+
+	_, err = queries.ReassignRecurringExpensesPaymentMethod(ctx, repository.ReassignRecurringExpensesPaymentMethodParams{
+		FromID:    paymentMethodID,
+		ToID:      targetID,
+		UpdatedAt: currentTime,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to reassign recurring expenses: %v", err)
+	}
+
+	rows, err := queries.DeletePaymentMethodByID(ctx, paymentMethodID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete payment method: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple payment methods deleted, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "no payment method deleted")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// ArchivePaymentMethodByID archives a payment method if the user has access to
+// the book.
+//
+// An archived payment method is hidden from the listings and cannot be used by
+// new expenses, but it is kept for the existing ones.
+func (s *EndpointService) ArchivePaymentMethodByID(ctx context.Context, userID, paymentMethodID string) error {
+	currentTime := generator.NowISO8601()
+	return s.setPaymentMethodArchivedAt(ctx, userID, paymentMethodID, &currentTime)
+}
+
+// UnarchivePaymentMethodByID unarchives a payment method if the user has
+// access to the book.
+func (s *EndpointService) UnarchivePaymentMethodByID(ctx context.Context, userID, paymentMethodID string) error {
+	return s.setPaymentMethodArchivedAt(ctx, userID, paymentMethodID, nil)
+}
+
+// setPaymentMethodArchivedAt archives or unarchives a payment method, nothing
+// is changed if it is already in that state.
+func (s *EndpointService) setPaymentMethodArchivedAt(ctx context.Context, userID, paymentMethodID string, archivedAt *string) error {
+	paymentMethod, err := s.getAccessiblePaymentMethod(ctx, userID, paymentMethodID, BookRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	if (paymentMethod.ArchivedAt != nil) == (archivedAt != nil) {
+		return nil
+	}
+
+	queries := repository.New(s.db)
+
+	rows, err := queries.SetPaymentMethodArchivedAt(ctx, repository.SetPaymentMethodArchivedAtParams{
+		ID:         paymentMethodID,
+		ArchivedAt: archivedAt,
+		UpdatedAt:  generator.NowISO8601(),
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to update payment method: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple payment methods updated, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "no payment method updated")
+	}
+
 	return nil
 }
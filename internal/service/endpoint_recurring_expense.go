@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/money"
+	"github.com/jljl1337/xpense/internal/recurrence"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+// RecurringExpense is a recurring expense with its amount as a decimal in the
+// book currency.
+type RecurringExpense struct {
+	repository.RecurringExpense
+	Amount money.Decimal `json:"amount"`
+}
+
+func newRecurringExpense(recurringExpense repository.RecurringExpense, bookCurrency string) RecurringExpense {
+	return RecurringExpense{
+		RecurringExpense: recurringExpense,
+		Amount:           money.New(recurringExpense.Amount, money.Scale(bookCurrency)),
+	}
+}
+
+// CreateRecurringExpense creates a new recurring expense if the user has access
+// to the book, category, and payment method.
+//
+// An empty end date means the recurring expense never ends.
+func (s *EndpointService) CreateRecurringExpense(ctx context.Context, userID, bookID, categoryID, paymentMethodID string, amount money.Decimal, remark, rule, startDate, endDate string) error {
+	queries := repository.New(s.db)
+
+	parsedRule, err := checkRecurringExpenseSchedule(rule, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	// Check if the user has access to the book, category, and payment method
+	err = s.checkBookCategoryPaymentMethod(ctx, userID, bookID, categoryID, paymentMethodID, "", "")
+	if err != nil {
+		return err
+	}
+
+	amountMinorUnits, err := s.getBookMinorUnits(ctx, bookID, amount)
+	if err != nil {
+		return err
+	}
+
+	// Create the recurring expense
+	currentTime := generator.NowISO8601()
+
+	_, err = queries.CreateRecurringExpense(ctx, repository.CreateRecurringExpenseParams{
+		ID:              generator.NewULID(),
+		BookID:          bookID,
+		CategoryID:      categoryID,
+		PaymentMethodID: paymentMethodID,
+		Amount:          amountMinorUnits,
+		Remark:          remark,
+		Rule:            parsedRule.String(),
+		StartDate:       startDate,
+		EndDate:         endDate,
+		CreatedAt:       currentTime,
+		UpdatedAt:       currentTime,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to create recurring expense: %v", err)
+	}
+
+	return nil
+}
+
+// GetRecurringExpensesByBookID retrieves all recurring expenses for a specific
+// book.
+//
+// It returns an empty slice if no recurring expenses are found in the book.
+func (s *EndpointService) GetRecurringExpensesByBookID(ctx context.Context, userID, bookID string) ([]RecurringExpense, error) {
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleViewer,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeUnprocessable, "book not found or access denied")
+	}
+
+	recurringExpenses, err := queries.GetRecurringExpensesByBookID(ctx, bookID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get recurring expenses by book ID: %v", err)
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]RecurringExpense, 0, len(recurringExpenses))
+	for _, recurringExpense := range recurringExpenses {
+		result = append(result, newRecurringExpense(recurringExpense, bookCurrency))
+	}
+
+	return result, nil
+}
+
+// GetRecurringExpenseByID retrieves a recurring expense by its ID if the user
+// has access to the book.
+func (s *EndpointService) GetRecurringExpenseByID(ctx context.Context, userID, recurringExpenseID string) (*RecurringExpense, error) {
+	recurringExpense, err := s.getAccessibleRecurringExpense(ctx, userID, recurringExpenseID, BookRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, recurringExpense.BookID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newRecurringExpense(*recurringExpense, bookCurrency)
+	return &result, nil
+}
+
+// UpdateRecurringExpense updates an existing recurring expense if the user has
+// access to the book, category, and payment method.
+func (s *EndpointService) UpdateRecurringExpense(ctx context.Context, userID, recurringExpenseID, categoryID, paymentMethodID string, amount money.Decimal, remark, rule, startDate, endDate string) error {
+	queries := repository.New(s.db)
+
+	parsedRule, err := checkRecurringExpenseSchedule(rule, startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	recurringExpense, err := s.getAccessibleRecurringExpense(ctx, userID, recurringExpenseID, BookRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	// Check if the user has access to the book, category, and payment method
+	err = s.checkBookCategoryPaymentMethod(ctx, userID, recurringExpense.BookID, categoryID, paymentMethodID, recurringExpense.CategoryID, recurringExpense.PaymentMethodID)
+	if err != nil {
+		return err
+	}
+
+	amountMinorUnits, err := s.getBookMinorUnits(ctx, recurringExpense.BookID, amount)
+	if err != nil {
+		return err
+	}
+
+	// Update the recurring expense
+	rows, err := queries.UpdateRecurringExpenseByID(ctx, repository.UpdateRecurringExpenseByIDParams{
+		ID:              recurringExpenseID,
+		CategoryID:      categoryID,
+		PaymentMethodID: paymentMethodID,
+		Amount:          amountMinorUnits,
+		Remark:          remark,
+		Rule:            parsedRule.String(),
+		StartDate:       startDate,
+		EndDate:         endDate,
+		UpdatedAt:       generator.NowISO8601(),
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to update recurring expense: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple recurring expenses updated with the same ID")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "recurring expense not updated")
+	}
+
+	return nil
+}
+
+// DeleteRecurringExpenseByID deletes a recurring expense by its ID if the user
+// has access to the book.
+//
+// Expenses already created from the recurring expense are kept.
+func (s *EndpointService) DeleteRecurringExpenseByID(ctx context.Context, userID, recurringExpenseID string) error {
+	queries := repository.New(s.db)
+
+	if _, err := s.getAccessibleRecurringExpense(ctx, userID, recurringExpenseID, BookRoleEditor); err != nil {
+		return err
+	}
+
+	// Proceed to delete the recurring expense
+	rows, err := queries.DeleteRecurringExpenseByID(ctx, recurringExpenseID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete recurring expense: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple recurring expenses deleted with the same ID")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "recurring expense not deleted")
+	}
+
+	return nil
+}
+
+// getAccessibleRecurringExpense retrieves a recurring expense by its ID and
+// checks if the user has at least the role in its book.
+func (s *EndpointService) getAccessibleRecurringExpense(ctx context.Context, userID, recurringExpenseID, role string) (*repository.RecurringExpense, error) {
+	queries := repository.New(s.db)
+
+	recurringExpenses, err := queries.GetRecurringExpenseByID(ctx, recurringExpenseID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get recurring expense by ID: %v", err)
+	}
+
+	if len(recurringExpenses) > 1 {
+		return nil, NewServiceError(ErrCodeInternal, "multiple recurring expenses found with the same ID")
+	}
+
+	if len(recurringExpenses) < 1 {
+		return nil, NewServiceError(ErrCodeNotFound, "recurring expense not found or access denied")
+	}
+
+	recurringExpense := recurringExpenses[0]
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: recurringExpense.BookID,
+		UserID: userID,
+		Role:   role,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeNotFound, "recurring expense not found or access denied")
+	}
+
+	return &recurringExpense, nil
+}
+
+// checkRecurringExpenseSchedule validates the rule and the date range of a
+// recurring expense, and returns the parsed rule.
+func checkRecurringExpenseSchedule(rule, startDate, endDate string) (recurrence.Rule, error) {
+	parsedRule, err := recurrence.Parse(rule)
+	if err != nil {
+		return recurrence.Rule{}, NewServiceErrorf(ErrCodeUnprocessable, "invalid rule: %v", err)
+	}
+
+	if endDate != "" && endDate < startDate {
+		return recurrence.Rule{}, NewServiceError(ErrCodeUnprocessable, "end date must not be before start date")
+	}
+
+	return parsedRule, nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jljl1337/xpense/internal/money"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+const (
+	SummaryGroupByMonth         = "month"
+	SummaryGroupByCategory      = "category"
+	SummaryGroupByPaymentMethod = "payment-method"
+)
+
+func IsValidSummaryGroupBy(groupBy string) bool {
+	switch groupBy {
+	case SummaryGroupByMonth, SummaryGroupByCategory, SummaryGroupByPaymentMethod:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExpenseSummaryGroup is the total of the expenses of a type in a group, the
+// fields of the dimensions not grouped by are null.
+//
+// The total of a category includes its subcategories, so the totals of nested
+// categories overlap.
+type ExpenseSummaryGroup struct {
+	Type string `json:"type"`
+	// Month is in YYYY-MM
+	Month             *string       `json:"month"`
+	CategoryID        *string       `json:"categoryID"`
+	CategoryName      *string       `json:"categoryName"`
+	CategoryParentID  *string       `json:"categoryParentID"`
+	PaymentMethodID   *string       `json:"paymentMethodID"`
+	PaymentMethodName *string       `json:"paymentMethodName"`
+	Total             money.Decimal `json:"total"`
+	Count             int64         `json:"count"`
+}
+
+type ExpenseSummary struct {
+	Currency string                `json:"currency"`
+	Groups   []ExpenseSummaryGroup `json:"groups"`
+}
+
+// GetExpenseSummary returns the totals of the expenses of a book between from
+// and to (inclusive, either can be empty) and with the tags of the filter if
+// the user has access to the book.
+//
+// The totals are always grouped by type, and by any combination of month,
+// category and payment method in groupBy.
+func (s *EndpointService) GetExpenseSummary(ctx context.Context, userID, bookID, from, to string, tagFilter TagFilter, groupBy []string) (*ExpenseSummary, error) {
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleViewer,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeNotFound, "book not found or access denied")
+	}
+
+	bookCurrency, err := s.getBookCurrency(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := queries.GetExpenseSummaryByBookID(ctx, repository.GetExpenseSummaryByBookIDParams{
+		BookID:          bookID,
+		From:            from,
+		To:              to,
+		TagFilterParams: tagFilter.params(),
+		ByMonth:         slices.Contains(groupBy, SummaryGroupByMonth),
+		ByCategory:      slices.Contains(groupBy, SummaryGroupByCategory),
+		ByPaymentMethod: slices.Contains(groupBy, SummaryGroupByPaymentMethod),
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get expense summary by book ID: %v", err)
+	}
+
+	bookScale := money.Scale(bookCurrency)
+
+	summary := &ExpenseSummary{
+		Currency: bookCurrency,
+		Groups:   make([]ExpenseSummaryGroup, 0, len(rows)),
+	}
+
+	for _, row := range rows {
+		summary.Groups = append(summary.Groups, ExpenseSummaryGroup{
+			Type:              row.Type,
+			Month:             row.Month,
+			CategoryID:        row.CategoryID,
+			CategoryName:      row.CategoryName,
+			CategoryParentID:  row.CategoryParentID,
+			PaymentMethodID:   row.PaymentMethodID,
+			PaymentMethodName: row.PaymentMethodName,
+			Total:             money.New(row.Total, bookScale),
+			Count:             row.Count,
+		})
+	}
+
+	return summary, nil
+}
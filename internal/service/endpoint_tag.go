@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+// CreateTag creates a new tag if the user has access to the book, and the book
+// has no tag with the same name.
+func (s *EndpointService) CreateTag(ctx context.Context, userID, bookID, name, description string) error {
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleEditor,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return NewServiceError(ErrCodeUnprocessable, "book not found or access denied")
+	}
+
+	if err := s.checkTagName(ctx, bookID, "", name); err != nil {
+		return err
+	}
+
+	currentTime := generator.NowISO8601()
+
+	_, err = queries.CreateTag(ctx, repository.CreateTagParams{
+		ID:          generator.NewULID(),
+		BookID:      bookID,
+		Name:        name,
+		Description: description,
+		CreatedAt:   currentTime,
+		UpdatedAt:   currentTime,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to create tag: %v", err)
+	}
+
+	return nil
+}
+
+// GetTagsByBookID retrieves all tags for a specific book.
+//
+// It returns an empty slice if no tags are found in the book.
+func (s *EndpointService) GetTagsByBookID(ctx context.Context, userID, bookID string) ([]repository.Tag, error) {
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: bookID,
+		UserID: userID,
+		Role:   BookRoleViewer,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeUnprocessable, "book not found or access denied")
+	}
+
+	tags, err := queries.GetTagsByBookID(ctx, bookID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get tags by book ID: %v", err)
+	}
+
+	return tags, nil
+}
+
+// GetTagByID retrieves a tag by its ID if the user has access to the book.
+func (s *EndpointService) GetTagByID(ctx context.Context, userID, tagID string) (*repository.Tag, error) {
+	return s.getAccessibleTag(ctx, userID, tagID, BookRoleViewer)
+}
+
+// getAccessibleTag retrieves a tag by its ID if the user has at least the role
+// in its book.
+func (s *EndpointService) getAccessibleTag(ctx context.Context, userID, tagID, role string) (*repository.Tag, error) {
+	queries := repository.New(s.db)
+
+	// Get the tag to find the book ID
+	tags, err := queries.GetTagByID(ctx, tagID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get tag by ID: %v", err)
+	}
+
+	if len(tags) > 1 {
+		return nil, NewServiceError(ErrCodeInternal, "multiple tags found with the same ID")
+	}
+
+	if len(tags) < 1 {
+		return nil, NewServiceError(ErrCodeNotFound, "tag not found or access denied")
+	}
+
+	tag := tags[0]
+
+	// Check if the user has access to the book
+	canAccess, err := queries.CheckBookAccess(ctx, repository.CheckBookAccessParams{
+		BookID: tag.BookID,
+		UserID: userID,
+		Role:   role,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to check book access: %v", err)
+	}
+
+	if !canAccess {
+		return nil, NewServiceError(ErrCodeNotFound, "tag not found or access denied")
+	}
+
+	return &tag, nil
+}
+
+// UpdateTagByID updates a tag if the user has access to the book, and no other
+// tag of the book has the same name.
+func (s *EndpointService) UpdateTagByID(ctx context.Context, userID, tagID, name, description string) error {
+	tag, err := s.getAccessibleTag(ctx, userID, tagID, BookRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkTagName(ctx, tag.BookID, tagID, name); err != nil {
+		return err
+	}
+
+	queries := repository.New(s.db)
+
+	rows, err := queries.UpdateTagByID(ctx, repository.UpdateTagByIDParams{
+		ID:          tagID,
+		Name:        name,
+		Description: description,
+		UpdatedAt:   generator.NowISO8601(),
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to update tag: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple tags updated, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "no tag updated")
+	}
+
+	return nil
+}
+
+// DeleteTagByID deletes a tag if the user has access to the book, the tag is
+// removed from all the expenses.
+func (s *EndpointService) DeleteTagByID(ctx context.Context, userID, tagID string) error {
+	queries := repository.New(s.db)
+
+	// Check if the user has access to the tag
+	canAccess, err := queries.CheckTagAccess(ctx, repository.CheckTagAccessParams{
+		TagID:  tagID,
+		UserID: userID,
+		Role:   BookRoleEditor,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to check tag access: %v", err)
+	}
+
+	if !canAccess {
+		return NewServiceError(ErrCodeNotFound, "tag not found or access denied")
+	}
+
+	rows, err := queries.DeleteTagByID(ctx, tagID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete tag: %v", err)
+	}
+
+	if rows > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple tags deleted, data integrity issue")
+	}
+
+	if rows < 1 {
+		return NewServiceError(ErrCodeInternal, "no tag deleted")
+	}
+
+	return nil
+}
+
+// checkTagName checks that no tag of the book other than tagID has the name.
+func (s *EndpointService) checkTagName(ctx context.Context, bookID, tagID, name string) error {
+	queries := repository.New(s.db)
+
+	tags, err := queries.GetTagByName(ctx, repository.GetTagByNameParams{
+		BookID: bookID,
+		Name:   name,
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to get tag by name: %v", err)
+	}
+
+	if len(tags) > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple tags found with the same name")
+	}
+
+	if len(tags) == 1 && tags[0].ID != tagID {
+		return NewServiceError(ErrCodeConflict, "tag name already exists in the book")
+	}
+
+	return nil
+}
+
+// TagFilter filters the expenses by their tags, no tag IDs match everything.
+type TagFilter struct {
+	TagIDs []string
+	// MatchAll matches the expenses with all the tags instead of any of them
+	MatchAll bool
+}
+
+func (f TagFilter) params() repository.TagFilterParams {
+	if len(f.TagIDs) == 0 {
+		return repository.TagFilterParams{}
+	}
+
+	return repository.TagFilterParams{
+		TagIDs:      jsonIDs(f.TagIDs),
+		TagMatchAll: f.MatchAll,
+	}
+}
+
+// distinctIDs returns the IDs sorted without duplicates.
+func distinctIDs(ids []string) []string {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// jsonIDs returns the distinct IDs as a JSON array, for the queries taking a
+// list of IDs.
+func jsonIDs(ids []string) string {
+	data, _ := json.Marshal(distinctIDs(ids))
+	return string(data)
+}
+
+// checkExpenseTags checks that all the tags belong to the book.
+func (s *EndpointService) checkExpenseTags(ctx context.Context, bookID string, tagIDs []string) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	queries := repository.New(s.db)
+
+	count, err := queries.CountTagsByBookIDAndIDs(ctx, repository.CountTagsByBookIDAndIDsParams{
+		BookID: bookID,
+		IDs:    jsonIDs(tagIDs),
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to count tags: %v", err)
+	}
+
+	if count != int64(len(distinctIDs(tagIDs))) {
+		return NewServiceError(ErrCodeUnprocessable, "tag not found or does not belong to the book")
+	}
+
+	return nil
+}
+
+// setExpenseTags replaces the tags of an expense, it must be called within the
+// transaction updating the expense.
+func setExpenseTags(ctx context.Context, queries *repository.Queries, expenseID string, tagIDs []string) error {
+	if _, err := queries.DeleteExpenseTagsByExpenseID(ctx, expenseID); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete expense tags: %v", err)
+	}
+
+	for _, tagID := range distinctIDs(tagIDs) {
+		_, err := queries.CreateExpenseTag(ctx, repository.CreateExpenseTagParams{
+			ExpenseID: expenseID,
+			TagID:     tagID,
+		})
+		if err != nil {
+			return NewServiceErrorf(ErrCodeInternal, "failed to create expense tag: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// loadExpenseTags sets the tag IDs of the expenses.
+func loadExpenseTags(ctx context.Context, queries *repository.Queries, expenses []Expense) error {
+	if len(expenses) == 0 {
+		return nil
+	}
+
+	expenseIDs := make([]string, 0, len(expenses))
+	for _, expense := range expenses {
+		expenseIDs = append(expenseIDs, expense.ID)
+	}
+
+	expenseTags, err := queries.GetExpenseTagsByExpenseIDs(ctx, repository.GetExpenseTagsByExpenseIDsParams{
+		ExpenseIDs: jsonIDs(expenseIDs),
+	})
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to get expense tags: %v", err)
+	}
+
+	tagIDs := map[string][]string{}
+	for _, expenseTag := range expenseTags {
+		tagIDs[expenseTag.ExpenseID] = append(tagIDs[expenseTag.ExpenseID], expenseTag.TagID)
+	}
+
+	for i := range expenses {
+		if ids, ok := tagIDs[expenses[i].ID]; ok {
+			expenses[i].TagIDs = ids
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jljl1337/xpense/internal/crypto"
+	"github.com/jljl1337/xpense/internal/env"
+	"github.com/jljl1337/xpense/internal/format"
+	"github.com/jljl1337/xpense/internal/generator"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+type TwoFactorStatus struct {
+	TOTPEnabled            bool  `json:"totpEnabled"`
+	RecoveryCodesRemaining int64 `json:"recoveryCodesRemaining"`
+}
+
+// TOTPEnrollment is a TOTP secret waiting for confirmation, with its otpauth
+// URI to be added to an authenticator app.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+func (s *EndpointService) GetTwoFactorStatus(ctx context.Context, userID string) (*TwoFactorStatus, error) {
+	queries := repository.New(s.db)
+
+	totp, err := getEnabledUserTOTP(ctx, queries, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := queries.CountUnusedUserRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to count recovery codes: %v", err)
+	}
+
+	return &TwoFactorStatus{
+		TOTPEnabled:            totp != nil,
+		RecoveryCodesRemaining: count,
+	}, nil
+}
+
+// EnrollTOTP generates a new TOTP secret for the user, replacing any secret
+// not confirmed yet. The secret is only enabled by ConfirmTOTP.
+func (s *EndpointService) EnrollTOTP(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	queries := repository.New(s.db)
+
+	users, err := queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get user: %v", err)
+	}
+
+	if len(users) > 1 {
+		return nil, NewServiceError(ErrCodeInternal, "multiple users found with the same ID")
+	}
+
+	if len(users) < 1 {
+		return nil, NewServiceError(ErrCodeNotFound, "user not found")
+	}
+
+	totp, err := getEnabledUserTOTP(ctx, queries, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp != nil {
+		return nil, NewServiceError(ErrCodeConflict, "two-factor authentication is already enabled")
+	}
+
+	secret, err := crypto.NewTOTPSecret()
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to generate TOTP secret: %v", err)
+	}
+
+	currentTime := generator.NowISO8601()
+
+	if _, err := queries.UpsertUserTOTP(ctx, repository.UpsertUserTOTPParams{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: currentTime,
+		UpdatedAt: currentTime,
+	}); err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to create TOTP secret: %v", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    crypto.TOTPURI(env.TOTPIssuer, users[0].Username, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables the TOTP secret of the user with a valid code, and
+// returns the recovery codes, which are only shown once.
+func (s *EndpointService) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	totps, err := queries.GetUserTOTPByUserID(ctx, userID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get TOTP secret: %v", err)
+	}
+
+	if len(totps) > 1 {
+		return nil, NewServiceError(ErrCodeInternal, "multiple TOTP secrets found for the same user")
+	}
+
+	if len(totps) < 1 {
+		return nil, NewServiceError(ErrCodeNotFound, "TOTP enrollment not found")
+	}
+
+	totp := totps[0]
+
+	if totp.EnabledAt != nil {
+		return nil, NewServiceError(ErrCodeConflict, "two-factor authentication is already enabled")
+	}
+
+	now := time.Now()
+
+	step, valid := crypto.CheckTOTPCode(totp.Secret, code, now, totp.LastStep)
+	if !valid {
+		return nil, NewServiceError(ErrCodeUnprocessable, "invalid TOTP code")
+	}
+
+	currentTime := format.TimeToISO8601(now)
+
+	rows, err := queries.EnableUserTOTP(ctx, repository.EnableUserTOTPParams{
+		EnabledAt: currentTime,
+		LastStep:  step,
+		UpdatedAt: currentTime,
+		UserID:    userID,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to enable TOTP: %v", err)
+	} else if rows < 1 {
+		return nil, NewServiceError(ErrCodeConflict, "two-factor authentication is already enabled")
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, queries, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor removes the TOTP secret and the recovery codes of the user
+// after checking the password.
+func (s *EndpointService) DisableTwoFactor(ctx context.Context, userID, password string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	if err := checkUserPassword(ctx, queries, userID, password); err != nil {
+		return err
+	}
+
+	totp, err := getEnabledUserTOTP(ctx, queries, userID)
+	if err != nil {
+		return err
+	}
+
+	if totp == nil {
+		return NewServiceError(ErrCodeConflict, "two-factor authentication is not enabled")
+	}
+
+	if _, err := queries.DeleteUserTOTPByUserID(ctx, userID); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete TOTP secret: %v", err)
+	}
+
+	if _, err := queries.DeleteUserRecoveryCodesByUserID(ctx, userID); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete recovery codes: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user after
+// checking the password, and returns the new codes.
+func (s *EndpointService) RegenerateRecoveryCodes(ctx context.Context, userID, password string) ([]string, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	if err := checkUserPassword(ctx, queries, userID, password); err != nil {
+		return nil, err
+	}
+
+	totp, err := getEnabledUserTOTP(ctx, queries, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp == nil {
+		return nil, NewServiceError(ErrCodeConflict, "two-factor authentication is not enabled")
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, queries, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return codes, nil
+}
+
+// getEnabledUserTOTP returns the confirmed TOTP secret of the user, or nil if
+// two-factor authentication is not enabled.
+func getEnabledUserTOTP(ctx context.Context, queries *repository.Queries, userID string) (*repository.UserTOTP, error) {
+	totps, err := queries.GetUserTOTPByUserID(ctx, userID)
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get TOTP secret: %v", err)
+	}
+
+	if len(totps) > 1 {
+		return nil, NewServiceError(ErrCodeInternal, "multiple TOTP secrets found for the same user")
+	}
+
+	if len(totps) < 1 || totps[0].EnabledAt == nil {
+		return nil, nil
+	}
+
+	return &totps[0], nil
+}
+
+// useTOTPCode checks the TOTP code of the user, and marks its time step as used
+// if it is valid.
+func useTOTPCode(ctx context.Context, queries *repository.Queries, userID, code string) (bool, error) {
+	totp, err := getEnabledUserTOTP(ctx, queries, userID)
+	if err != nil || totp == nil {
+		return false, err
+	}
+
+	now := time.Now()
+
+	step, valid := crypto.CheckTOTPCode(totp.Secret, code, now, totp.LastStep)
+	if !valid {
+		return false, nil
+	}
+
+	rows, err := queries.UpdateUserTOTPLastStep(ctx, repository.UpdateUserTOTPLastStepParams{
+		LastStep:  step,
+		UpdatedAt: format.TimeToISO8601(now),
+		UserID:    userID,
+	})
+	if err != nil {
+		return false, NewServiceErrorf(ErrCodeInternal, "failed to update TOTP secret: %v", err)
+	}
+
+	return rows == 1, nil
+}
+
+// useRecoveryCode marks the recovery code of the user as used if it is valid
+// and not used yet.
+func useRecoveryCode(ctx context.Context, queries *repository.Queries, userID, code string) (bool, error) {
+	code = normalizeRecoveryCode(code)
+	if code == "" {
+		return false, nil
+	}
+
+	rows, err := queries.UseUserRecoveryCode(ctx, repository.UseUserRecoveryCodeParams{
+		UsedAt:   generator.NowISO8601(),
+		UserID:   userID,
+		CodeHash: crypto.HashToken(code),
+	})
+	if err != nil {
+		return false, NewServiceErrorf(ErrCodeInternal, "failed to use recovery code: %v", err)
+	}
+
+	return rows == 1, nil
+}
+
+// replaceRecoveryCodes deletes the recovery codes of the user and generates new
+// ones, only their hashes being stored.
+func replaceRecoveryCodes(ctx context.Context, queries *repository.Queries, userID string) ([]string, error) {
+	if _, err := queries.DeleteUserRecoveryCodesByUserID(ctx, userID); err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to delete recovery codes: %v", err)
+	}
+
+	currentTime := generator.NowISO8601()
+	codes := make([]string, 0, env.RecoveryCodeCount)
+
+	for range env.RecoveryCodeCount {
+		code := generator.NewToken(env.RecoveryCodeLength, env.RecoveryCodeCharset)
+
+		if _, err := queries.CreateUserRecoveryCode(ctx, repository.CreateUserRecoveryCodeParams{
+			ID:        generator.NewULID(),
+			UserID:    userID,
+			CodeHash:  crypto.HashToken(normalizeRecoveryCode(code)),
+			CreatedAt: currentTime,
+		}); err != nil {
+			return nil, NewServiceErrorf(ErrCodeInternal, "failed to create recovery code: %v", err)
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode ignores the case, spaces and dashes of a recovery code
+// typed by the user.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+func checkUserPassword(ctx context.Context, queries *repository.Queries, userID, password string) error {
+	users, err := queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to get user: %v", err)
+	}
+
+	if len(users) > 1 {
+		return NewServiceError(ErrCodeInternal, "multiple users found with the same ID")
+	}
+
+	if len(users) < 1 {
+		return NewServiceError(ErrCodeNotFound, "user not found")
+	}
+
+	if !crypto.CheckPasswordHash(password, users[0].PasswordHash) {
+		return NewServiceError(ErrCodeUnprocessable, "password is incorrect")
+	}
+
+	return nil
+}
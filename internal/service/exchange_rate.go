@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+// getExchangeRate returns the amount of the to currency for 1 unit of the from
+// currency, using the latest rates on or before the date.
+//
+// The rate is exact, the stored rates being read as the decimals they were
+// loaded from rather than their nearest binary floating point values.
+func (s *EndpointService) getExchangeRate(ctx context.Context, fromCurrency, toCurrency, date string) (*big.Rat, error) {
+	if fromCurrency == toCurrency {
+		return big.NewRat(1, 1), nil
+	}
+
+	queries := repository.New(s.db)
+
+	rates, err := queries.GetExchangeRate(ctx, repository.GetExchangeRateParams{
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		Date:         date,
+	})
+	if err != nil {
+		return nil, NewServiceErrorf(ErrCodeInternal, "failed to get exchange rate: %v", err)
+	}
+
+	if len(rates) < 1 {
+		return nil, NewServiceErrorf(ErrCodeUnprocessable, "no exchange rate from %s to %s on or before %s", fromCurrency, toCurrency, date)
+	}
+
+	fromRate, ok := new(big.Rat).SetString(strconv.FormatFloat(rates[0].FromRate, 'g', -1, 64))
+	if !ok {
+		return nil, NewServiceErrorf(ErrCodeInternal, "invalid exchange rate of %s: %v", fromCurrency, rates[0].FromRate)
+	}
+
+	toRate, ok := new(big.Rat).SetString(strconv.FormatFloat(rates[0].ToRate, 'g', -1, 64))
+	if !ok {
+		return nil, NewServiceErrorf(ErrCodeInternal, "invalid exchange rate of %s: %v", toCurrency, rates[0].ToRate)
+	}
+
+	return toRate.Quo(toRate, fromRate), nil
+}
@@ -6,27 +6,38 @@ import (
 
 	"github.com/jmoiron/sqlx"
 
+	"github.com/jljl1337/xpense/internal/crypto"
 	"github.com/jljl1337/xpense/internal/env"
 	"github.com/jljl1337/xpense/internal/format"
+	"github.com/jljl1337/xpense/internal/generator"
 	"github.com/jljl1337/xpense/internal/repository"
 )
 
+// sessionLastSeenInterval is how often the last seen time of a session is
+// updated, so that not every request writes to the database
+const sessionLastSeenInterval = time.Minute
+
 type MiddlewareService struct {
 	db *sqlx.DB
+	// sessionTokenHashKey is the key of the session token hashes
+	sessionTokenHashKey []byte
 }
 
-func NewMiddlewareService(db *sqlx.DB) *MiddlewareService {
+func NewMiddlewareService(db *sqlx.DB, sessionTokenHashKey []byte) *MiddlewareService {
 	return &MiddlewareService{
-		db: db,
+		db:                  db,
+		sessionTokenHashKey: sessionTokenHashKey,
 	}
 }
 
 // GetSessionUserIDAndRefreshSession validates the session token (and CSRF token),
-// refreshes the session expiration, and returns the associated user ID.
-func (s *MiddlewareService) GetSessionUserIDAndRefreshSession(ctx context.Context, sessionToken, CSRFToken string) (string, error) {
+// refreshes the session expiration and last seen time, and returns the
+// associated user ID.
+func (s *MiddlewareService) GetSessionUserIDAndRefreshSession(ctx context.Context, sessionToken, CSRFToken, userAgent, ip string) (string, error) {
 	queries := repository.New(s.db)
 
-	sessions, err := queries.GetSessionByToken(ctx, sessionToken)
+	sessionTokenHash := hashSessionToken(sessionToken, s.sessionTokenHashKey)
+	sessions, err := queries.GetSessionByToken(ctx, sessionTokenHash)
 
 	if err != nil {
 		return "", NewServiceErrorf(ErrCodeInternal, "failed to get session: %v", err)
@@ -48,7 +59,7 @@ func (s *MiddlewareService) GetSessionUserIDAndRefreshSession(ctx context.Contex
 	}
 
 	// CSRF token does not match
-	if CSRFToken != "" && session.CsrfToken != CSRFToken {
+	if CSRFToken != "" && !checkSessionCSRFToken(session, sessionToken, CSRFToken, s.sessionTokenHashKey) {
 		return "", NewServiceError(ErrCodeUnauthorized, "unauthorized")
 	}
 
@@ -59,6 +70,18 @@ func (s *MiddlewareService) GetSessionUserIDAndRefreshSession(ctx context.Contex
 		return "", NewServiceError(ErrCodeUnauthorized, "unauthorized")
 	}
 
+	// Record the client the session is seen from
+	if session.LastSeenAt < format.TimeToISO8601(now.Add(-sessionLastSeenInterval)) {
+		if _, err := queries.UpdateSessionLastSeenAt(ctx, repository.UpdateSessionLastSeenAtParams{
+			UserAgent:  nullableString(userAgent),
+			IP:         nullableString(ip),
+			LastSeenAt: nowISO8601,
+			ID:         session.ID,
+		}); err != nil {
+			return "", NewServiceErrorf(ErrCodeInternal, "failed to update session last seen time: %v", err)
+		}
+	}
+
 	// Only refresh session if remaining lifetime is below threshold
 	expiresAt, err := format.ISO8601ToTime(session.ExpiresAt)
 	if err != nil {
@@ -69,7 +92,7 @@ func (s *MiddlewareService) GetSessionUserIDAndRefreshSession(ctx context.Contex
 	if remainingLifetimeMin < float64(env.SessionRefreshThresholdMin) {
 		newExpiresAt := format.TimeToISO8601(now.Add(time.Duration(env.SessionLifetimeMin) * time.Minute))
 		rows, err := queries.UpdateSessionByToken(ctx, repository.UpdateSessionByTokenParams{
-			Token:     sessionToken,
+			TokenHash: sessionTokenHash,
 			ExpiresAt: newExpiresAt,
 			UpdatedAt: nowISO8601,
 		})
@@ -84,3 +107,320 @@ func (s *MiddlewareService) GetSessionUserIDAndRefreshSession(ctx context.Contex
 
 	return session.UserID.String, nil
 }
+
+// GetAPITokenUserID validates the API token, records its use, and returns the
+// associated user ID. A read-only token is forbidden from the requests that
+// write.
+func (s *MiddlewareService) GetAPITokenUserID(ctx context.Context, token string, write bool) (string, error) {
+	queries := repository.New(s.db)
+
+	apiTokens, err := queries.GetAPITokenByTokenHash(ctx, crypto.HashToken(token))
+	if err != nil {
+		return "", NewServiceErrorf(ErrCodeInternal, "failed to get API token: %v", err)
+	}
+
+	if len(apiTokens) > 1 {
+		return "", NewServiceError(ErrCodeInternal, "multiple API tokens found with the same token")
+	}
+
+	if len(apiTokens) < 1 {
+		return "", NewServiceError(ErrCodeUnauthorized, "unauthorized")
+	}
+
+	apiToken := apiTokens[0]
+
+	// API token expired
+	nowISO8601 := format.TimeToISO8601(time.Now())
+	if apiToken.ExpiresAt != nil && *apiToken.ExpiresAt < nowISO8601 {
+		return "", NewServiceError(ErrCodeUnauthorized, "unauthorized")
+	}
+
+	if write && apiToken.Scope != APITokenScopeReadWrite {
+		return "", NewServiceError(ErrCodeForbidden, "API token is read-only")
+	}
+
+	_, err = queries.UpdateAPITokenLastUsedAt(ctx, repository.UpdateAPITokenLastUsedAtParams{
+		LastUsedAt: nowISO8601,
+		ID:         apiToken.ID,
+	})
+	if err != nil {
+		return "", NewServiceErrorf(ErrCodeInternal, "failed to update API token: %v", err)
+	}
+
+	return apiToken.UserID, nil
+}
+
+// The kinds of sign-in throttles, a sign-in attempt counts for both its client
+// IP and its username.
+const (
+	signInThrottleKindIP       = "ip"
+	signInThrottleKindUsername = "username"
+)
+
+type signInThrottleKey struct {
+	kind  string
+	value string
+}
+
+// signInThrottleKeys returns the throttles of a sign-in attempt, the username
+// being empty if it is unknown.
+func signInThrottleKeys(ip, username string) []signInThrottleKey {
+	keys := []signInThrottleKey{{kind: signInThrottleKindIP, value: ip}}
+	if username != "" {
+		keys = append(keys, signInThrottleKey{kind: signInThrottleKindUsername, value: username})
+	}
+
+	return keys
+}
+
+// GetSignInLockout returns how long the sign-in attempts of the client IP or
+// username are still locked out, or zero if they are not.
+func (s *MiddlewareService) GetSignInLockout(ctx context.Context, ip, username string) (time.Duration, error) {
+	queries := repository.New(s.db)
+
+	now := time.Now()
+	lockout := time.Duration(0)
+
+	for _, key := range signInThrottleKeys(ip, username) {
+		throttles, err := queries.GetSignInThrottle(ctx, repository.GetSignInThrottleParams{
+			Kind:  key.kind,
+			Value: key.value,
+		})
+		if err != nil {
+			return 0, NewServiceErrorf(ErrCodeInternal, "failed to get sign-in throttle: %v", err)
+		}
+
+		if len(throttles) < 1 || throttles[0].LockedUntil == nil {
+			continue
+		}
+
+		lockedUntil, err := format.ISO8601ToTime(*throttles[0].LockedUntil)
+		if err != nil {
+			return 0, NewServiceErrorf(ErrCodeInternal, "failed to parse sign-in lockout: %v", err)
+		}
+
+		lockout = max(lockout, lockedUntil.Sub(now))
+	}
+
+	return lockout, nil
+}
+
+// signInAttemptTimeout is how long an attempt in progress is counted by the
+// throttles, far above the time to check a password or a second factor
+const signInAttemptTimeout = time.Minute
+
+// ReserveSignInAttempt counts a sign-in attempt as in progress for the client
+// IP and username, and returns zero, unless they are locked out or have as many
+// attempts in progress as failures left before the lockout. It then returns how
+// long to wait before trying again, and nothing is counted.
+//
+// The check and the count are a single statement for each throttle, so that
+// parallel attempts cannot all pass the check before any of them fails. Every
+// reserved attempt must be released with ReleaseSignInAttempt, after recording
+// its failure if any.
+func (s *MiddlewareService) ReserveSignInAttempt(ctx context.Context, ip, username string) (time.Duration, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	now := time.Now()
+
+	for _, key := range signInThrottleKeys(ip, username) {
+		rows, err := queries.ReserveSignInThrottleAttempt(ctx, repository.ReserveSignInThrottleAttemptParams{
+			Kind:        key.kind,
+			Value:       key.value,
+			Now:         format.TimeToISO8601(now),
+			StaleBefore: format.TimeToISO8601(now.Add(-signInAttemptTimeout)),
+			WindowStart: format.TimeToISO8601(now.Add(-time.Duration(env.SignInThrottleWindowMin) * time.Minute)),
+			Threshold:   int64(env.SignInThrottleThreshold),
+		})
+		if err != nil {
+			return 0, NewServiceErrorf(ErrCodeInternal, "failed to reserve sign-in attempt: %v", err)
+		}
+
+		if rows > 1 {
+			return 0, NewServiceError(ErrCodeInternal, "multiple sign-in throttles reserved with the same key")
+		}
+
+		if rows == 1 {
+			continue
+		}
+
+		// Nothing is counted if any of the throttles does not allow the
+		// attempt
+		tx.Rollback()
+
+		lockout, err := s.GetSignInLockout(ctx, ip, username)
+		if err != nil {
+			return 0, err
+		}
+
+		// The other attempts in progress are done within seconds
+		return max(lockout, time.Second), nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return 0, nil
+}
+
+// ReleaseSignInAttempt stops counting a sign-in attempt reserved with
+// ReserveSignInAttempt as in progress.
+func (s *MiddlewareService) ReleaseSignInAttempt(ctx context.Context, ip, username string) error {
+	queries := repository.New(s.db)
+
+	for _, key := range signInThrottleKeys(ip, username) {
+		if _, err := queries.ReleaseSignInThrottleAttempt(ctx, repository.ReleaseSignInThrottleAttemptParams{
+			Kind:  key.kind,
+			Value: key.value,
+		}); err != nil {
+			return NewServiceErrorf(ErrCodeInternal, "failed to release sign-in attempt: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// GetSignInPendingUsername returns the username of the user waiting for the
+// second factor on a pre-session, or an empty string if the pre-session is not
+// valid or not waiting for it.
+func (s *MiddlewareService) GetSignInPendingUsername(ctx context.Context, preSessionToken string) (string, error) {
+	queries := repository.New(s.db)
+
+	sessions, err := queries.GetSessionByToken(ctx, hashSessionToken(preSessionToken, s.sessionTokenHashKey))
+	if err != nil {
+		return "", NewServiceErrorf(ErrCodeInternal, "failed to get pre-session: %v", err)
+	}
+
+	if len(sessions) > 1 {
+		return "", NewServiceError(ErrCodeInternal, "multiple sessions found with the same token")
+	}
+
+	if len(sessions) < 1 {
+		return "", nil
+	}
+
+	session := sessions[0]
+
+	if session.UserID.Valid || session.PendingUserID == nil || session.ExpiresAt < generator.NowISO8601() {
+		return "", nil
+	}
+
+	users, err := queries.GetUserByID(ctx, *session.PendingUserID)
+	if err != nil {
+		return "", NewServiceErrorf(ErrCodeInternal, "failed to get user: %v", err)
+	}
+
+	if len(users) > 1 {
+		return "", NewServiceError(ErrCodeInternal, "multiple users found with the same ID")
+	}
+
+	if len(users) < 1 {
+		return "", nil
+	}
+
+	return users[0].Username, nil
+}
+
+// RecordSignInFailure records a failed sign-in attempt, and locks out the
+// client IP or username once its failures reach the threshold. The lockout
+// doubles on each further failure, up to the maximum.
+func (s *MiddlewareService) RecordSignInFailure(ctx context.Context, ip, username string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	queries := repository.New(tx)
+
+	now := time.Now()
+	nowISO8601 := format.TimeToISO8601(now)
+	windowStart := format.TimeToISO8601(now.Add(-time.Duration(env.SignInThrottleWindowMin) * time.Minute))
+
+	if _, err := queries.CreateSignInFailure(ctx, repository.CreateSignInFailureParams{
+		ID:        generator.NewULID(),
+		Username:  nullableString(username),
+		IP:        ip,
+		CreatedAt: nowISO8601,
+	}); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to create sign-in failure: %v", err)
+	}
+
+	for _, key := range signInThrottleKeys(ip, username) {
+		if _, err := queries.UpsertSignInThrottleFailure(ctx, repository.UpsertSignInThrottleFailureParams{
+			Kind:         key.kind,
+			Value:        key.value,
+			LastFailedAt: nowISO8601,
+			WindowStart:  windowStart,
+		}); err != nil {
+			return NewServiceErrorf(ErrCodeInternal, "failed to update sign-in throttle: %v", err)
+		}
+
+		throttles, err := queries.GetSignInThrottle(ctx, repository.GetSignInThrottleParams{
+			Kind:  key.kind,
+			Value: key.value,
+		})
+		if err != nil {
+			return NewServiceErrorf(ErrCodeInternal, "failed to get sign-in throttle: %v", err)
+		}
+
+		if len(throttles) != 1 {
+			return NewServiceError(ErrCodeInternal, "sign-in throttle not found after update")
+		}
+
+		if throttles[0].Failures < int64(env.SignInThrottleThreshold) {
+			continue
+		}
+
+		if _, err := queries.UpdateSignInThrottleLockedUntil(ctx, repository.UpdateSignInThrottleLockedUntilParams{
+			LockedUntil: format.TimeToISO8601(now.Add(signInLockoutDuration(throttles[0].Failures))),
+			UpdatedAt:   nowISO8601,
+			Kind:        key.kind,
+			Value:       key.value,
+		}); err != nil {
+			return NewServiceErrorf(ErrCodeInternal, "failed to lock out sign-in: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// ResetSignInFailures forgets the failures of the username after a successful
+// sign-in. The failures of the client IP are kept, so that signing in to one
+// account does not reset the attempts on the others.
+func (s *MiddlewareService) ResetSignInFailures(ctx context.Context, username string) error {
+	queries := repository.New(s.db)
+
+	if _, err := queries.DeleteSignInThrottle(ctx, repository.DeleteSignInThrottleParams{
+		Kind:  signInThrottleKindUsername,
+		Value: username,
+	}); err != nil {
+		return NewServiceErrorf(ErrCodeInternal, "failed to delete sign-in throttle: %v", err)
+	}
+
+	return nil
+}
+
+// signInLockoutDuration returns the lockout after the failures, starting from
+// the base lockout at the threshold.
+func signInLockoutDuration(failures int64) time.Duration {
+	lockout := time.Duration(env.SignInLockoutBaseMin) * time.Minute
+	maxLockout := time.Duration(env.SignInLockoutMaxMin) * time.Minute
+
+	for i := int64(env.SignInThrottleThreshold); i < failures && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+
+	return min(lockout, maxLockout)
+}
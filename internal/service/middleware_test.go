@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/jljl1337/xpense/internal/env"
+)
+
+func TestReserveSignInAttempt(t *testing.T) {
+	threshold, windowMin, baseMin, maxMin := env.SignInThrottleThreshold, env.SignInThrottleWindowMin, env.SignInLockoutBaseMin, env.SignInLockoutMaxMin
+	t.Cleanup(func() {
+		env.SignInThrottleThreshold, env.SignInThrottleWindowMin, env.SignInLockoutBaseMin, env.SignInLockoutMaxMin = threshold, windowMin, baseMin, maxMin
+	})
+	env.SignInThrottleThreshold, env.SignInThrottleWindowMin, env.SignInLockoutBaseMin, env.SignInLockoutMaxMin = 3, 15, 1, 60
+
+	database := newTestDB(t)
+	s := NewMiddlewareService(database, []byte("key"))
+	ctx := context.Background()
+
+	// Parallel attempts pass up to the failures left before the lockout
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	reserved := 0
+	for range 10 {
+		wg.Go(func() {
+			lockout, err := s.ReserveSignInAttempt(ctx, "192.0.2.1", "alice")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if lockout == 0 {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	if reserved != 3 {
+		t.Fatalf("%d parallel attempts reserved, want 3", reserved)
+	}
+
+	// A released attempt which did not fail leaves room for another one
+	if err := s.ReleaseSignInAttempt(ctx, "192.0.2.1", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if lockout, err := s.ReserveSignInAttempt(ctx, "192.0.2.1", "alice"); err != nil || lockout != 0 {
+		t.Fatalf("ReserveSignInAttempt after a release = %v, %v, want 0", lockout, err)
+	}
+
+	// All of them fail, which locks out both the IP and the username
+	for range 3 {
+		if err := s.RecordSignInFailure(ctx, "192.0.2.1", "alice"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.ReleaseSignInAttempt(ctx, "192.0.2.1", "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, key := range []struct{ ip, username string }{
+		{ip: "192.0.2.1", username: "bob"},
+		{ip: "192.0.2.2", username: "alice"},
+	} {
+		lockout, err := s.ReserveSignInAttempt(ctx, key.ip, key.username)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lockout <= 0 {
+			t.Errorf("ReserveSignInAttempt(%s, %s) = %v, want a lockout", key.ip, key.username, lockout)
+		}
+	}
+
+	// Nothing is counted for the attempts that are refused, or for the other
+	// throttle of a refused attempt
+	var pending int64
+	if err := database.Get(&pending, "SELECT COALESCE(SUM(pending), 0) FROM sign_in_throttle"); err != nil {
+		t.Fatal(err)
+	}
+	if pending != 0 {
+		t.Errorf("%d attempts in progress, want 0", pending)
+	}
+
+	// Another client can still sign in to another account
+	if lockout, err := s.ReserveSignInAttempt(ctx, "192.0.2.3", "bob"); err != nil || lockout != 0 {
+		t.Errorf("ReserveSignInAttempt of another client = %v, %v, want 0", lockout, err)
+	}
+}
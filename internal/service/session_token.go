@@ -0,0 +1,31 @@
+package service
+
+import (
+	"crypto/hmac"
+
+	"github.com/jljl1337/xpense/internal/crypto"
+	"github.com/jljl1337/xpense/internal/repository"
+)
+
+// hashSessionToken returns the keyed hash of a session token, which is what
+// the session table stores instead of the token.
+func hashSessionToken(sessionToken string, key []byte) string {
+	return crypto.HashTokenWithKey(sessionToken, key)
+}
+
+// sessionCSRFToken returns the CSRF token of a session. It is derived from the
+// session token, so that it can be returned again without being stored.
+func sessionCSRFToken(sessionToken string, key []byte) string {
+	return crypto.HashTokenWithKey("csrf:"+sessionToken, key)
+}
+
+// checkSessionCSRFToken checks the CSRF token of a session, which is either
+// derived from the session token or, for the sessions created before the
+// tokens were hashed, the one with the stored hash.
+func checkSessionCSRFToken(session repository.Session, sessionToken, CSRFToken string, key []byte) bool {
+	if hmac.Equal([]byte(CSRFToken), []byte(sessionCSRFToken(sessionToken, key))) {
+		return true
+	}
+
+	return hmac.Equal([]byte(crypto.HashTokenWithKey(CSRFToken, key)), []byte(session.CsrfTokenHash))
+}
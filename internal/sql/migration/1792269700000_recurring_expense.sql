@@ -0,0 +1,27 @@
+CREATE TABLE recurring_expense (
+    id TEXT NOT NULL,
+    book_id TEXT NOT NULL,
+    category_id TEXT NOT NULL,
+    payment_method_id TEXT NOT NULL,
+    amount REAL NOT NULL,
+    remark TEXT NOT NULL,
+    rule TEXT NOT NULL,
+    start_date TEXT NOT NULL,
+    end_date TEXT NOT NULL,
+    materialized_until TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (book_id) REFERENCES book(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES category(id) ON DELETE CASCADE,
+    FOREIGN KEY (payment_method_id) REFERENCES payment_method(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_recurring_expense_book_id ON recurring_expense(book_id);
+CREATE INDEX idx_recurring_expense_category_id ON recurring_expense(category_id);
+CREATE INDEX idx_recurring_expense_payment_method_id ON recurring_expense(payment_method_id);
+
+ALTER TABLE expense ADD COLUMN recurring_expense_id TEXT REFERENCES recurring_expense(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX idx_expense_recurring_expense_id_date ON expense(recurring_expense_id, date);
@@ -0,0 +1,5 @@
+ALTER TABLE expense ADD COLUMN type TEXT NOT NULL DEFAULT 'expense' CHECK (type IN ('expense', 'income', 'transfer'));
+ALTER TABLE expense ADD COLUMN destination_payment_method_id TEXT REFERENCES payment_method(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_expense_type ON expense(type);
+CREATE INDEX idx_expense_destination_payment_method_id ON expense(destination_payment_method_id);
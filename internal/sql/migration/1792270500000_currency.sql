@@ -0,0 +1,17 @@
+ALTER TABLE book ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+
+ALTER TABLE expense ADD COLUMN original_currency TEXT;
+ALTER TABLE expense ADD COLUMN original_amount REAL;
+
+CREATE TABLE exchange_rate (
+    date TEXT NOT NULL,
+    base_currency TEXT NOT NULL,
+    quote_currency TEXT NOT NULL,
+    rate REAL NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    PRIMARY KEY (date, base_currency, quote_currency)
+);
+
+CREATE INDEX idx_exchange_rate_quote_currency_date ON exchange_rate(quote_currency, date);
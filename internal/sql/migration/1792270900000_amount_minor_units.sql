@@ -0,0 +1,48 @@
+-- Amounts are stored as integer minor units of their currency, e.g. cents for
+-- USD, the scales here must match the ones in the money package
+CREATE TEMP TABLE currency_scale (
+    currency TEXT NOT NULL,
+    multiplier INTEGER NOT NULL,
+
+    PRIMARY KEY (currency)
+);
+
+INSERT INTO currency_scale (currency, multiplier) VALUES
+    ('BIF', 1), ('CLP', 1), ('DJF', 1), ('GNF', 1), ('ISK', 1), ('JPY', 1),
+    ('KMF', 1), ('KRW', 1), ('PYG', 1), ('RWF', 1), ('UGX', 1), ('UYI', 1),
+    ('VND', 1), ('VUV', 1), ('XAF', 1), ('XOF', 1), ('XPF', 1),
+    ('BHD', 1000), ('IQD', 1000), ('JOD', 1000), ('KWD', 1000), ('LYD', 1000),
+    ('OMR', 1000), ('TND', 1000),
+    ('CLF', 10000), ('UYW', 10000);
+
+-- Expense amount, in the book currency
+ALTER TABLE expense RENAME COLUMN amount TO amount_real;
+ALTER TABLE expense ADD COLUMN amount INTEGER NOT NULL DEFAULT 0;
+
+UPDATE expense SET amount = CAST(ROUND(amount_real * COALESCE((
+    SELECT cs.multiplier FROM book JOIN currency_scale AS cs ON cs.currency = book.currency WHERE book.id = expense.book_id
+), 100)) AS INTEGER);
+
+ALTER TABLE expense DROP COLUMN amount_real;
+
+-- Expense original amount, in the original currency
+ALTER TABLE expense RENAME COLUMN original_amount TO original_amount_real;
+ALTER TABLE expense ADD COLUMN original_amount INTEGER;
+
+UPDATE expense SET original_amount = CAST(ROUND(original_amount_real * COALESCE((
+    SELECT cs.multiplier FROM currency_scale AS cs WHERE cs.currency = expense.original_currency
+), 100)) AS INTEGER) WHERE original_amount_real IS NOT NULL;
+
+ALTER TABLE expense DROP COLUMN original_amount_real;
+
+-- Recurring expense amount, in the book currency
+ALTER TABLE recurring_expense RENAME COLUMN amount TO amount_real;
+ALTER TABLE recurring_expense ADD COLUMN amount INTEGER NOT NULL DEFAULT 0;
+
+UPDATE recurring_expense SET amount = CAST(ROUND(amount_real * COALESCE((
+    SELECT cs.multiplier FROM book JOIN currency_scale AS cs ON cs.currency = book.currency WHERE book.id = recurring_expense.book_id
+), 100)) AS INTEGER);
+
+ALTER TABLE recurring_expense DROP COLUMN amount_real;
+
+DROP TABLE currency_scale;
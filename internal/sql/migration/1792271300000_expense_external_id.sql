@@ -0,0 +1,3 @@
+ALTER TABLE expense ADD COLUMN external_id TEXT;
+
+CREATE UNIQUE INDEX idx_expense_payment_method_id_external_id ON expense(payment_method_id, external_id);
@@ -0,0 +1 @@
+ALTER TABLE payment_method ADD COLUMN kind TEXT NOT NULL DEFAULT 'asset' CHECK (kind IN ('asset', 'liability'));
@@ -0,0 +1,26 @@
+-- Full-text index of the remarks, the remarks themselves are read from the
+-- expense table by rowid. Case and diacritics are ignored.
+--
+-- It requires SQLite to be built with FTS5 (the sqlite_fts5 build tag). The
+-- rowids of expense are not stable across VACUUM, which must be followed by
+-- INSERT INTO expense_fts (expense_fts) VALUES ('rebuild').
+CREATE VIRTUAL TABLE expense_fts USING fts5(
+    remark,
+    content = 'expense',
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO expense_fts (expense_fts) VALUES ('rebuild');
+
+CREATE TRIGGER expense_fts_insert AFTER INSERT ON expense BEGIN
+    INSERT INTO expense_fts (rowid, remark) VALUES (new.rowid, new.remark);
+END;
+
+CREATE TRIGGER expense_fts_delete AFTER DELETE ON expense BEGIN
+    INSERT INTO expense_fts (expense_fts, rowid, remark) VALUES ('delete', old.rowid, old.remark);
+END;
+
+CREATE TRIGGER expense_fts_update AFTER UPDATE OF remark ON expense BEGIN
+    INSERT INTO expense_fts (expense_fts, rowid, remark) VALUES ('delete', old.rowid, old.remark);
+    INSERT INTO expense_fts (rowid, remark) VALUES (new.rowid, new.remark);
+END;
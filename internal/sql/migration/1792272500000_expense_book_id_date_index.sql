@@ -0,0 +1,5 @@
+-- Supports listing the expenses of a book by date with keyset pagination, and
+-- replaces the index on book_id alone
+CREATE INDEX idx_expense_book_id_date_updated_at_id ON expense(book_id, date, updated_at, id);
+
+DROP INDEX idx_expense_book_id;
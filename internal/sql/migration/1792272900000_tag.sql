@@ -0,0 +1,24 @@
+CREATE TABLE tag (
+    id TEXT NOT NULL,
+    book_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (book_id) REFERENCES book(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_tag_book_id_name ON tag(book_id, name);
+
+CREATE TABLE expense_tag (
+    expense_id TEXT NOT NULL,
+    tag_id TEXT NOT NULL,
+
+    PRIMARY KEY (expense_id, tag_id),
+    FOREIGN KEY (expense_id) REFERENCES expense(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tag(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_expense_tag_tag_id ON expense_tag(tag_id);
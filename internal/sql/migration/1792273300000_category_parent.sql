@@ -0,0 +1,4 @@
+-- Subcategories become top level categories when their parent is deleted
+ALTER TABLE category ADD COLUMN parent_id TEXT REFERENCES category(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_category_parent_id ON category(parent_id);
@@ -0,0 +1,3 @@
+-- Archived categories and payment methods are kept for the existing expenses
+ALTER TABLE category ADD COLUMN archived_at TEXT;
+ALTER TABLE payment_method ADD COLUMN archived_at TEXT;
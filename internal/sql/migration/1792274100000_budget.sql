@@ -0,0 +1,17 @@
+CREATE TABLE budget (
+    id TEXT NOT NULL,
+    book_id TEXT NOT NULL,
+    category_id TEXT,
+    amount INTEGER NOT NULL,
+    period TEXT NOT NULL CHECK (period IN ('weekly', 'monthly', 'yearly')),
+    rollover INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (book_id) REFERENCES book(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES category(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_budget_book_id ON budget(book_id);
+CREATE INDEX idx_budget_category_id ON budget(category_id);
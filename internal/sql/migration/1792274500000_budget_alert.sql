@@ -0,0 +1,30 @@
+CREATE TABLE budget_alert (
+    id TEXT NOT NULL,
+    budget_id TEXT NOT NULL,
+    period_start TEXT NOT NULL,
+    period_end TEXT NOT NULL,
+    threshold INTEGER NOT NULL,
+    spent INTEGER NOT NULL,
+    available INTEGER NOT NULL,
+    created_at TEXT NOT NULL,
+    delivered_at TEXT,
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (budget_id) REFERENCES budget(id) ON DELETE CASCADE
+);
+
+-- Each threshold of a budget is alerted once per period
+CREATE UNIQUE INDEX idx_budget_alert_budget_id_period_start_threshold ON budget_alert(budget_id, period_start, threshold);
+CREATE INDEX idx_budget_alert_delivered_at ON budget_alert(delivered_at);
+
+CREATE TABLE budget_alert_delivery (
+    id TEXT NOT NULL,
+    budget_alert_id TEXT NOT NULL,
+    attempted_at TEXT NOT NULL,
+    error TEXT NOT NULL,
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (budget_alert_id) REFERENCES budget_alert(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_budget_alert_delivery_budget_alert_id ON budget_alert_delivery(budget_alert_id);
@@ -0,0 +1,17 @@
+-- The owner of a book is also its user_id, a book has exactly one owner
+CREATE TABLE book_member (
+    book_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    PRIMARY KEY (book_id, user_id),
+    FOREIGN KEY (book_id) REFERENCES book(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_book_member_user_id ON book_member(user_id);
+
+INSERT INTO book_member (book_id, user_id, role, created_at, updated_at)
+SELECT id, user_id, 'owner', created_at, created_at FROM book;
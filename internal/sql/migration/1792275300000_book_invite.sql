@@ -0,0 +1,18 @@
+-- An invite is deleted once redeemed, expired invites are purged by a cron job
+CREATE TABLE book_invite (
+    id TEXT NOT NULL,
+    book_id TEXT NOT NULL,
+    code TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('editor', 'viewer')),
+    created_by TEXT NOT NULL,
+    expires_at TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (book_id) REFERENCES book(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_book_invite_code ON book_invite(code);
+CREATE INDEX idx_book_invite_book_id ON book_invite(book_id);
+CREATE INDEX idx_book_invite_expires_at ON book_invite(expires_at);
@@ -0,0 +1,18 @@
+-- Only the hash of the token is stored, the token is shown once on creation
+CREATE TABLE api_token (
+    id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL,
+    scope TEXT NOT NULL CHECK (scope IN ('read-only', 'read-write')),
+    expires_at TEXT,
+    last_used_at TEXT,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_api_token_token_hash ON api_token(token_hash);
+CREATE INDEX idx_api_token_user_id ON api_token(user_id);
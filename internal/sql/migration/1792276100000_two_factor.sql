@@ -0,0 +1,32 @@
+-- The secret is enabled only after the user confirms it with a valid code
+CREATE TABLE user_totp (
+    user_id TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    enabled_at TEXT,
+    -- last_step is the time step of the last code used, to reject replays
+    last_step INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    PRIMARY KEY (user_id),
+    FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+-- Only the hash of the code is stored, the codes are shown once on generation
+CREATE TABLE user_recovery_code (
+    id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at TEXT,
+    created_at TEXT NOT NULL,
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_recovery_code_user_id ON user_recovery_code(user_id);
+
+-- A pre-session with a pending user has passed the password check and waits
+-- for the second factor
+ALTER TABLE session ADD COLUMN pending_user_id TEXT REFERENCES user(id) ON DELETE CASCADE;
+ALTER TABLE session ADD COLUMN second_factor_attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,26 @@
+-- A log of the failed sign-in attempts, username is NULL for the second
+-- factor attempts
+CREATE TABLE sign_in_failure (
+    id TEXT NOT NULL,
+    username TEXT,
+    ip TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_sign_in_failure_created_at ON sign_in_failure(created_at);
+
+-- The recent failures of a client IP or a username, locked_until is set once
+-- the failures reach the threshold
+CREATE TABLE sign_in_throttle (
+    kind TEXT NOT NULL CHECK (kind IN ('ip', 'username')),
+    value TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    locked_until TEXT,
+    last_failed_at TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    PRIMARY KEY (kind, value)
+);
@@ -0,0 +1,15 @@
+-- Only keyed hashes of the tokens are stored, the key being kept outside of
+-- the database
+ALTER TABLE session RENAME COLUMN token TO token_hash;
+ALTER TABLE session RENAME COLUMN csrf_token TO csrf_token_hash;
+
+-- The key is not available here, so the tokens of the existing sessions are
+-- hashed on start-up, and the sessions are removed from this table once done
+CREATE TABLE unhashed_session (
+    session_id TEXT NOT NULL,
+
+    PRIMARY KEY (session_id),
+    FOREIGN KEY (session_id) REFERENCES session(id) ON DELETE CASCADE
+);
+
+INSERT INTO unhashed_session (session_id) SELECT id FROM session;
@@ -0,0 +1,6 @@
+-- The client of a session, unknown for the sessions created before
+ALTER TABLE session ADD COLUMN user_agent TEXT;
+ALTER TABLE session ADD COLUMN ip TEXT;
+ALTER TABLE session ADD COLUMN last_seen_at TEXT NOT NULL DEFAULT '';
+
+UPDATE session SET last_seen_at = updated_at;
@@ -0,0 +1,27 @@
+-- The full-text index keeps its own copy of the remarks with the ID of the
+-- expense, instead of reading them from the expense table by rowid, as the
+-- rowids of expense are not stable across VACUUM.
+DROP TRIGGER expense_fts_insert;
+DROP TRIGGER expense_fts_delete;
+DROP TRIGGER expense_fts_update;
+DROP TABLE expense_fts;
+
+CREATE VIRTUAL TABLE expense_fts USING fts5(
+    expense_id UNINDEXED,
+    remark,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO expense_fts (expense_id, remark) SELECT id, remark FROM expense;
+
+CREATE TRIGGER expense_fts_insert AFTER INSERT ON expense BEGIN
+    INSERT INTO expense_fts (expense_id, remark) VALUES (new.id, new.remark);
+END;
+
+CREATE TRIGGER expense_fts_delete AFTER DELETE ON expense BEGIN
+    DELETE FROM expense_fts WHERE expense_id = old.id;
+END;
+
+CREATE TRIGGER expense_fts_update AFTER UPDATE OF id, remark ON expense BEGIN
+    UPDATE expense_fts SET expense_id = new.id, remark = new.remark WHERE expense_id = old.id;
+END;
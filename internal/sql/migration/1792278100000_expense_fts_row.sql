@@ -0,0 +1,45 @@
+-- The full-text index is keyed by the integer rowid of expense_fts_row, which
+-- maps it to the expense ID, so that the triggers find the row to update or
+-- delete with the index on expense_id instead of scanning the index.
+DROP TRIGGER expense_fts_insert;
+DROP TRIGGER expense_fts_delete;
+DROP TRIGGER expense_fts_update;
+DROP TABLE expense_fts;
+
+CREATE TABLE expense_fts_row (
+    id INTEGER NOT NULL,
+    expense_id TEXT NOT NULL,
+
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX idx_expense_fts_row_expense_id ON expense_fts_row(expense_id);
+
+CREATE VIRTUAL TABLE expense_fts USING fts5(
+    remark,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO expense_fts_row (expense_id) SELECT id FROM expense;
+
+INSERT INTO expense_fts (rowid, remark)
+SELECT expense_fts_row.id, expense.remark
+FROM expense_fts_row JOIN expense ON expense.id = expense_fts_row.expense_id;
+
+CREATE TRIGGER expense_fts_insert AFTER INSERT ON expense BEGIN
+    INSERT INTO expense_fts_row (expense_id) VALUES (new.id);
+    INSERT INTO expense_fts (rowid, remark)
+    SELECT id, new.remark FROM expense_fts_row WHERE expense_id = new.id;
+END;
+
+CREATE TRIGGER expense_fts_delete AFTER DELETE ON expense BEGIN
+    DELETE FROM expense_fts WHERE rowid = (SELECT id FROM expense_fts_row WHERE expense_id = old.id);
+    DELETE FROM expense_fts_row WHERE expense_id = old.id;
+END;
+
+CREATE TRIGGER expense_fts_update AFTER UPDATE OF id, remark ON expense
+WHEN old.id IS NOT new.id OR old.remark IS NOT new.remark BEGIN
+    UPDATE expense_fts_row SET expense_id = new.id WHERE expense_id = old.id;
+    UPDATE expense_fts SET remark = new.remark
+    WHERE rowid = (SELECT id FROM expense_fts_row WHERE expense_id = new.id);
+END;
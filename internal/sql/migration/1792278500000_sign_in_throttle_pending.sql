@@ -0,0 +1,4 @@
+-- The attempts of a client IP or a username that are still being checked, so
+-- that parallel attempts cannot all pass the lockout before any of their
+-- failures is recorded
+ALTER TABLE sign_in_throttle ADD COLUMN pending INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,69 @@
+import { customFetch } from "~/lib/db/fetch";
+
+export type APITokenScope = "read-only" | "read-write";
+
+export type APIToken = {
+  id: string;
+  userID: string;
+  name: string;
+  scope: APITokenScope;
+  expiresAt: string | null;
+  lastUsedAt: string | null;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export async function createAPIToken(
+  name: string,
+  scope: APITokenScope,
+  expiresAt: string,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    "/api/users/me/api-tokens",
+    "POST",
+    {
+      name,
+      scope,
+      expiresAt,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  // The token is only returned on creation
+  const data: APIToken & { token: string } = await response.json();
+  return { data, error: null };
+}
+
+export async function getAPITokens() {
+  const response = await customFetch("/api/users/me/api-tokens", "GET");
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: APIToken[] = await response.json();
+  return { data, error: null };
+}
+
+export async function deleteAPIToken(apiTokenID: string, csrfToken: string) {
+  const response = await customFetch(
+    `/api/users/me/api-tokens/${apiTokenID}`,
+    "DELETE",
+    null,
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
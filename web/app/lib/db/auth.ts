@@ -4,6 +4,20 @@ type CsrfToken = {
   csrfToken: string;
 };
 
+type SignInResult = CsrfToken & {
+  secondFactorRequired: boolean;
+};
+
+export type Session = {
+  id: string;
+  userAgent: string | null;
+  ip: string | null;
+  createdAt: string;
+  lastSeenAt: string;
+  expiresAt: string;
+  current: boolean;
+};
+
 export async function signUp(username: string, password: string) {
   const response = await customFetch("/api/auth/sign-up", "POST", {
     username,
@@ -48,6 +62,35 @@ export async function signIn(
     csrfToken,
   );
 
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  // The pre-session is kept if a second factor is required
+  const data: SignInResult = await response.json();
+  return { data, error: null };
+}
+
+/**
+ * Finishes a sign-in requiring a second factor, with either a TOTP code or a
+ * recovery code.
+ */
+export async function signInSecondFactor(
+  code: string,
+  recoveryCode: string,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    "/api/auth/sign-in/second-factor",
+    "POST",
+    {
+      code,
+      recoveryCode,
+    },
+    csrfToken,
+  );
+
   if (!response.ok) {
     const error = await response.text();
     return { error };
@@ -99,3 +142,31 @@ export async function signOutAll(csrfToken: string) {
 
   return { error: null };
 }
+
+export async function getSessions() {
+  const response = await customFetch("/api/auth/sessions", "GET");
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: Session[] = await response.json();
+  return { data, error: null };
+}
+
+export async function revokeSession(sessionID: string, csrfToken: string) {
+  const response = await customFetch(
+    `/api/auth/sessions/${sessionID}`,
+    "DELETE",
+    null,
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
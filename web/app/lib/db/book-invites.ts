@@ -0,0 +1,86 @@
+import type { BookRole } from "~/lib/db/book-members";
+import { customFetch } from "~/lib/db/fetch";
+
+export type BookInvite = {
+  id: string;
+  bookID: string;
+  code: string;
+  role: BookRole;
+  createdBy: string;
+  expiresAt: string;
+  createdAt: string;
+};
+
+export async function createBookInvite(
+  bookID: string,
+  role: BookRole,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    `/api/books/${bookID}/invites`,
+    "POST",
+    {
+      role,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: BookInvite = await response.json();
+  return { data, error: null };
+}
+
+export async function getBookInvites(bookID: string) {
+  const response = await customFetch(`/api/books/${bookID}/invites`, "GET");
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: BookInvite[] = await response.json();
+  return { data, error: null };
+}
+
+export async function deleteBookInvite(
+  bookID: string,
+  inviteID: string,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    `/api/books/${bookID}/invites/${inviteID}`,
+    "DELETE",
+    null,
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function redeemBookInvite(code: string, csrfToken: string) {
+  const response = await customFetch(
+    "/api/invites/redeem",
+    "POST",
+    {
+      code,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: { bookID: string } = await response.json();
+  return { data, error: null };
+}
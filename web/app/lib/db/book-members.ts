@@ -0,0 +1,91 @@
+import { customFetch } from "~/lib/db/fetch";
+
+export type BookRole = "owner" | "editor" | "viewer";
+
+export type BookMember = {
+  bookID: string;
+  userID: string;
+  username: string;
+  role: BookRole;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export async function addBookMember(
+  bookID: string,
+  username: string,
+  role: BookRole,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    `/api/books/${bookID}/members`,
+    "POST",
+    {
+      username,
+      role,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function getBookMembers(bookID: string) {
+  const response = await customFetch(`/api/books/${bookID}/members`, "GET");
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: BookMember[] = await response.json();
+  return { data, error: null };
+}
+
+export async function updateBookMember(
+  bookID: string,
+  userID: string,
+  role: BookRole,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    `/api/books/${bookID}/members/${userID}`,
+    "PUT",
+    {
+      role,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function removeBookMember(
+  bookID: string,
+  userID: string,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    `/api/books/${bookID}/members/${userID}`,
+    "DELETE",
+    null,
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
@@ -9,6 +9,7 @@ export type Book = {
   userID: string;
   name: string;
   description: string;
+  currency: string;
   createdAt: string;
   updatedAt: string;
 };
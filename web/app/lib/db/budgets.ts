@@ -0,0 +1,173 @@
+import { customFetch } from "~/lib/db/fetch";
+
+export type BudgetPeriod = "weekly" | "monthly" | "yearly";
+
+export type Budget = {
+  id: string;
+  bookID: string;
+  categoryID: string | null;
+  amount: string;
+  period: BudgetPeriod;
+  rollover: boolean;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export type BudgetPeriodStatus = {
+  start: string;
+  end: string;
+  rollover: string;
+  available: string;
+  spent: string;
+  remaining: string;
+  percentageUsed: number | null;
+};
+
+export type BudgetStatus = Budget & {
+  periods: BudgetPeriodStatus[];
+};
+
+export type BudgetAlertDelivery = {
+  id: string;
+  budgetAlertID: string;
+  attemptedAt: string;
+  error: string;
+};
+
+export type BudgetAlert = {
+  id: string;
+  budgetID: string;
+  periodStart: string;
+  periodEnd: string;
+  threshold: number;
+  spent: string;
+  available: string;
+  createdAt: string;
+  deliveredAt: string | null;
+  deliveries: BudgetAlertDelivery[];
+};
+
+export async function createBudget(
+  bookID: string,
+  categoryID: string,
+  amount: string,
+  period: BudgetPeriod,
+  rollover: boolean,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    "/api/budgets",
+    "POST",
+    {
+      bookID,
+      categoryID,
+      amount,
+      period,
+      rollover,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function getBudgets(bookID: string) {
+  const response = await customFetch(`/api/budgets?book-id=${bookID}`, "GET");
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: Budget[] = await response.json();
+  return { data, error: null };
+}
+
+export async function getBudget(budgetID: string) {
+  const response = await customFetch(`/api/budgets/${budgetID}`, "GET");
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: Budget = await response.json();
+  return { data, error: null };
+}
+
+export async function updateBudget(
+  budgetID: string,
+  categoryID: string,
+  amount: string,
+  period: BudgetPeriod,
+  rollover: boolean,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    `/api/budgets/${budgetID}`,
+    "PUT",
+    {
+      categoryID,
+      amount,
+      period,
+      rollover,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function deleteBudget(budgetID: string, csrfToken: string) {
+  const response = await customFetch(
+    `/api/budgets/${budgetID}`,
+    "DELETE",
+    null,
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function getBudgetStatuses(bookID: string, periods?: number) {
+  const query = periods ? `?periods=${periods}` : "";
+  const response = await customFetch(
+    `/api/books/${bookID}/budgets/status${query}`,
+    "GET",
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: BudgetStatus[] = await response.json();
+  return { data, error: null };
+}
+
+export async function getBudgetAlerts(budgetID: string) {
+  const response = await customFetch(`/api/budgets/${budgetID}/alerts`, "GET");
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: BudgetAlert[] = await response.json();
+  return { data, error: null };
+}
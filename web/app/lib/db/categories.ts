@@ -3,12 +3,22 @@ import { customFetch } from "~/lib/db/fetch";
 export type Category = {
   id: string;
   bookID: string;
+  parentID: string | null;
   name: string;
   description: string;
   createdAt: string;
   updatedAt: string;
+  archivedAt: string | null;
+  children: Category[];
 };
 
+function flattenCategories(categories: Category[]): Category[] {
+  return categories.flatMap((category) => [
+    category,
+    ...flattenCategories(category.children),
+  ]);
+}
+
 export async function createCategory(
   bookID: string,
   name: string,
@@ -34,9 +44,9 @@ export async function createCategory(
   return { error: null };
 }
 
-export async function getCategories(bookID: string) {
+export async function getCategories(bookID: string, includeArchived = false) {
   const response = await customFetch(
-    `/api/categories?book-id=${bookID}`,
+    `/api/categories?book-id=${bookID}&include-archived=${includeArchived}`,
     "GET",
   );
 
@@ -45,7 +55,8 @@ export async function getCategories(bookID: string) {
     return { data: null, error };
   }
 
-  const data: Category[] = await response.json();
+  const tree: Category[] = await response.json();
+  const data = flattenCategories(tree);
   return { data, error: null };
 }
 
@@ -85,9 +96,14 @@ export async function updateCategory(
   return { error: null };
 }
 
-export async function deleteCategory(categoryID: string, csrfToken: string) {
+export async function deleteCategory(
+  categoryID: string,
+  csrfToken: string,
+  reassignTo?: string,
+) {
+  const query = reassignTo ? `?reassign-to=${reassignTo}` : "";
   const response = await customFetch(
-    `/api/categories/${categoryID}`,
+    `/api/categories/${categoryID}${query}`,
     "DELETE",
     null,
     csrfToken,
@@ -100,3 +116,55 @@ export async function deleteCategory(categoryID: string, csrfToken: string) {
 
   return { error: null };
 }
+
+export async function mergeCategory(
+  categoryID: string,
+  targetID: string,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    `/api/categories/${categoryID}/merge`,
+    "POST",
+    { targetID },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function archiveCategory(categoryID: string, csrfToken: string) {
+  const response = await customFetch(
+    `/api/categories/${categoryID}/archive`,
+    "POST",
+    null,
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function unarchiveCategory(categoryID: string, csrfToken: string) {
+  const response = await customFetch(
+    `/api/categories/${categoryID}/unarchive`,
+    "POST",
+    null,
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
@@ -10,10 +10,17 @@ export type Expense = {
   categoryID: string;
   paymentMethodID: string;
   date: string;
-  amount: number;
+  amount: string;
   remark: string;
   createdAt: string;
   updatedAt: string;
+  recurringExpenseID: string | null;
+  type: "expense" | "income" | "transfer";
+  destinationPaymentMethodID: string | null;
+  originalCurrency: string | null;
+  originalAmount: string | null;
+  externalID: string | null;
+  tagIDs: string[];
 };
 
 export async function createExpense(
@@ -134,6 +141,10 @@ export async function updateExpense(
   date: string,
   amount: number,
   remark: string,
+  type: Expense["type"],
+  destinationPaymentMethodID: string | null,
+  originalCurrency: string | null,
+  originalAmount: string | null,
   csrfToken: string,
 ) {
   const response = await customFetch(
@@ -145,6 +156,10 @@ export async function updateExpense(
       date,
       amount,
       remark,
+      type,
+      destinationPaymentMethodID: destinationPaymentMethodID ?? "",
+      originalCurrency: originalCurrency ?? "",
+      originalAmount,
     },
     csrfToken,
   );
@@ -5,8 +5,10 @@ export type PaymentMethod = {
   bookID: string;
   name: string;
   description: string;
+  kind: "asset" | "liability";
   createdAt: string;
   updatedAt: string;
+  archivedAt: string | null;
 };
 
 export async function createPaymentMethod(
@@ -34,9 +36,12 @@ export async function createPaymentMethod(
   return { error: null };
 }
 
-export async function getPaymentMethods(bookID: string) {
+export async function getPaymentMethods(
+  bookID: string,
+  includeArchived = false,
+) {
   const response = await customFetch(
-    `/api/payment-methods?book-id=${bookID}`,
+    `/api/payment-methods?book-id=${bookID}&include-archived=${includeArchived}`,
     "GET",
   );
 
@@ -91,9 +96,11 @@ export async function updatePaymentMethod(
 export async function deletePaymentMethod(
   paymentMethodID: string,
   csrfToken: string,
+  reassignTo?: string,
 ) {
+  const query = reassignTo ? `?reassign-to=${reassignTo}` : "";
   const response = await customFetch(
-    `/api/payment-methods/${paymentMethodID}`,
+    `/api/payment-methods/${paymentMethodID}${query}`,
     "DELETE",
     null,
     csrfToken,
@@ -106,3 +113,61 @@ export async function deletePaymentMethod(
 
   return { error: null };
 }
+
+export async function mergePaymentMethod(
+  paymentMethodID: string,
+  targetID: string,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    `/api/payment-methods/${paymentMethodID}/merge`,
+    "POST",
+    { targetID },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function archivePaymentMethod(
+  paymentMethodID: string,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    `/api/payment-methods/${paymentMethodID}/archive`,
+    "POST",
+    null,
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function unarchivePaymentMethod(
+  paymentMethodID: string,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    `/api/payment-methods/${paymentMethodID}/unarchive`,
+    "POST",
+    null,
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
@@ -0,0 +1,102 @@
+import { customFetch } from "~/lib/db/fetch";
+
+export type Tag = {
+  id: string;
+  bookID: string;
+  name: string;
+  description: string;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export async function createTag(
+  bookID: string,
+  name: string,
+  description: string,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    "/api/tags",
+    "POST",
+    {
+      bookID,
+      name,
+      description,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function getTags(bookID: string) {
+  const response = await customFetch(
+    `/api/tags?book-id=${bookID}`,
+    "GET",
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: Tag[] = await response.json();
+  return { data, error: null };
+}
+
+export async function getTag(tagID: string) {
+  const response = await customFetch(`/api/tags/${tagID}`, "GET");
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: Tag = await response.json();
+  return { data, error: null };
+}
+
+export async function updateTag(
+  tagID: string,
+  name: string,
+  description: string,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    `/api/tags/${tagID}`,
+    "PUT",
+    {
+      name,
+      description,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function deleteTag(tagID: string, csrfToken: string) {
+  const response = await customFetch(
+    `/api/tags/${tagID}`,
+    "DELETE",
+    null,
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
@@ -0,0 +1,107 @@
+import { customFetch } from "~/lib/db/fetch";
+
+export type TwoFactorStatus = {
+  totpEnabled: boolean;
+  recoveryCodesRemaining: number;
+};
+
+export type TOTPEnrollment = {
+  secret: string;
+  uri: string;
+};
+
+type RecoveryCodes = {
+  recoveryCodes: string[];
+};
+
+export async function getTwoFactorStatus() {
+  const response = await customFetch("/api/users/me/2fa", "GET");
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: TwoFactorStatus = await response.json();
+  return { data, error: null };
+}
+
+export async function enrollTOTP(csrfToken: string) {
+  const response = await customFetch(
+    "/api/users/me/2fa/totp",
+    "POST",
+    null,
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: TOTPEnrollment = await response.json();
+  return { data, error: null };
+}
+
+/**
+ * Confirms the TOTP enrolment and returns the recovery codes, which are only
+ * returned once.
+ */
+export async function confirmTOTP(code: string, csrfToken: string) {
+  const response = await customFetch(
+    "/api/users/me/2fa/totp/confirm",
+    "POST",
+    {
+      code,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: RecoveryCodes = await response.json();
+  return { data: data.recoveryCodes, error: null };
+}
+
+export async function disableTwoFactor(password: string, csrfToken: string) {
+  const response = await customFetch(
+    "/api/users/me/2fa/disable",
+    "POST",
+    {
+      password,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { error };
+  }
+
+  return { error: null };
+}
+
+export async function regenerateRecoveryCodes(
+  password: string,
+  csrfToken: string,
+) {
+  const response = await customFetch(
+    "/api/users/me/2fa/recovery-codes",
+    "POST",
+    {
+      password,
+    },
+    csrfToken,
+  );
+
+  if (!response.ok) {
+    const error = await response.text();
+    return { data: null, error };
+  }
+
+  const data: RecoveryCodes = await response.json();
+  return { data: data.recoveryCodes, error: null };
+}
@@ -1,3 +1,4 @@
+import { useState } from "react";
 import { Link, redirect, useNavigate } from "react-router";
 import type { Route } from "./+types/sign-in";
 
@@ -23,7 +24,12 @@ import {
 } from "~/components/ui/form";
 import { Input } from "~/components/ui/input";
 
-import { createPreSession, getCsrfToken, signIn } from "~/lib/db/auth";
+import {
+  createPreSession,
+  getCsrfToken,
+  signIn,
+  signInSecondFactor,
+} from "~/lib/db/auth";
 import { getMe } from "~/lib/db/users";
 
 const formSchema = z.object({
@@ -31,6 +37,13 @@ const formSchema = z.object({
   password: z.string().min(1, "Password is required"),
 });
 
+const secondFactorFormSchema = z.object({
+  code: z.string().trim().min(1, "Code is required"),
+});
+
+// TOTP codes are 6 digits, anything else is taken as a recovery code
+const totpCodeRegex = /^\d{6}$/;
+
 export async function clientLoader() {
   const me = await getMe();
 
@@ -72,23 +85,57 @@ export default function Page({ loaderData }: Route.ComponentProps) {
     formState: { isSubmitting, errors },
   } = form;
 
+  const secondFactorForm = useForm<z.infer<typeof secondFactorFormSchema>>({
+    resolver: zodResolver(secondFactorFormSchema),
+    defaultValues: {
+      code: "",
+    },
+  });
+
+  const [secondFactorRequired, setSecondFactorRequired] = useState(false);
+
   const navigate = useNavigate();
 
   async function onSubmit(values: z.infer<typeof formSchema>) {
-    const { error } = await signIn(
+    const { data, error } = await signIn(
       values.username,
       values.password,
       loaderData.data.preSessionCSRFToken,
     );
-    if (error) {
+    if (error != null) {
       setError("root", {
         message: error,
       });
       return;
     }
+    if (data.secondFactorRequired) {
+      setSecondFactorRequired(true);
+      return;
+    }
     navigate("/books");
   }
 
+  async function onSecondFactorSubmit(
+    values: z.infer<typeof secondFactorFormSchema>,
+  ) {
+    const isTOTPCode = totpCodeRegex.test(values.code);
+    const { error } = await signInSecondFactor(
+      isTOTPCode ? values.code : "",
+      isTOTPCode ? "" : values.code,
+      loaderData.data.preSessionCSRFToken,
+    );
+    if (error) {
+      secondFactorForm.setError("root", {
+        message: error,
+      });
+      return;
+    }
+    navigate("/books");
+  }
+
+  const secondFactorErrors = secondFactorForm.formState.errors;
+  const isSecondFactorSubmitting = secondFactorForm.formState.isSubmitting;
+
   return (
     <>
       <title>Sign In | Xpense</title>
@@ -99,68 +146,112 @@ export default function Page({ loaderData }: Route.ComponentProps) {
               <CardHeader>
                 <CardTitle>Sign in to your account</CardTitle>
                 <CardDescription>
-                  Enter your credentials below to sign in
+                  {secondFactorRequired
+                    ? "Enter the code from your authenticator app or a recovery code"
+                    : "Enter your credentials below to sign in"}
                 </CardDescription>
               </CardHeader>
               <CardContent>
-                <Form {...form}>
-                  <form
-                    onSubmit={form.handleSubmit(onSubmit)}
-                    className="space-y-4"
-                  >
-                    <FormField
-                      control={form.control}
-                      name="username"
-                      render={({ field }) => (
-                        <FormItem>
-                          <FormLabel>Username</FormLabel>
-                          <FormControl>
-                            <Input placeholder="your_username" {...field} />
-                          </FormControl>
-                          <FormMessage />
-                        </FormItem>
-                      )}
-                    />
-                    <FormField
-                      control={form.control}
-                      name="password"
-                      render={({ field }) => (
-                        <FormItem>
-                          <FormLabel>Password</FormLabel>
-                          <FormControl>
-                            <Input
-                              type="password"
-                              placeholder="yourVerySecureP@ssw0rd!"
-                              {...field}
-                            />
-                          </FormControl>
-                          <FormMessage />
-                        </FormItem>
+                {secondFactorRequired ? (
+                  <Form {...secondFactorForm}>
+                    <form
+                      onSubmit={secondFactorForm.handleSubmit(
+                        onSecondFactorSubmit,
                       )}
-                    />
-                    <Button
-                      type="submit"
-                      className="w-full"
-                      disabled={isSubmitting}
+                      className="space-y-4"
                     >
-                      Submit
-                    </Button>
-                    {errors.root?.message && !isSubmitting && (
-                      <div className="text-destructive text-sm text-center">
-                        {errors.root?.message}
-                      </div>
-                    )}
-                    <div className="mt-4 text-center text-sm">
-                      Don&apos;t have an account?{" "}
-                      <Link
-                        to="/auth/sign-up"
-                        className="underline underline-offset-4"
+                      <FormField
+                        control={secondFactorForm.control}
+                        name="code"
+                        render={({ field }) => (
+                          <FormItem>
+                            <FormLabel>Code</FormLabel>
+                            <FormControl>
+                              <Input
+                                autoComplete="one-time-code"
+                                placeholder="123456"
+                                {...field}
+                              />
+                            </FormControl>
+                            <FormMessage />
+                          </FormItem>
+                        )}
+                      />
+                      <Button
+                        type="submit"
+                        className="w-full"
+                        disabled={isSecondFactorSubmitting}
                       >
-                        Sign up
-                      </Link>
-                    </div>
-                  </form>
-                </Form>
+                        Verify
+                      </Button>
+                      {secondFactorErrors.root?.message &&
+                        !isSecondFactorSubmitting && (
+                          <div className="text-destructive text-sm text-center">
+                            {secondFactorErrors.root?.message}
+                          </div>
+                        )}
+                    </form>
+                  </Form>
+                ) : (
+                  <Form {...form}>
+                    <form
+                      onSubmit={form.handleSubmit(onSubmit)}
+                      className="space-y-4"
+                    >
+                      <FormField
+                        control={form.control}
+                        name="username"
+                        render={({ field }) => (
+                          <FormItem>
+                            <FormLabel>Username</FormLabel>
+                            <FormControl>
+                              <Input placeholder="your_username" {...field} />
+                            </FormControl>
+                            <FormMessage />
+                          </FormItem>
+                        )}
+                      />
+                      <FormField
+                        control={form.control}
+                        name="password"
+                        render={({ field }) => (
+                          <FormItem>
+                            <FormLabel>Password</FormLabel>
+                            <FormControl>
+                              <Input
+                                type="password"
+                                placeholder="yourVerySecureP@ssw0rd!"
+                                {...field}
+                              />
+                            </FormControl>
+                            <FormMessage />
+                          </FormItem>
+                        )}
+                      />
+                      <Button
+                        type="submit"
+                        className="w-full"
+                        disabled={isSubmitting}
+                      >
+                        Submit
+                      </Button>
+                      {errors.root?.message && !isSubmitting && (
+                        <div className="text-destructive text-sm text-center">
+                          {errors.root?.message}
+                        </div>
+                      )}
+                      <div className="mt-4 text-center text-sm">
+                        Don&apos;t have an account?{" "}
+                        <Link
+                          to="/auth/sign-up"
+                          className="underline underline-offset-4"
+                        >
+                          Sign up
+                        </Link>
+                      </div>
+                    </form>
+                  </Form>
+                )}
               </CardContent>
             </Card>
           </div>
@@ -15,8 +15,8 @@ export async function clientLoader({ params }: Route.ClientLoaderArgs) {
   const [csrfToken, categoryList, paymentMethodList, expense] =
     await Promise.all([
       getCsrfToken(),
-      getCategories(params.bookID),
-      getPaymentMethods(params.bookID),
+      getCategories(params.bookID, true),
+      getPaymentMethods(params.bookID, true),
       getExpense(params.expenseID),
     ]);
 
@@ -52,6 +52,10 @@ export default function Page({ loaderData, params }: Route.ComponentProps) {
       data.date,
       data.amount,
       data.remark,
+      expense.type,
+      expense.destinationPaymentMethodID,
+      expense.originalCurrency,
+      expense.originalAmount,
       loaderData.csrfToken,
     );
 
@@ -73,7 +77,7 @@ export default function Page({ loaderData, params }: Route.ComponentProps) {
         categoryIdValue={defaultCategoryID}
         paymentMethodIdValue={defaultPaymentMethodID}
         dateValue={defaultDate}
-        amountValue={expense.amount}
+        amountValue={Number(expense.amount)}
         remarkValue={expense.remark}
         submitButtonLabel="Update"
         action={action}
@@ -41,8 +41,8 @@ export async function clientLoader({
         paymentMethodID,
         remark,
       ),
-      getCategories(params.bookID),
-      getPaymentMethods(params.bookID),
+      getCategories(params.bookID, true),
+      getPaymentMethods(params.bookID, true),
       getExpensesByBookID(
         params.bookID,
         page,